@@ -1,30 +1,58 @@
 package admin_service
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"ride-hail-system/internal/admin/handler"
 	"ride-hail-system/internal/admin/repository"
+	adminrmq "ride-hail-system/internal/admin/rmq"
 	"ride-hail-system/internal/admin/service"
+	adminws "ride-hail-system/internal/admin/websocket"
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/config"
 	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RunAdmin(cfg *config.Config, conn *pgx.Conn, mux *http.ServeMux) {
+func RunAdmin(ctx context.Context, cfg *config.Config, conn *pgxpool.Pool, commonMq *rmq.RabbitMQ, consumerOpts rmq.ConsumerOptions, mux *http.ServeMux, hub *websocket.Hub, wsMux *http.ServeMux, authn *auth.Authenticator, depth rmq.DepthInspector, sessions service.SessionManager, tokens service.TokenRevoker) {
 	logger.SetServiceName("admin-service")
 
 	logger.Info("startup", "Starting Admin Service...", "", "")
 
 	repo := repository.NewAdminRepository(conn)
-	svc := service.NewAdminService(repo)
+	svc := service.NewAdminService(repo, consumerOpts.Metrics, depth, sessions, tokens)
 	h := handler.NewAdminHandler(svc)
 
-	mux.HandleFunc("GET /admin/overview", h.GetSystemOverview)
-	mux.HandleFunc("GET /admin/rides/active", h.GetActiveRides)
-	mux.HandleFunc("GET /admin/drivers/online", h.GetOnlineDrivers)
-	mux.HandleFunc("GET /admin/metrics", h.GetSystemMetrics)
+	mux.HandleFunc("GET /admin/overview", authn.Require(auth.PermAdminRead, h.GetSystemOverview))
+	mux.HandleFunc("GET /admin/rides/active", authn.Require(auth.PermAdminRead, h.GetActiveRides))
+	mux.HandleFunc("GET /admin/drivers/online", authn.Require(auth.PermAdminRead, h.GetOnlineDrivers))
+	mux.HandleFunc("GET /admin/metrics", authn.Require(auth.PermAdminRead, h.GetSystemMetrics))
+	mux.HandleFunc("GET /admin/queues", authn.Require(auth.PermAdminRead, h.GetQueueStats))
+	mux.HandleFunc("GET /admin/users/{user_id}/sessions", authn.Require(auth.PermAdminRead, h.GetUserSessions))
+	mux.HandleFunc("DELETE /admin/sessions/{session_id}", authn.Require(auth.PermAdminManage, h.DisconnectSession))
+	mux.HandleFunc("PUT /admin/users/{user_id}/status", authn.Require(auth.PermAdminManage, h.SetUserStatus))
+	mux.HandleFunc("GET /admin/auth-events", authn.Require(auth.PermAdminRead, h.ListAuthEvents))
+
+	bus, err := rmq.NewAMQPBus(commonMq.Conn)
+	if err != nil {
+		logger.Error("init_rmq_client", "Failed to init admin RMQ client", "", "", err.Error())
+		return
+	}
+	feed := service.NewOpsFeed(svc, adminrmq.NewClient(bus, consumerOpts), hub,
+		time.Duration(cfg.Admin.FeedMetricsSeconds)*time.Second,
+		time.Duration(cfg.Admin.FeedLocationThrottleMs)*time.Millisecond)
+	if err := feed.Start(ctx); err != nil {
+		logger.Error("init_admin_feed", "Failed to start admin ops feed", "", "", err.Error())
+		return
+	}
+	wsMux.HandleFunc("/ws/admin", func(w http.ResponseWriter, r *http.Request) {
+		adminws.AdminWSHandler(ctx, w, r, hub, authn)
+	})
 
 	logger.Info("startup_complete", "Admin Service started successfully", "", "")
 }
@@ -0,0 +1,46 @@
+package chat_service
+
+import (
+	"context"
+	"net/http"
+
+	"ride-hail-system/internal/chat/handler"
+	"ride-hail-system/internal/chat/repository"
+	chatrmq "ride-hail-system/internal/chat/rmq"
+	"ride-hail-system/internal/chat/service"
+	chatws "ride-hail-system/internal/chat/websocket"
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	commonrmq "ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RunChat(ctx context.Context, conn *pgxpool.Pool, commonMq *commonrmq.RabbitMQ, consumerOpts commonrmq.ConsumerOptions, mux *http.ServeMux, hub *websocket.Hub, authn *auth.Authenticator) {
+	logger.SetServiceName("chat-service")
+
+	logger.Info("startup", "Starting Chat Service...", "", "")
+
+	bus, err := commonrmq.NewAMQPBus(commonMq.Conn)
+	if err != nil {
+		logger.Error("init_rmq_client", "Failed to init chat RMQ client", "", "", err.Error())
+		return
+	}
+	rmqClient := chatrmq.NewClient(bus, consumerOpts)
+
+	repo := repository.NewChatRepository(conn)
+	svc := service.NewChatService(repo, rmqClient, hub)
+	h := handler.NewChatHandler(svc)
+
+	mux.HandleFunc("GET /chat/templates", authn.Require(auth.PermChat, h.GetTemplates))
+	mux.HandleFunc("GET /rides/{ride_id}/chat", authn.Require(auth.PermRidesRead, h.GetHistory))
+	mux.HandleFunc("GET /admin/rides/{ride_id}/chat", authn.Require(auth.PermAdminRead, h.GetTranscript))
+
+	chatws.RegisterHandlers(hub, svc)
+
+	logger.Info("listener_ride_status", "Listening for ride status changes...", "", "")
+	svc.ListenRideStatus(ctx, commonrmq.QueueChatRideStatus)
+
+	logger.Info("startup_complete", "Chat Service started successfully", "", "")
+}
@@ -0,0 +1,35 @@
+package contact_service
+
+import (
+	"net/http"
+	"time"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/config"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/contact/handler"
+	"ride-hail-system/internal/contact/provider"
+	"ride-hail-system/internal/contact/repository"
+	"ride-hail-system/internal/contact/service"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RunContact регистрирует маршруты связи участников.
+func RunContact(cfg *config.Config, conn *pgxpool.Pool, mux *http.ServeMux, authn *auth.Authenticator) *service.ContactService {
+	logger.SetServiceName("contact-service")
+
+	logger.Info("startup", "Starting Contact Relay Service...", "", "")
+
+	repo := repository.NewContactRepository(conn)
+	// Настоящий провайдер телефонии подключается реализацией service.Provider.
+	svc := service.NewContactService(repo, provider.NewFake(), time.Duration(cfg.Contact.ProxyTTLMinutes)*time.Minute)
+	h := handler.NewContactHandler(svc)
+
+	mux.HandleFunc("GET /rides/{ride_id}/contact", authn.Require(auth.PermRidesRead, h.GetCounterpart))
+	mux.HandleFunc("POST /contact/{proxy_id}/call", authn.Require(auth.PermContact, h.Call))
+	mux.HandleFunc("POST /contact/{proxy_id}/sms", authn.Require(auth.PermContact, h.SendSMS))
+
+	logger.Info("startup_complete", "Contact Relay Service started successfully", "", "")
+	return svc
+}
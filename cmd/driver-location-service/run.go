@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/config"
 	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/outbox"
 	commonrmq "ride-hail-system/internal/common/rmq"
 	"ride-hail-system/internal/common/websocket"
 	"ride-hail-system/internal/driver/handler"
@@ -13,45 +15,50 @@ import (
 	driverrmq "ride-hail-system/internal/driver/rmq"
 	"ride-hail-system/internal/driver/service"
 	driverws "ride-hail-system/internal/driver/websocket"
-	"ride-hail-system/internal/user/jwt"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RunDriver(cfg *config.Config, conn *pgx.Conn, commonMq *commonrmq.RabbitMQ, mux *http.ServeMux, hub *websocket.Hub, wsMux *http.ServeMux, jwtManager *jwt.Manager) {
+func RunDriver(ctx context.Context, cfg *config.Config, conn *pgxpool.Pool, commonMq *commonrmq.RabbitMQ, outboxStore *outbox.Store, consumerOpts commonrmq.ConsumerOptions, mux *http.ServeMux, hub *websocket.Hub, wsMux *http.ServeMux, authn *auth.Authenticator) {
 	logger.SetServiceName("driver-location-service")
 
 	logger.Info("startup", "Starting Driver & Location Service...", "", "")
 
-	rmqClient, err := driverrmq.NewClient(commonMq.URL, "driver_topic")
+	bus, err := commonrmq.NewAMQPBus(commonMq.Conn)
 	if err != nil {
 		logger.Error("init_rmq_client", "Failed to init driver RMQ client", "", "", err.Error())
 		return
 	}
+	rmqClient := driverrmq.NewClient(bus, commonrmq.ExchangeDriver, outboxStore, consumerOpts)
 
 	repo := repository.NewDriverRepository(conn)
 	svc := service.NewDriverService(repo, rmqClient, hub)
-	h := handler.NewHandler(svc, jwtManager)
+	h := handler.NewHandler(svc)
 
-	mux.HandleFunc("POST /drivers/{driver_id}/online", h.GoOnline)
-	mux.HandleFunc("POST /drivers/{driver_id}/offline", h.GoOffline)
-	mux.HandleFunc("POST /drivers/{driver_id}/location", h.UpdateLocation)
-	mux.HandleFunc("POST /drivers/{driver_id}/start", h.Start)
-	mux.HandleFunc("POST /drivers/{driver_id}/complete", h.Complete)
+	// Водитель управляет только своей сменой: driver_id должен совпадать с токеном.
+	driverOnly := func(next http.HandlerFunc) http.HandlerFunc {
+		return authn.Require(auth.PermDriverOps, auth.Self("driver_id", next))
+	}
+	mux.HandleFunc("POST /drivers/{driver_id}/online", driverOnly(h.GoOnline))
+	mux.HandleFunc("POST /drivers/{driver_id}/offline", driverOnly(h.GoOffline))
+	mux.HandleFunc("POST /drivers/{driver_id}/location", driverOnly(h.UpdateLocation))
+	mux.HandleFunc("POST /drivers/{driver_id}/start", driverOnly(h.Start))
+	mux.HandleFunc("POST /drivers/{driver_id}/complete", driverOnly(h.Complete))
 
+	driverws.RegisterHandlers(hub, svc)
 	wsMux.HandleFunc("/ws/drivers/", func(w http.ResponseWriter, r *http.Request) {
-		driverws.DriverWSHandler(w, r, hub, jwtManager, svc)
+		driverws.DriverWSHandler(ctx, w, r, hub, authn)
 	})
 
-	go func() {
-		logger.Info("listener_rides", "Listening for ride requests...", "", "")
-		svc.ListenForRides(context.Background(), "ride_requests")
-	}()
+	logger.Info("listener_rides", "Listening for ride requests...", "", "")
+	svc.ListenForRides(ctx, commonrmq.QueueRideRequests)
+
+	logger.Info("listener_passengers", "Listening for passenger matching...", "", "")
+	svc.ListenForPassengers(ctx, commonrmq.QueueDriverMatching)
 
-	go func() {
-		logger.Info("listener_passengers", "Listening for passenger matching...", "", "")
-		svc.ListenForPassengers(context.Background(), "driver_matching")
-	}()
+	// Циклы публикации сообщений из WebSocket запускаются один раз на сервис.
+	go svc.SendToMq(ctx)
+	go svc.UpdateLocationWS(ctx)
 
 	logger.Info("startup_complete", "Driver & Location Service started successfully", "", "")
 }
@@ -0,0 +1,44 @@
+package onboarding_service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/config"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/onboarding/blobstore"
+	"ride-hail-system/internal/onboarding/handler"
+	"ride-hail-system/internal/onboarding/repository"
+	"ride-hail-system/internal/onboarding/service"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RunOnboarding регистрирует маршруты документов водителей.
+func RunOnboarding(ctx context.Context, cfg *config.Config, conn *pgxpool.Pool, mux *http.ServeMux, authn *auth.Authenticator) {
+	logger.SetServiceName("onboarding-service")
+
+	logger.Info("startup", "Starting Driver Onboarding Service...", "", "")
+
+	blobs, err := blobstore.NewLocal(cfg.Onboarding.BlobDir)
+	if err != nil {
+		logger.Error("init_blobstore", "Failed to init document storage", "", "", err.Error())
+		return
+	}
+	repo := repository.NewDocumentRepository(conn)
+	svc := service.NewOnboardingService(repo, blobs, int64(cfg.Onboarding.MaxUploadMB)<<20)
+	h := handler.NewOnboardingHandler(svc)
+
+	mux.HandleFunc("POST /drivers/{driver_id}/documents", authn.Require(auth.PermDriverOps, auth.Self("driver_id", h.Upload)))
+	mux.HandleFunc("GET /drivers/{driver_id}/documents", authn.Require(auth.PermDriverOps, auth.Self("driver_id", h.GetStatus)))
+	mux.HandleFunc("GET /admin/documents/pending", authn.Require(auth.PermAdminRead, h.GetPending))
+	mux.HandleFunc("GET /admin/documents/{document_id}/file", authn.Require(auth.PermAdminRead, h.GetFile))
+	mux.HandleFunc("POST /admin/documents/{document_id}/approve", authn.Require(auth.PermAdminManage, h.Approve))
+	mux.HandleFunc("POST /admin/documents/{document_id}/reject", authn.Require(auth.PermAdminManage, h.Reject))
+
+	go svc.RunExpiry(ctx, time.Duration(cfg.Onboarding.ExpiryCheckMinutes)*time.Minute)
+
+	logger.Info("startup_complete", "Driver Onboarding Service started successfully", "", "")
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/config"
 	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/outbox"
 	commonrmq "ride-hail-system/internal/common/rmq"
 	"ride-hail-system/internal/common/websocket"
 	ridehttp "ride-hail-system/internal/ride/handler"
@@ -13,49 +15,60 @@ import (
 	ridermq "ride-hail-system/internal/ride/rmq"
 	"ride-hail-system/internal/ride/service"
 	ridews "ride-hail-system/internal/ride/websocket"
-	"ride-hail-system/internal/user/jwt"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func RunRide(
+	ctx context.Context,
 	cfg *config.Config,
-	conn *pgx.Conn,
+	conn *pgxpool.Pool,
 	commonMq *commonrmq.RabbitMQ,
+	outboxStore *outbox.Store,
+	consumerOpts commonrmq.ConsumerOptions,
 	mux *http.ServeMux,
 	hub *websocket.Hub,
 	wsMux *http.ServeMux,
-	jwtManager *jwt.Manager,
+	authn *auth.Authenticator,
+	contacts service.ContactRegistry,
 ) {
 	logger.SetServiceName("ride-service")
 
 	logger.Info("startup", "Starting Ride Service...", "", "")
 
-	rmqClient, err := ridermq.NewClient(commonMq.URL, "ride_topic")
+	bus, err := commonrmq.NewAMQPBus(commonMq.Conn)
 	if err != nil {
 		logger.Error("init_rmq_client", "Failed to init ride RMQ client", "", "", err.Error())
 		return
 	}
+	rmqClient := ridermq.NewClient(bus, commonrmq.ExchangeRide, outboxStore, consumerOpts)
 
 	repo := repository.NewRideRepository(conn)
-	svc := service.NewRideManager(repo, rmqClient, hub)
-	h := ridehttp.NewRideHandler(svc, jwtManager)
+	svc := service.NewRideManager(repo, rmqClient, hub, contacts)
+	h := ridehttp.NewRideHandler(svc)
 
-	go func() {
-		logger.Info("listener_driver", "Listening for driver responses...", "", "")
-		svc.ListenForDriver(context.Background(), "driver_responses")
-	}()
+	logger.Info("listener_driver", "Listening for driver responses...", "", "")
+	svc.ListenForDriver(ctx, commonrmq.QueueDriverResponses)
 
-	go func() {
-		logger.Info("listener_location", "Listening for location updates...", "", "")
-		svc.LocationUpdate(context.Background(), "location_updates_ride")
-	}()
+	logger.Info("listener_location", "Listening for location updates...", "", "")
+	svc.LocationUpdate(ctx, commonrmq.QueueLocationUpdates)
 
-	mux.HandleFunc("POST /rides", h.CreateRide)
-	mux.HandleFunc("POST /rides/{ride_id}/cancel", h.CancelRide)
+	// Цикл публикации ответов пассажиров один на сервис, а не на соединение.
+	go svc.SendPassInfo(ctx)
 
+	mux.HandleFunc("POST /rides", authn.Require(auth.PermRidesWrite, h.CreateRide))
+	mux.HandleFunc("POST /rides/{ride_id}/cancel", authn.Require(auth.PermRidesWrite, h.CancelRide))
+	// Запасной канал событий поездки для сетей, где заблокирован WebSocket.
+	mux.HandleFunc("GET /rides/{ride_id}/events", authn.Require(auth.PermRideEvents, func(w http.ResponseWriter, r *http.Request) {
+		ridews.PassengerEventsHandler(ctx, w, r, hub, svc)
+	}))
+	mux.HandleFunc("GET /rides/{ride_id}/events/poll", authn.Require(auth.PermRideEvents, func(w http.ResponseWriter, r *http.Request) {
+		ridews.PassengerPollHandler(ctx, w, r, hub, svc)
+	}))
+
+	ridews.RegisterHandlers(hub, svc)
 	wsMux.HandleFunc("/ws/passengers/", func(w http.ResponseWriter, r *http.Request) {
-		ridews.PassengerWSHandler(w, r, hub, jwtManager, svc)
+		ridews.PassengerWSHandler(ctx, w, r, hub, authn)
 	})
 
 	logger.Info("startup_complete", "Ride Service started successfully", "", "")
@@ -2,17 +2,22 @@ package user_service
 
 import (
 	"net/http"
+	"time"
 
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/config"
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/user/handler"
 	"ride-hail-system/internal/user/jwt"
+	"ride-hail-system/internal/user/mailer"
+	"ride-hail-system/internal/user/model"
 	"ride-hail-system/internal/user/repository"
 	"ride-hail-system/internal/user/service"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RunUser(db *pgx.Conn, mux *http.ServeMux, jwtManager *jwt.Manager) {
+func RunUser(cfg *config.Config, db *pgxpool.Pool, mux *http.ServeMux, jwtManager *jwt.Manager, authn *auth.Authenticator, revocations *service.RevocationList) *service.AuthService {
 	logger.SetServiceName("user-service")
 
 	logger.Info("startup", "Starting User Service...", "", "")
@@ -20,15 +25,74 @@ func RunUser(db *pgx.Conn, mux *http.ServeMux, jwtManager *jwt.Manager) {
 	userRepo := repository.NewUserRepository(db)
 	if userRepo == nil {
 		logger.Error("init_repository", "Failed to initialize user repository", "", "", "repository is nil")
-		return
+		return nil
 	}
 
-	authService := service.NewAuthService(userRepo, jwtManager)
-	authHandler := handler.NewAuthHandler(authService)
+	tokenRepo := repository.NewTokenRepository(db)
 
+	passwords := service.NewPasswordHasher(cfg.Password.Argon2Time, cfg.Password.Argon2MemoryKiB, cfg.Password.Argon2Threads)
+	auditRepo := repository.NewAuditRepository(db)
+	throttle := service.NewLoginThrottle(auditRepo, service.ThrottleOptions{
+		AccountThreshold: cfg.Login.AccountThreshold,
+		IPThreshold:      cfg.Login.IPThreshold,
+		LockoutBase:      time.Duration(cfg.Login.LockoutBaseSeconds) * time.Second,
+		LockoutMax:       time.Duration(cfg.Login.LockoutMaxMinutes) * time.Minute,
+	})
+	var requiredFor []model.Role
+	if cfg.TwoFactor.RequiredForAdmin {
+		requiredFor = append(requiredFor, model.RoleAdmin)
+	}
+	twoFactor := service.NewTwoFactorService(repository.NewTwoFactorRepository(db), userRepo, passwords, auditRepo, cfg.TwoFactor.Issuer, requiredFor...)
+	authService := service.NewAuthService(userRepo, tokenRepo, jwtManager, revocations, passwords, auditRepo, throttle, twoFactor)
+	// Настоящая отправка почты подключается реализацией service.Mailer.
+	var mail service.Mailer = mailer.NewLog()
+	if cfg.Mail.Dir != "" {
+		dir, err := mailer.NewDir(cfg.Mail.Dir, cfg.Mail.From)
+		if err != nil {
+			logger.Error("init_mailer", "Failed to initialize mail directory", "", "", err.Error())
+			return nil
+		}
+		mail = dir
+	}
+	profileService := service.NewProfileService(userRepo, passwords, authService, mail, emailTokenSigner(cfg))
+	profileHandler := handler.NewProfileHandler(profileService)
+	authHandler := handler.NewAuthHandler(authService, profileService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactor)
+
+	keysHandler := handler.NewKeysHandler(jwtManager)
+
+	mux.HandleFunc("GET /.well-known/jwks.json", keysHandler.JWKS)
 	mux.HandleFunc("POST /register", authHandler.Register)
 	mux.HandleFunc("POST /login", authHandler.Login)
+	mux.HandleFunc("POST /login/2fa", authHandler.LoginTwoFactor)
 	mux.HandleFunc("POST /refresh", authHandler.RefreshToken)
+	mux.HandleFunc("POST /logout", authn.Authenticated(authHandler.Logout))
+	mux.HandleFunc("POST /logout-all", authn.Authenticated(authHandler.LogoutAll))
+
+	mux.HandleFunc("GET /me", authn.Authenticated(profileHandler.GetMe))
+	mux.HandleFunc("PATCH /me", authn.Authenticated(profileHandler.UpdateMe))
+	mux.HandleFunc("POST /me/password", authn.Authenticated(profileHandler.ChangePassword))
+	mux.HandleFunc("POST /me/email", authn.Authenticated(profileHandler.RequestEmailChange))
+	mux.HandleFunc("GET /me/2fa", authn.Authenticated(twoFactorHandler.Status))
+	mux.HandleFunc("POST /me/2fa/enroll", authn.Authenticated(twoFactorHandler.Enroll))
+	mux.HandleFunc("POST /me/2fa/confirm", authn.Authenticated(twoFactorHandler.Confirm))
+	mux.HandleFunc("POST /me/2fa/disable", authn.Authenticated(twoFactorHandler.Disable))
+	mux.HandleFunc("POST /me/2fa/recovery-codes", authn.Authenticated(twoFactorHandler.RegenerateRecoveryCodes))
+	mux.HandleFunc("POST /email/confirm", profileHandler.ConfirmEmailChange)
+	mux.HandleFunc("POST /email/verify", profileHandler.VerifyEmail)
+	mux.HandleFunc("POST /email/verify/resend", authn.Authenticated(profileHandler.ResendVerification))
+	mux.HandleFunc("POST /password/forgot", profileHandler.ForgotPassword)
+	mux.HandleFunc("POST /password/reset", profileHandler.ResetPassword)
 
 	logger.Info("startup_complete", "User Service started successfully", "", "")
+	return authService
+}
+
+// emailTokenSigner возвращает nil без EMAIL_TOKEN_SECRET: ссылки из писем отключены.
+func emailTokenSigner(cfg *config.Config) *service.EmailTokenSigner {
+	if cfg.Mail.TokenSecret == "" {
+		logger.Error("init_mail", "Email verification, email change and password reset are disabled", "", "", "EMAIL_TOKEN_SECRET is not set")
+		return nil
+	}
+	return service.NewEmailTokenSigner([]byte(cfg.Mail.TokenSecret))
 }
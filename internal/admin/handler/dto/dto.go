@@ -63,3 +63,8 @@ type LocationResponse struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
+
+type UserStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
@@ -2,12 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"ride-hail-system/internal/admin/handler/dto"
 	"ride-hail-system/internal/admin/model"
+	"ride-hail-system/internal/admin/repository"
 	"ride-hail-system/internal/admin/service"
 	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/websocket"
 )
 
 type AdminHandler struct {
@@ -113,3 +118,151 @@ func (h *AdminHandler) GetSystemMetrics(w http.ResponseWriter, r *http.Request)
 
 	logger.Info(action, "System metrics retrieved successfully", requestID, "")
 }
+
+func (h *AdminHandler) GetQueueStats(w http.ResponseWriter, r *http.Request) {
+	const action = "GetQueueStats"
+	requestID := r.Header.Get("X-Request-ID")
+
+	stats := h.service.GetQueueStats(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logger.Error(action, "Failed to encode response", requestID, "", err.Error())
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info(action, "Queue stats retrieved successfully", requestID, "")
+}
+
+func (h *AdminHandler) GetUserSessions(w http.ResponseWriter, r *http.Request) {
+	const action = "GetUserSessions"
+	requestID := r.Header.Get("X-Request-ID")
+	userID := r.PathValue("user_id")
+
+	sessions, err := h.service.GetUserSessions(r.Context(), userID)
+	if err != nil {
+		logger.Error(action, "Failed to get user sessions", requestID, "", err.Error())
+		http.Error(w, "Failed to get user sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		logger.Error(action, "Failed to encode response", requestID, "", err.Error())
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info(action, "User sessions retrieved successfully", requestID, "")
+}
+
+func (h *AdminHandler) DisconnectSession(w http.ResponseWriter, r *http.Request) {
+	const action = "DisconnectSession"
+	requestID := r.Header.Get("X-Request-ID")
+	sessionID := r.PathValue("session_id")
+
+	if err := h.service.DisconnectSession(r.Context(), sessionID); err != nil {
+		if errors.Is(err, websocket.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		logger.Error(action, "Failed to disconnect session", requestID, "", err.Error())
+		http.Error(w, "Failed to disconnect session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info(action, "Session "+sessionID+" disconnected", requestID, "")
+}
+
+func (h *AdminHandler) SetUserStatus(w http.ResponseWriter, r *http.Request) {
+	const action = "SetUserStatus"
+	requestID := r.Header.Get("X-Request-ID")
+	userID := r.PathValue("user_id")
+
+	var req dto.UserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.service.SetUserStatus(r.Context(), userID, req.Status, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidUserStatus):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repository.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			logger.Error(action, "Failed to set user status", requestID, "", err.Error())
+			http.Error(w, "Failed to set user status", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(change); err != nil {
+		logger.Error(action, "Failed to encode response", requestID, "", err.Error())
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info(action, "User "+userID+" status set to "+req.Status, requestID, "")
+}
+
+// ListAuthEvents — журнал аутентификации.
+func (h *AdminHandler) ListAuthEvents(w http.ResponseWriter, r *http.Request) {
+	const action = "ListAuthEvents"
+	requestID := r.Header.Get("X-Request-ID")
+	q := r.URL.Query()
+
+	filter := model.AuthEventFilter{
+		UserID: q.Get("user_id"),
+		Email:  q.Get("email"),
+		IP:     q.Get("ip"),
+		Event:  q.Get("event"),
+	}
+	var err error
+	if filter.Since, err = parseTimeParam(q.Get("since")); err != nil {
+		http.Error(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = parseTimeParam(q.Get("until")); err != nil {
+		http.Error(w, "until must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+
+	response, err := h.service.ListAuthEvents(r.Context(), filter, page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAuthEvent) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Error(action, "Failed to get auth events", requestID, "", err.Error())
+		http.Error(w, "Failed to get auth events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error(action, "Failed to encode response", requestID, "", err.Error())
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info(action, "Auth events retrieved successfully", requestID, "")
+}
+
+func parseTimeParam(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
+)
 
 type SystemOverview struct {
 	Timestamp          time.Time      `json:"timestamp"`
@@ -64,3 +69,59 @@ type Location struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
+
+type UserSessions struct {
+	UserID   string                  `json:"user_id"`
+	Sessions []websocket.SessionInfo `json:"sessions"`
+}
+
+// UserStatusChange — результат смены статуса учётной записи администратором.
+type UserStatusChange struct {
+	UserID               string `json:"user_id"`
+	Role                 string `json:"role"`
+	Status               string `json:"status"`
+	DriverWentOffline    bool   `json:"driver_went_offline"`
+	SessionsDisconnected int    `json:"sessions_disconnected"`
+}
+
+// AuthEvent — запись журнала аутентификации: вход, неудачная попытка, обновление токенов или выход.
+type AuthEvent struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Event     string    `json:"event"`
+	UserID    *string   `json:"user_id,omitempty"`
+	Email     *string   `json:"email,omitempty"`
+	SessionID *string   `json:"session_id,omitempty"`
+	IP        *string   `json:"ip,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	Reason    *string   `json:"reason,omitempty"`
+}
+
+// AuthEventFilter — условия выборки журнала; пустые поля не ограничивают.
+type AuthEventFilter struct {
+	UserID string
+	Email  string
+	IP     string
+	Event  string
+	Since  *time.Time
+	Until  *time.Time
+}
+
+type AuthEventsResponse struct {
+	Events     []AuthEvent `json:"events"`
+	TotalCount int         `json:"total_count"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+}
+
+type QueueStats struct {
+	Timestamp time.Time           `json:"timestamp"`
+	Consumers []rmq.ConsumerStats `json:"consumers"`
+}
+
+// MetricsSnapshot — периодический снимок для живой ленты администратора.
+type MetricsSnapshot struct {
+	Timestamp time.Time           `json:"timestamp"`
+	Overview  *SystemOverview     `json:"overview,omitempty"`
+	Queues    []rmq.ConsumerStats `json:"queues"`
+}
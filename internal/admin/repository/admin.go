@@ -2,19 +2,23 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"ride-hail-system/internal/admin/model"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type AdminRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewAdminRepository(db *pgx.Conn) *AdminRepository {
+func NewAdminRepository(db *pgxpool.Pool) *AdminRepository {
 	return &AdminRepository{db: db}
 }
 
@@ -275,3 +279,95 @@ func (r *AdminRepository) GetSystemMetrics(ctx context.Context) (*model.SystemMe
 
 	return metrics, nil
 }
+
+// SetUserStatus меняет статус и снимает заблокированного водителя с линии.
+func (r *AdminRepository) SetUserStatus(ctx context.Context, userID, status string) (string, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var role string
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET status = $2, updated_at = now()
+		WHERE id = $1
+		RETURNING role
+	`, userID, status).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", false, ErrUserNotFound
+		}
+		return "", false, fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	offline := false
+	if status != "ACTIVE" {
+		tag, err := tx.Exec(ctx, `
+			UPDATE drivers SET status = 'OFFLINE', updated_at = now()
+			WHERE id = $1 AND status = 'AVAILABLE'
+		`, userID)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to take driver offline: %w", err)
+		}
+		if offline = tag.RowsAffected() > 0; offline {
+			if _, err := tx.Exec(ctx, `
+				UPDATE driver_sessions SET ended_at = now()
+				WHERE driver_id = $1 AND ended_at IS NULL
+			`, userID); err != nil {
+				return "", false, fmt.Errorf("failed to close driver session: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", false, fmt.Errorf("failed to commit user status: %w", err)
+	}
+	return role, offline, nil
+}
+
+// ListAuthEvents возвращает страницу журнала аутентификации, новые записи первыми.
+func (r *AdminRepository) ListAuthEvents(ctx context.Context, f model.AuthEventFilter, page, pageSize int) (*model.AuthEventsResponse, error) {
+	response := &model.AuthEventsResponse{
+		Page:     page,
+		PageSize: pageSize,
+		Events:   []model.AuthEvent{},
+	}
+
+	const where = `
+		WHERE ($1 = '' OR user_id::text = $1)
+		  AND ($2 = '' OR lower(email) = lower($2))
+		  AND ($3 = '' OR ip = $3)
+		  AND ($4 = '' OR event = $4)
+		  AND ($5::timestamptz IS NULL OR created_at >= $5)
+		  AND ($6::timestamptz IS NULL OR created_at < $6)
+	`
+	args := []any{f.UserID, f.Email, f.IP, f.Event, f.Since, f.Until}
+
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM auth_events`+where, args...).Scan(&response.TotalCount); err != nil {
+		return nil, fmt.Errorf("failed to count auth events: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, created_at, event, user_id::text, email, session_id::text, ip, user_agent, reason
+		FROM auth_events`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7 OFFSET $8
+	`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e model.AuthEvent
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Event, &e.UserID, &e.Email, &e.SessionID, &e.IP, &e.UserAgent, &e.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan auth event: %w", err)
+		}
+		response.Events = append(response.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read auth events: %w", err)
+	}
+	return response, nil
+}
@@ -0,0 +1,68 @@
+package rmq
+
+import (
+	"context"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/rmq"
+)
+
+type Client struct {
+	Bus     rmq.Consumer
+	Options rmq.ConsumerOptions
+}
+
+func NewClient(bus rmq.Consumer, opts rmq.ConsumerOptions) *Client {
+	return &Client{Bus: bus, Options: opts}
+}
+
+func (c *Client) ConsumeRideStatus(ctx context.Context, queueName string, handler func(msg rmq.RideStatusUpdateMessage) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypeRideStatus, PartitionBy: "ride_id"}
+
+	err := rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.RideStatusUpdateMessage
+		if err := env.Decode(&msg); err != nil {
+			return err
+		}
+		return handler(msg)
+	})
+	if err != nil {
+		logger.Error("rmq_consume_failed", "Failed to start consuming ride statuses", queueName, "", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (c *Client) ConsumeDriverState(ctx context.Context, queueName string, handler func(msg rmq.DriverStateMessage) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypeDriverState, PartitionBy: "driver_id"}
+
+	err := rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.DriverStateMessage
+		if err := env.Decode(&msg); err != nil {
+			return err
+		}
+		return handler(msg)
+	})
+	if err != nil {
+		logger.Error("rmq_consume_failed", "Failed to start consuming driver states", queueName, "", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (c *Client) ConsumeLocations(ctx context.Context, queueName string, handler func(msg rmq.LocationUpdateMessage) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypeLocationUpdate, PartitionBy: "driver_id"}
+
+	err := rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.LocationUpdateMessage
+		if err := env.Decode(&msg); err != nil {
+			return err
+		}
+		return handler(msg)
+	})
+	if err != nil {
+		logger.Error("rmq_consume_failed", "Failed to start consuming driver locations", queueName, "", err.Error())
+		return err
+	}
+	return nil
+}
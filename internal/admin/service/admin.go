@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"ride-hail-system/internal/admin/model"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
+	usermodel "ride-hail-system/internal/user/model"
 )
 
 type AdminRepository interface {
@@ -11,14 +17,36 @@ type AdminRepository interface {
 	GetActiveRides(ctx context.Context, page, pageSize int) (*model.ActiveRidesResponse, error)
 	GetOnlineDrivers(ctx context.Context) ([]model.OnlineDriver, error)
 	GetSystemMetrics(ctx context.Context) (*model.SystemMetrics, error)
+	SetUserStatus(ctx context.Context, userID, status string) (role string, driverOffline bool, err error)
+	ListAuthEvents(ctx context.Context, f model.AuthEventFilter, page, pageSize int) (*model.AuthEventsResponse, error)
 }
 
+// SessionManager — сессии WebSocket на всех узлах; реализуется websocket.Cluster.
+type SessionManager interface {
+	Sessions(ctx context.Context, userID string) ([]websocket.SessionInfo, error)
+	Disconnect(ctx context.Context, sessionID string) error
+}
+
+// TokenRevoker отзывает токены пользователя; реализуется сервисом пользователей.
+type TokenRevoker interface {
+	RevokeUser(ctx context.Context, userID, reason string) error
+}
+
+var (
+	ErrInvalidUserStatus = errors.New("status must be ACTIVE, INACTIVE or BANNED")
+	ErrInvalidAuthEvent  = errors.New("unknown auth event type")
+)
+
 type AdminService struct {
-	repo AdminRepository
+	repo     AdminRepository
+	metrics  *rmq.Metrics
+	depth    rmq.DepthInspector
+	sessions SessionManager
+	tokens   TokenRevoker
 }
 
-func NewAdminService(repo AdminRepository) *AdminService {
-	return &AdminService{repo: repo}
+func NewAdminService(repo AdminRepository, metrics *rmq.Metrics, depth rmq.DepthInspector, sessions SessionManager, tokens TokenRevoker) *AdminService {
+	return &AdminService{repo: repo, metrics: metrics, depth: depth, sessions: sessions, tokens: tokens}
 }
 
 func (s *AdminService) GetSystemOverview(ctx context.Context) (*model.SystemOverview, error) {
@@ -56,3 +84,90 @@ func (s *AdminService) GetOnlineDrivers(ctx context.Context) ([]model.OnlineDriv
 func (s *AdminService) GetSystemMetrics(ctx context.Context) (*model.SystemMetrics, error) {
 	return s.repo.GetSystemMetrics(ctx)
 }
+
+// GetQueueStats возвращает глубину очередей и задержку обработки консьюмеров.
+func (s *AdminService) GetQueueStats(ctx context.Context) *model.QueueStats {
+	consumers := s.metrics.Snapshot(s.depth)
+	if consumers == nil {
+		consumers = make([]rmq.ConsumerStats, 0)
+	}
+	return &model.QueueStats{
+		Timestamp: time.Now().UTC(),
+		Consumers: consumers,
+	}
+}
+
+// GetUserSessions возвращает активные соединения пользователя.
+func (s *AdminService) GetUserSessions(ctx context.Context, userID string) (*model.UserSessions, error) {
+	sessions, err := s.sessions.Sessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = make([]websocket.SessionInfo, 0)
+	}
+	return &model.UserSessions{UserID: userID, Sessions: sessions}, nil
+}
+
+// DisconnectSession принудительно закрывает одно соединение пользователя.
+func (s *AdminService) DisconnectSession(ctx context.Context, sessionID string) error {
+	return s.sessions.Disconnect(ctx, sessionID)
+}
+
+// SetUserStatus меняет статус; при блокировке отзывает токены и закрывает соединения.
+func (s *AdminService) SetUserStatus(ctx context.Context, userID, status, reason string) (*model.UserStatusChange, error) {
+	switch usermodel.UserStatus(status) {
+	case usermodel.UserActive, usermodel.UserInactive, usermodel.UserBanned:
+	default:
+		return nil, ErrInvalidUserStatus
+	}
+
+	role, offline, err := s.repo.SetUserStatus(ctx, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	change := &model.UserStatusChange{UserID: userID, Role: role, Status: status, DriverWentOffline: offline}
+	if usermodel.UserStatus(status) == usermodel.UserActive {
+		return change, nil
+	}
+
+	if reason == "" {
+		reason = "status " + status
+	}
+	if err := s.tokens.RevokeUser(ctx, userID, reason); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessions.Sessions(ctx, userID)
+	if err != nil {
+		logger.Warn("SetUserStatus", "Failed to list user sessions", "", "", err.Error())
+		return change, nil
+	}
+	for _, session := range sessions {
+		if err := s.sessions.Disconnect(ctx, session.ID); err != nil {
+			logger.Warn("SetUserStatus", "Failed to disconnect session "+session.ID, "", "", err.Error())
+			continue
+		}
+		change.SessionsDisconnected++
+	}
+	return change, nil
+}
+
+// ListAuthEvents ищет по журналу аутентификации.
+func (s *AdminService) ListAuthEvents(ctx context.Context, f model.AuthEventFilter, page, pageSize int) (*model.AuthEventsResponse, error) {
+	switch usermodel.AuthEventType(f.Event) {
+	case "", usermodel.EventLoginSucceeded, usermodel.EventLoginFailed, usermodel.EventLoginLocked,
+		usermodel.EventLoginChallenged, usermodel.EventTokenRefreshed, usermodel.EventRefreshFailed,
+		usermodel.EventRefreshReused, usermodel.EventLogout, usermodel.EventLogoutAll,
+		usermodel.EventTwoFactorOn, usermodel.EventTwoFactorOff:
+	default:
+		return nil, ErrInvalidAuthEvent
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 50
+	}
+	return s.repo.ListAuthEvents(ctx, f, page, pageSize)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ride-hail-system/internal/admin/model"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
+)
+
+// FeedBus — очереди, из которых строится живая лента. Реализуется admin/rmq.Client.
+type FeedBus interface {
+	ConsumeRideStatus(ctx context.Context, queueName string, handler func(msg rmq.RideStatusUpdateMessage) error) error
+	ConsumeDriverState(ctx context.Context, queueName string, handler func(msg rmq.DriverStateMessage) error) error
+	ConsumeLocations(ctx context.Context, queueName string, handler func(msg rmq.LocationUpdateMessage) error) error
+}
+
+// FeedPublisher — доставка в топик admin:ops. Реализуется websocket.Hub.
+type FeedPublisher interface {
+	PublishMessage(topic, msgType string, payload any) (int, error)
+	PublishLocalMessage(topic, msgType string, payload any) (int, error)
+	Count(topic string) (clients, subscribers int)
+}
+
+// OpsFeed пересылает события поездок и водителей в ленту администратора.
+type OpsFeed struct {
+	admin    *AdminService
+	bus      FeedBus
+	hub      FeedPublisher
+	interval time.Duration
+	throttle time.Duration
+
+	mu        sync.Mutex
+	positions map[string]time.Time
+}
+
+func NewOpsFeed(admin *AdminService, bus FeedBus, hub FeedPublisher, interval, throttle time.Duration) *OpsFeed {
+	return &OpsFeed{
+		admin:     admin,
+		bus:       bus,
+		hub:       hub,
+		interval:  interval,
+		throttle:  throttle,
+		positions: make(map[string]time.Time),
+	}
+}
+
+func (f *OpsFeed) Start(ctx context.Context) error {
+	err := f.bus.ConsumeRideStatus(ctx, rmq.QueueAdminRideStatus, func(msg rmq.RideStatusUpdateMessage) error {
+		f.publish(websocket.MsgAdminRideStatus, msg.RideID, msg)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = f.bus.ConsumeDriverState(ctx, rmq.QueueAdminDriverStatus, func(msg rmq.DriverStateMessage) error {
+		if msg.Status == "OFFLINE" {
+			f.forget(msg.DriverID)
+		}
+		f.publish(websocket.MsgAdminDriverStatus, msg.RideID, msg)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = f.bus.ConsumeLocations(ctx, rmq.QueueAdminLocations, func(msg rmq.LocationUpdateMessage) error {
+		if f.allow(msg.DriverID, time.Now()) {
+			f.publish(websocket.MsgAdminDriverLocation, msg.RideID, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go f.runSnapshots(ctx)
+	return nil
+}
+
+// allow пропускает не больше одной позиции водителя за интервал throttle.
+func (f *OpsFeed) allow(driverID string, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if last, ok := f.positions[driverID]; ok && now.Sub(last) < f.throttle {
+		return false
+	}
+	f.positions[driverID] = now
+	return true
+}
+
+func (f *OpsFeed) forget(driverID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.positions, driverID)
+}
+
+// prune удаляет водителей, от которых давно не было координат.
+func (f *OpsFeed) prune(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, last := range f.positions {
+		if now.Sub(last) > time.Minute {
+			delete(f.positions, id)
+		}
+	}
+}
+
+func (f *OpsFeed) publish(msgType, rideID string, payload any) {
+	if _, err := f.hub.PublishMessage(websocket.TopicAdminOps, msgType, payload); err != nil {
+		logger.Warn("admin_feed", "Failed to publish "+msgType, "", rideID, err.Error())
+	}
+}
+
+func (f *OpsFeed) runSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			f.prune(now)
+			// Без подключённых администраторов не нагружаем базу запросами.
+			if _, subscribers := f.hub.Count(websocket.TopicAdminOps); subscribers == 0 {
+				continue
+			}
+			snapshot := f.Snapshot(ctx)
+			if _, err := f.hub.PublishLocalMessage(websocket.TopicAdminOps, websocket.MsgAdminMetrics, snapshot); err != nil {
+				logger.Warn("admin_feed", "Failed to publish metrics snapshot", "", "", err.Error())
+			}
+		}
+	}
+}
+
+// Snapshot собирает обзор системы и статистику консьюмеров.
+func (f *OpsFeed) Snapshot(ctx context.Context) model.MetricsSnapshot {
+	snapshot := model.MetricsSnapshot{
+		Timestamp: time.Now().UTC(),
+		Queues:    f.admin.GetQueueStats(ctx).Consumers,
+	}
+	overview, err := f.admin.GetSystemOverview(ctx)
+	if err != nil {
+		logger.Warn("admin_feed", "Failed to build system overview", "", "", err.Error())
+		return snapshot
+	}
+	snapshot.Overview = overview
+	return snapshot
+}
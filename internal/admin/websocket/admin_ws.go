@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	commonws "ride-hail-system/internal/common/websocket"
+
+	"github.com/gorilla/websocket"
+)
+
+var Upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// AdminWSHandler подключает администратора к живой ленте admin:ops.
+func AdminWSHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, hub *commonws.Hub, authn *auth.Authenticator) {
+	conn, err := Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Error("admin_ws_upgrade", "WebSocket upgrade failed", "", "", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+
+	var authMsg struct {
+		Type  string `json:"type"`
+		Token string `json:"token"`
+	}
+	if err := conn.ReadJSON(&authMsg); err != nil {
+		logger.Error("admin_ws_auth", "failed to read auth message", "", "", err.Error())
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "auth failed"))
+		return
+	}
+
+	claims, err := authn.Verify(authMsg.Token)
+	if err != nil {
+		logger.Warn("admin_ws_token", "invalid token for admin", "", "", err.Error())
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "invalid token"))
+		return
+	}
+	if authn.Permit(claims, auth.PermAdminRead) != nil {
+		logger.Warn("admin_ws_token", "non-admin tried to open the ops feed", "", "", claims.UserID)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "forbidden"))
+		return
+	}
+
+	client := hub.NewClient(commonws.RoleAdmin, claims.UserID, conn)
+	client.RemoteAddr = r.RemoteAddr
+	client.UserAgent = r.UserAgent()
+	hub.Register(client)
+	hub.Subscribe(client, commonws.TopicAdminOps)
+	logger.Info("admin_ws_connect", "admin connected to ops feed", claims.UserID, "")
+
+	go client.WritePump()
+
+	client.ReadPump(ctx)
+	hub.Unregister(client)
+	logger.Info("admin_ws_disconnect", "admin disconnected from ops feed", claims.UserID, "")
+}
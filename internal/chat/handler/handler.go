@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ride-hail-system/internal/chat/service"
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	usermodel "ride-hail-system/internal/user/model"
+)
+
+type ChatHandler struct {
+	service *service.ChatService
+}
+
+func NewChatHandler(s *service.ChatService) *ChatHandler {
+	return &ChatHandler{service: s}
+}
+
+// GetTemplates возвращает быстрые ответы для роли текущего пользователя.
+func (h *ChatHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.ClaimsFrom(r.Context())
+	writeJSON(w, "GetTemplates", h.service.Templates(usermodel.Role(claims.Role)))
+}
+
+// GetHistory возвращает переписку поездки её участнику.
+func (h *ChatHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	const action = "GetChatHistory"
+	rideID := r.PathValue("ride_id")
+
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	messages, err := h.service.History(r.Context(), usermodel.Role(claims.Role), claims.UserID, rideID)
+	if err != nil {
+		if errors.Is(err, service.ErrRideNotFound) || errors.Is(err, service.ErrNotParticipant) {
+			http.Error(w, "ride not found", http.StatusNotFound)
+			return
+		}
+		logger.Error(action, "Failed to get chat history", "", rideID, err.Error())
+		http.Error(w, "Failed to get chat history", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, action, messages)
+}
+
+// GetTranscript отдаёт администратору полную переписку поездки.
+func (h *ChatHandler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	const action = "GetChatTranscript"
+	rideID := r.PathValue("ride_id")
+
+	claims, _ := auth.ClaimsFrom(r.Context())
+	transcript, err := h.service.Transcript(r.Context(), rideID)
+	if err != nil {
+		if errors.Is(err, service.ErrRideNotFound) {
+			http.Error(w, "ride not found", http.StatusNotFound)
+			return
+		}
+		logger.Error(action, "Failed to get chat transcript", "", rideID, err.Error())
+		http.Error(w, "Failed to get chat transcript", http.StatusInternalServerError)
+		return
+	}
+	logger.Info(action, "Chat transcript retrieved by admin "+claims.UserID, "", rideID)
+	writeJSON(w, action, transcript)
+}
+
+func writeJSON(w http.ResponseWriter, action string, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error(action, "Failed to encode response", "", "", err.Error())
+	}
+}
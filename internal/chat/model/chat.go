@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	usermodel "ride-hail-system/internal/user/model"
+)
+
+type Message struct {
+	ID          string         `json:"message_id"`
+	RideID      string         `json:"ride_id"`
+	SenderID    string         `json:"sender_id"`
+	SenderRole  usermodel.Role `json:"sender_role"`
+	ClientID    string         `json:"client_id"`
+	Text        string         `json:"text"`
+	Template    string         `json:"template,omitempty"`
+	SentAt      time.Time      `json:"sent_at"`
+	DeliveredAt *time.Time     `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time     `json:"read_at,omitempty"`
+}
+
+// Ride — участники и статус поездки, к которой привязан чат.
+type Ride struct {
+	ID          string
+	PassengerID string
+	DriverID    string
+	Status      string
+}
+
+type ReceiptStatus string
+
+const (
+	ReceiptDelivered ReceiptStatus = "delivered"
+	ReceiptRead      ReceiptStatus = "read"
+)
+
+// Receipt уведомляет отправителя, что его сообщения доставлены или прочитаны.
+type Receipt struct {
+	RideID     string        `json:"ride_id"`
+	MessageIDs []string      `json:"message_ids"`
+	Status     ReceiptStatus `json:"status"`
+	At         time.Time     `json:"at"`
+}
+
+// Template — готовый быстрый ответ.
+type Template struct {
+	Code string `json:"code"`
+	Text string `json:"text"`
+}
+
+type Closed struct {
+	RideID string `json:"ride_id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type Transcript struct {
+	RideID      string    `json:"ride_id"`
+	PassengerID string    `json:"passenger_id"`
+	DriverID    string    `json:"driver_id,omitempty"`
+	Status      string    `json:"status"`
+	Messages    []Message `json:"messages"`
+}
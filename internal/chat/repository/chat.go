@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/chat/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrRideNotFound = errors.New("ride not found")
+
+type ChatRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewChatRepository(db *pgxpool.Pool) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+func (r *ChatRepository) GetRide(ctx context.Context, rideID string) (model.Ride, error) {
+	ride := model.Ride{ID: rideID}
+
+	query := `
+		SELECT passenger_id::text, coalesce(driver_id::text, ''), coalesce(status, '')
+		FROM rides
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, rideID).Scan(&ride.PassengerID, &ride.DriverID, &ride.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Ride{}, ErrRideNotFound
+		}
+		return model.Ride{}, fmt.Errorf("failed to get ride: %w", err)
+	}
+	return ride, nil
+}
+
+// InsertMessage сохраняет сообщение.
+func (r *ChatRepository) InsertMessage(ctx context.Context, msg model.Message) (model.Message, bool, error) {
+	query := `
+		INSERT INTO chat_messages (ride_id, sender_id, sender_role, client_id, body, template)
+		VALUES ($1, $2, $3, $4, $5, nullif($6, ''))
+		ON CONFLICT (ride_id, sender_id, client_id) DO NOTHING
+		RETURNING id::text, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, msg.RideID, msg.SenderID, msg.SenderRole, msg.ClientID, msg.Text, msg.Template).
+		Scan(&msg.ID, &msg.SentAt)
+	if err == nil {
+		return msg, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return model.Message{}, false, fmt.Errorf("failed to insert chat message: %w", err)
+	}
+
+	existing, err := r.scanMessages(ctx, `
+		SELECT `+messageColumns+`
+		FROM chat_messages
+		WHERE ride_id = $1 AND sender_id = $2 AND client_id = $3
+	`, msg.RideID, msg.SenderID, msg.ClientID)
+	if err != nil {
+		return model.Message{}, false, err
+	}
+	if len(existing) == 0 {
+		return model.Message{}, false, fmt.Errorf("chat message %s disappeared after conflict", msg.ClientID)
+	}
+	return existing[0], false, nil
+}
+
+// MarkReceipt отмечает сообщения собеседника доставленными или прочитанными
+// и возвращает ID изменённых сообщений. Прочитанное считается и доставленным.
+func (r *ChatRepository) MarkReceipt(ctx context.Context, rideID, recipientID string, ids []string, status model.ReceiptStatus, at time.Time) ([]string, error) {
+	var query string
+	switch status {
+	case model.ReceiptDelivered:
+		query = `
+			UPDATE chat_messages
+			SET delivered_at = $4
+			WHERE ride_id = $1 AND sender_id <> $2 AND id::text = any($3) AND delivered_at IS NULL
+			RETURNING id::text
+		`
+	case model.ReceiptRead:
+		query = `
+			UPDATE chat_messages
+			SET read_at = $4, delivered_at = coalesce(delivered_at, $4)
+			WHERE ride_id = $1 AND sender_id <> $2 AND id::text = any($3) AND read_at IS NULL
+			RETURNING id::text
+		`
+	default:
+		return nil, fmt.Errorf("unknown receipt status %q", status)
+	}
+
+	rows, err := r.db.Query(ctx, query, rideID, recipientID, ids, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update chat receipts: %w", err)
+	}
+	defer rows.Close()
+
+	var updated []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan chat receipt: %w", err)
+		}
+		updated = append(updated, id)
+	}
+	return updated, rows.Err()
+}
+
+func (r *ChatRepository) ListMessages(ctx context.Context, rideID string) ([]model.Message, error) {
+	return r.scanMessages(ctx, `
+		SELECT `+messageColumns+`
+		FROM chat_messages
+		WHERE ride_id = $1
+		ORDER BY created_at, id
+	`, rideID)
+}
+
+const messageColumns = `id::text, ride_id::text, sender_id::text, sender_role, client_id, body,
+		coalesce(template, ''), created_at, delivered_at, read_at`
+
+func (r *ChatRepository) scanMessages(ctx context.Context, query string, args ...any) ([]model.Message, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.Message
+	for rows.Next() {
+		var m model.Message
+		if err := rows.Scan(&m.ID, &m.RideID, &m.SenderID, &m.SenderRole, &m.ClientID, &m.Text,
+			&m.Template, &m.SentAt, &m.DeliveredAt, &m.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
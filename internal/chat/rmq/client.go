@@ -0,0 +1,35 @@
+package rmq
+
+import (
+	"context"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/rmq"
+)
+
+type Client struct {
+	Bus     rmq.Consumer
+	Options rmq.ConsumerOptions
+}
+
+func NewClient(bus rmq.Consumer, opts rmq.ConsumerOptions) *Client {
+	return &Client{Bus: bus, Options: opts}
+}
+
+func (c *Client) ConsumeRideStatus(ctx context.Context, queueName string, handler func(msg rmq.RideStatusUpdateMessage) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypeRideStatus, PartitionBy: "ride_id"}
+
+	err := rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.RideStatusUpdateMessage
+		if err := env.Decode(&msg); err != nil {
+			return err
+		}
+		logger.Info("rmq_message_received", "Ride status received", queueName, msg.RideID)
+		return handler(msg)
+	})
+	if err != nil {
+		logger.Error("rmq_consume_failed", "Failed to start consuming ride statuses", queueName, "", err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ride-hail-system/internal/chat/model"
+	"ride-hail-system/internal/chat/repository"
+	"ride-hail-system/internal/common/logger"
+	commonmq "ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
+	ridemodel "ride-hail-system/internal/ride/model"
+	usermodel "ride-hail-system/internal/user/model"
+	"ride-hail-system/pkg/uuid"
+)
+
+type ChatRepository interface {
+	GetRide(ctx context.Context, rideID string) (model.Ride, error)
+	InsertMessage(ctx context.Context, msg model.Message) (model.Message, bool, error)
+	MarkReceipt(ctx context.Context, rideID, recipientID string, ids []string, status model.ReceiptStatus, at time.Time) ([]string, error)
+	ListMessages(ctx context.Context, rideID string) ([]model.Message, error)
+}
+
+// MessageBus — операции шины, нужные чату. Реализуется chat/rmq.Client.
+type MessageBus interface {
+	ConsumeRideStatus(ctx context.Context, queueName string, handler func(msg commonmq.RideStatusUpdateMessage) error) error
+}
+
+// Publisher доставляет сообщения в WebSocket-топики. Реализуется websocket.Hub.
+type Publisher interface {
+	PublishMessage(topic, msgType string, payload any) (int, error)
+}
+
+var (
+	ErrRideNotFound    = repository.ErrRideNotFound
+	ErrNotParticipant  = errors.New("user is not a participant of the ride")
+	ErrChatClosed      = errors.New("chat is not available for the ride in its current status")
+	ErrEmptyMessage    = errors.New("message text or template is required")
+	ErrMessageTooLong  = fmt.Errorf("message is longer than %d characters", maxMessageLength)
+	ErrUnknownTemplate = errors.New("unknown quick-reply template")
+	ErrInvalidReceipt  = errors.New("receipt status must be delivered or read")
+)
+
+const maxMessageLength = 1000
+
+// defaultTemplates — быстрые ответы для каждой стороны поездки.
+var defaultTemplates = map[usermodel.Role][]model.Template{
+	usermodel.RoleDriver: {
+		{Code: "on_my_way", Text: "I'm on my way."},
+		{Code: "arrived", Text: "I've arrived at the pickup point."},
+		{Code: "running_late", Text: "I'm running a few minutes late, sorry."},
+		{Code: "cant_find", Text: "I can't find you. Where exactly are you?"},
+	},
+	usermodel.RolePassenger: {
+		{Code: "coming", Text: "I'm coming out now."},
+		{Code: "wait_please", Text: "Please wait a couple of minutes."},
+		{Code: "where_are_you", Text: "Where are you?"},
+		{Code: "at_pickup", Text: "I'm at the pickup point."},
+	},
+}
+
+// chatOpen — статусы, в которых участники могут переписываться: от
+// назначения водителя до завершения поездки.
+func chatOpen(status string) bool {
+	switch ridemodel.RideStatus(status) {
+	case ridemodel.RideMatched, ridemodel.RideEnRoute, ridemodel.RideArrived, ridemodel.RideInProgress:
+		return true
+	}
+	return false
+}
+
+type ChatService struct {
+	repo  ChatRepository
+	mq    MessageBus
+	wsHub Publisher
+}
+
+func NewChatService(repo ChatRepository, mq MessageBus, wsHub Publisher) *ChatService {
+	return &ChatService{repo: repo, mq: mq, wsHub: wsHub}
+}
+
+// SendRequest — сообщение от участника. Если указан Template, текст берётся из шаблона.
+type SendRequest struct {
+	RideID   string `json:"ride_id"`
+	ClientID string `json:"client_id"`
+	Text     string `json:"text"`
+	Template string `json:"template"`
+}
+
+// Templates возвращает быстрые ответы для роли.
+func (s *ChatService) Templates(role usermodel.Role) []model.Template {
+	templates := defaultTemplates[role]
+	if templates == nil {
+		return []model.Template{}
+	}
+	return templates
+}
+
+// Send сохраняет сообщение и доставляет его обоим участникам.
+func (s *ChatService) Send(ctx context.Context, role usermodel.Role, userID string, req SendRequest) (model.Message, error) {
+	const action = "chat_send"
+
+	ride, err := s.participant(ctx, role, userID, req.RideID)
+	if err != nil {
+		return model.Message{}, err
+	}
+	if !chatOpen(ride.Status) {
+		return model.Message{}, ErrChatClosed
+	}
+
+	text := strings.TrimSpace(req.Text)
+	if req.Template != "" {
+		tpl, ok := s.template(role, req.Template)
+		if !ok {
+			return model.Message{}, ErrUnknownTemplate
+		}
+		text = tpl.Text
+	}
+	if text == "" {
+		return model.Message{}, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > maxMessageLength {
+		return model.Message{}, ErrMessageTooLong
+	}
+
+	clientID := req.ClientID
+	if clientID == "" {
+		if clientID, err = uuid.NewUUID(); err != nil {
+			return model.Message{}, fmt.Errorf("failed to generate client id: %w", err)
+		}
+	}
+
+	msg, created, err := s.repo.InsertMessage(ctx, model.Message{
+		RideID:     ride.ID,
+		SenderID:   userID,
+		SenderRole: role,
+		ClientID:   clientID,
+		Text:       text,
+		Template:   req.Template,
+	})
+	if err != nil {
+		logger.Error(action, "Failed to save chat message", "", ride.ID, err.Error())
+		return model.Message{}, err
+	}
+	if created {
+		logger.Info(action, "Chat message sent by "+string(role), "", ride.ID)
+	}
+
+	s.deliver(ride, websocket.MsgChatMessage, msg)
+	return msg, nil
+}
+
+// Receipt отмечает сообщения собеседника доставленными или прочитанными и уведомляет обе стороны.
+func (s *ChatService) Receipt(ctx context.Context, role usermodel.Role, userID, rideID string, ids []string, status model.ReceiptStatus) error {
+	if status != model.ReceiptDelivered && status != model.ReceiptRead {
+		return ErrInvalidReceipt
+	}
+	ride, err := s.participant(ctx, role, userID, rideID)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	at := time.Now().UTC()
+	updated, err := s.repo.MarkReceipt(ctx, ride.ID, userID, ids, status, at)
+	if err != nil {
+		logger.Error("chat_receipt", "Failed to update chat receipts", "", ride.ID, err.Error())
+		return err
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+
+	s.deliver(ride, websocket.MsgChatReceipt, model.Receipt{
+		RideID:     ride.ID,
+		MessageIDs: updated,
+		Status:     status,
+		At:         at,
+	})
+	return nil
+}
+
+// History возвращает переписку поездки её участнику, в том числе после закрытия чата.
+func (s *ChatService) History(ctx context.Context, role usermodel.Role, userID, rideID string) ([]model.Message, error) {
+	ride, err := s.participant(ctx, role, userID, rideID)
+	if err != nil {
+		return nil, err
+	}
+	return s.messages(ctx, ride.ID)
+}
+
+// Transcript — полная переписка поездки для разбора жалоб администратором.
+func (s *ChatService) Transcript(ctx context.Context, rideID string) (model.Transcript, error) {
+	ride, err := s.repo.GetRide(ctx, rideID)
+	if err != nil {
+		return model.Transcript{}, err
+	}
+	messages, err := s.messages(ctx, ride.ID)
+	if err != nil {
+		return model.Transcript{}, err
+	}
+	return model.Transcript{
+		RideID:      ride.ID,
+		PassengerID: ride.PassengerID,
+		DriverID:    ride.DriverID,
+		Status:      ride.Status,
+		Messages:    messages,
+	}, nil
+}
+
+// ListenRideStatus закрывает чат, когда поездка завершена или отменена.
+func (s *ChatService) ListenRideStatus(ctx context.Context, queueName string) {
+	err := s.mq.ConsumeRideStatus(ctx, queueName, func(msg commonmq.RideStatusUpdateMessage) error {
+		status := ridemodel.RideStatus(msg.Status)
+		if status != ridemodel.RideCompleted && status != ridemodel.RideCancelled {
+			return nil
+		}
+
+		ride, err := s.repo.GetRide(ctx, msg.RideID)
+		if err != nil {
+			logger.Error("chat_close", "Failed to load ride for chat closure", "", msg.RideID, err.Error())
+			return err
+		}
+		if ride.DriverID == "" && msg.DriverID != "" {
+			ride.DriverID = msg.DriverID
+		}
+
+		s.deliver(ride, websocket.MsgChatClosed, model.Closed{
+			RideID: ride.ID,
+			Status: msg.Status,
+			Reason: msg.Message,
+		})
+		logger.Info("chat_close", "Chat closed with ride status "+msg.Status, "", ride.ID)
+		return nil
+	})
+	if err != nil {
+		logger.Error("chat_listen_status", "Failed to consume ride statuses", queueName, "", err.Error())
+	}
+}
+
+func (s *ChatService) participant(ctx context.Context, role usermodel.Role, userID, rideID string) (model.Ride, error) {
+	ride, err := s.repo.GetRide(ctx, rideID)
+	if err != nil {
+		return model.Ride{}, err
+	}
+	switch {
+	case role == usermodel.RolePassenger && ride.PassengerID == userID:
+	case role == usermodel.RoleDriver && ride.DriverID != "" && ride.DriverID == userID:
+	default:
+		return model.Ride{}, ErrNotParticipant
+	}
+	return ride, nil
+}
+
+func (s *ChatService) template(role usermodel.Role, code string) (model.Template, bool) {
+	for _, tpl := range defaultTemplates[role] {
+		if tpl.Code == code {
+			return tpl, true
+		}
+	}
+	return model.Template{}, false
+}
+
+func (s *ChatService) messages(ctx context.Context, rideID string) ([]model.Message, error) {
+	messages, err := s.repo.ListMessages(ctx, rideID)
+	if err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []model.Message{}
+	}
+	return messages, nil
+}
+
+// deliver отправляет событие чата обоим участникам, включая другие устройства отправителя.
+func (s *ChatService) deliver(ride model.Ride, msgType string, payload any) {
+	topics := []string{websocket.PassengerTopic(ride.PassengerID)}
+	if ride.DriverID != "" {
+		topics = append(topics, websocket.DriverTopic(ride.DriverID))
+	}
+	for _, topic := range topics {
+		if _, err := s.wsHub.PublishMessage(topic, msgType, payload); err != nil {
+			logger.Warn("chat_deliver", "Failed to deliver "+msgType, "", ride.ID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"ride-hail-system/internal/chat/model"
+	"ride-hail-system/internal/chat/service"
+	commonws "ride-hail-system/internal/common/websocket"
+	usermodel "ride-hail-system/internal/user/model"
+)
+
+// hubRoles сопоставляет роль клиента хаба с ролью пользователя.
+var hubRoles = map[string]usermodel.Role{
+	commonws.RoleDriver:    usermodel.RoleDriver,
+	commonws.RolePassenger: usermodel.RolePassenger,
+}
+
+// RegisterHandlers подключает сообщения чата к соединениям водителей и пассажиров.
+func RegisterHandlers(hub *commonws.Hub, svc *service.ChatService) {
+	for hubRole, role := range hubRoles {
+		hub.Handle(hubRole, commonws.MsgChatSend, func(ctx context.Context, c *commonws.Client, msg commonws.Message) error {
+			var req service.SendRequest
+			if err := json.Unmarshal(msg.Payload, &req); err != nil {
+				return err
+			}
+			_, err := svc.Send(ctx, role, c.UserID, req)
+			return err
+		})
+
+		hub.Handle(hubRole, commonws.MsgChatMark, func(ctx context.Context, c *commonws.Client, msg commonws.Message) error {
+			var receipt model.Receipt
+			if err := json.Unmarshal(msg.Payload, &receipt); err != nil {
+				return err
+			}
+			return svc.Receipt(ctx, role, c.UserID, receipt.RideID, receipt.MessageIDs, receipt.Status)
+		})
+	}
+}
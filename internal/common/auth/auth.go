@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/user/jwt"
+)
+
+var (
+	ErrMissingToken = errors.New("missing Authorization header")
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrRevokedToken = errors.New("token has been revoked")
+	ErrForbidden    = errors.New("forbidden: not authorized")
+	// ErrEmailNotVerified — роль действие разрешает, но адрес не подтверждён.
+	ErrEmailNotVerified = errors.New("forbidden: email is not verified")
+	// ErrTwoFactorRequired — роль обязана пользоваться 2FA, а она не настроена.
+	ErrTwoFactorRequired = errors.New("forbidden: two-factor authentication must be set up first")
+)
+
+// Verifier проверяет access-токен.
+type Verifier interface {
+	ValidateAccessToken(token string) (*jwt.Claims, error)
+}
+
+// Revoker сообщает, отозван ли ещё не истёкший access-токен: после выхода
+// из сессии или блокировки пользователя.
+type Revoker interface {
+	Revoked(userID, sessionID string, issuedAt time.Time) bool
+}
+
+type ctxKey struct{}
+
+// WithClaims кладёт claims в контекст запроса.
+func WithClaims(ctx context.Context, claims jwt.Claims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+// ClaimsFrom возвращает claims, положенные middleware.
+func ClaimsFrom(ctx context.Context) (jwt.Claims, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(jwt.Claims)
+	return claims, ok
+}
+
+// Authenticator — middleware аутентификации и проверки прав по маршрутам.
+type Authenticator struct {
+	verifier        Verifier
+	policy          Policy
+	revoker         Revoker
+	requireVerified bool
+}
+
+// NewAuthenticator создаёт middleware. Без requireVerified действия из
+// verifiedOnly доступны и с неподтверждённым адресом.
+func NewAuthenticator(verifier Verifier, policy Policy, revoker Revoker, requireVerified bool) *Authenticator {
+	return &Authenticator{verifier: verifier, policy: policy, revoker: revoker, requireVerified: requireVerified}
+}
+
+// Verify проверяет токен, пришедший не в заголовке, например первым сообщением WebSocket.
+func (a *Authenticator) Verify(token string) (jwt.Claims, error) {
+	if token == "" {
+		return jwt.Claims{}, ErrMissingToken
+	}
+	claims, err := a.verifier.ValidateAccessToken(token)
+	if err != nil {
+		return jwt.Claims{}, ErrInvalidToken
+	}
+	if a.revoker != nil && claims.IssuedAt != nil && a.revoker.Revoked(claims.UserID, claims.SessionID, claims.IssuedAt.Time) {
+		return jwt.Claims{}, ErrRevokedToken
+	}
+	return *claims, nil
+}
+
+// Permit проверяет право роли, обязательную 2FA и подтверждение email для perm.
+func (a *Authenticator) Permit(claims jwt.Claims, perm Permission) error {
+	if !a.policy.Allows(claims.Role, perm) {
+		return ErrForbidden
+	}
+	if claims.TwoFactorPending {
+		return ErrTwoFactorRequired
+	}
+	if a.requireVerified && claims.EmailUnverified && verifiedOnly[perm] {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// Authenticated пропускает запрос с действительным bearer-токеном любой роли.
+func (a *Authenticator) Authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := a.authenticate(r)
+		if err != nil {
+			unauthorized(w, r, err)
+			return
+		}
+		next(w, r.WithContext(WithClaims(r.Context(), claims)))
+	}
+}
+
+// Require пропускает запрос, если роль из токена имеет право perm, а для
+// действий, закрытых до подтверждения email, — ещё и подтверждённый адрес.
+func (a *Authenticator) Require(perm Permission, next http.HandlerFunc) http.HandlerFunc {
+	return a.Authenticated(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFrom(r.Context())
+		if err := a.Permit(claims, perm); err != nil {
+			logger.Warn("auth_forbidden", "Role "+claims.Role+" denied permission "+string(perm), r.Header.Get("X-Request-ID"), claims.UserID, r.Method+" "+r.URL.Path+": "+err.Error())
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// Self требует, чтобы параметр пути param совпадал с пользователем из токена.
+func Self(param string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFrom(r.Context())
+		if !ok || strings.TrimSpace(r.PathValue(param)) != claims.UserID {
+			http.Error(w, "forbidden: token does not match "+strings.TrimSuffix(param, "_id"), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (jwt.Claims, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return jwt.Claims{}, ErrMissingToken
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return jwt.Claims{}, ErrInvalidToken
+	}
+	return a.Verify(strings.TrimSpace(token))
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	logger.Warn("auth_unauthorized", "Rejected unauthenticated request", r.Header.Get("X-Request-ID"), "", r.Method+" "+r.URL.Path+": "+err.Error())
+	w.Header().Set("WWW-Authenticate", `Bearer realm="ride-hail"`)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
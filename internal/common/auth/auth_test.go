@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"ride-hail-system/internal/user/jwt"
+	usermodel "ride-hail-system/internal/user/model"
+)
+
+func TestPermitEmailVerification(t *testing.T) {
+	unverified := jwt.Claims{UserID: "p-1", Role: string(usermodel.RolePassenger), EmailUnverified: true}
+
+	tests := []struct {
+		name            string
+		requireVerified bool
+		perm            Permission
+		want            error
+	}{
+		{"verified only", true, PermRidesWrite, ErrEmailNotVerified},
+		{"open to unverified", true, PermRideEvents, nil},
+		{"email links disabled", false, PermRidesWrite, nil},
+		{"role still checked", false, PermAdminRead, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthenticator(nil, DefaultPolicy(), nil, tt.requireVerified)
+			if err := a.Permit(unverified, tt.perm); !errors.Is(err, tt.want) {
+				t.Fatalf("Permit(%s) = %v, want %v", tt.perm, err, tt.want)
+			}
+		})
+	}
+}
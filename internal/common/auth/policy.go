@@ -0,0 +1,46 @@
+package auth
+
+import usermodel "ride-hail-system/internal/user/model"
+
+// Permission — действие, которое маршрут требует от роли.
+type Permission string
+
+const (
+	PermRidesWrite  Permission = "rides:write"  // заказ и отмена поездки
+	PermRideEvents  Permission = "rides:events" // SSE и long-poll событий поездки
+	PermRidesRead   Permission = "rides:read"   // переписка и контакты по своей поездке
+	PermDriverOps   Permission = "driver:operate"
+	PermChat        Permission = "chat:use"
+	PermContact     Permission = "contact:use"
+	PermAdminRead   Permission = "admin:read"
+	PermAdminManage Permission = "admin:manage" // управление пользователями, сессиями и проверка документов
+)
+
+// verifiedOnly — действия, недоступные до подтверждения email: заказ
+// поездок, работа на линии и связь с другими участниками.
+var verifiedOnly = map[Permission]bool{
+	PermRidesWrite: true,
+	PermDriverOps:  true,
+	PermChat:       true,
+	PermContact:    true,
+}
+
+// Policy сопоставляет роли с разрешёнными действиями.
+type Policy map[string][]Permission
+
+func DefaultPolicy() Policy {
+	return Policy{
+		string(usermodel.RolePassenger): {PermRidesWrite, PermRideEvents, PermRidesRead, PermChat, PermContact},
+		string(usermodel.RoleDriver):    {PermDriverOps, PermRidesRead, PermChat, PermContact},
+		string(usermodel.RoleAdmin):     {PermAdminRead, PermAdminManage},
+	}
+}
+
+func (p Policy) Allows(role string, perm Permission) bool {
+	for _, granted := range p[role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
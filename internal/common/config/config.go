@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,6 +15,7 @@ type Config struct {
 		User     string
 		Password string
 		Name     string
+		MaxConns int
 	}
 	RabbitMQ struct {
 		Host     string
@@ -21,7 +24,63 @@ type Config struct {
 		Password string
 	}
 	WebSocket struct {
-		Port int
+		Port   int
+		NodeID string
+		// Сообщения пользователю хранятся для повторной отправки после обрыва.
+		RetentionSeconds int
+		RetentionSize    int
+	}
+	JWT struct {
+		AccessTTLMinutes int
+		RefreshTTLHours  int
+		// Ключ подписи меняется раз в KeyRotationHours; реплики перечитывают
+		// ключи раз в KeyReloadSeconds. Access-токен должен жить дольше двух
+		// перечитываний, иначе ключ удалят раньше, чем его увидят все реплики.
+		KeyRotationHours int
+		KeyReloadSeconds int
+		// Ключ AES-256 в base64, которым шифруются закрытые ключи подписи в базе.
+		KeyEncryptionKey string
+	}
+	Password struct {
+		// Параметры argon2id. При изменении старые хэши пересчитываются при входе.
+		Argon2Time      int
+		Argon2MemoryKiB int
+		Argon2Threads   int
+	}
+	Login struct {
+		// Блокировка удваивается с каждой неудачей после порога, до LockoutMaxMinutes.
+		AccountThreshold   int
+		IPThreshold        int
+		LockoutBaseSeconds int
+		LockoutMaxMinutes  int
+	}
+	TwoFactor struct {
+		// Issuer — название сервиса в приложении-аутентификаторе.
+		Issuer string
+		// RequiredForAdmin: администратор без 2FA после входа может только настроить её.
+		RequiredForAdmin bool
+	}
+	Mail struct {
+		// Каталог, куда письма складываются файлами .eml; пустой — письма только пишутся в лог.
+		Dir  string
+		From string
+		// Секрет подписи токенов из писем. Пустой — ссылки из писем отключены, подтверждение email не требуется.
+		TokenSecret string
+	}
+	Contact struct {
+		// Срок жизни прокси-идентификатора, по которому участники поездки связываются.
+		ProxyTTLMinutes int
+	}
+	Onboarding struct {
+		// Каталог локального хранилища документов водителей.
+		BlobDir            string
+		MaxUploadMB        int
+		ExpiryCheckMinutes int
+	}
+	Admin struct {
+		// Интервал снимков метрик и минимальный интервал позиций одного водителя в ленте.
+		FeedMetricsSeconds     int
+		FeedLocationThrottleMs int
 	}
 	Services struct {
 		RideServicePort           int
@@ -30,6 +89,12 @@ type Config struct {
 	}
 }
 
+// ConsumerConfig переопределяет prefetch и число воркеров консьюмера очереди.
+type ConsumerConfig struct {
+	Prefetch int
+	Workers  int
+}
+
 func getEnv(key, def string) string {
 	if val, ok := os.LookupEnv(key); ok && val != "" {
 		return val
@@ -46,6 +111,24 @@ func getEnvInt(key string, def int) int {
 	return def
 }
 
+func getEnvBool(key string, def bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// defaultNodeID различает реплики сервиса: имя хоста уникально для контейнера.
+func defaultNodeID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return fmt.Sprintf("node-%d", os.Getpid())
+	}
+	return host
+}
+
 func LoadConfig() (*Config, error) {
 	cfg := &Config{}
 
@@ -54,6 +137,7 @@ func LoadConfig() (*Config, error) {
 	cfg.Database.User = getEnv("DB_USER", "ridehail_user")
 	cfg.Database.Password = getEnv("DB_PASSWORD", "ridehail_pass")
 	cfg.Database.Name = getEnv("DB_NAME", "ridehail_db")
+	cfg.Database.MaxConns = getEnvInt("DB_MAX_CONNS", 32)
 
 	cfg.RabbitMQ.Host = getEnv("RABBITMQ_HOST", "localhost")
 	cfg.RabbitMQ.Port = getEnvInt("RABBITMQ_PORT", 5672)
@@ -61,14 +145,73 @@ func LoadConfig() (*Config, error) {
 	cfg.RabbitMQ.Password = getEnv("RABBITMQ_PASSWORD", "guest")
 
 	cfg.WebSocket.Port = getEnvInt("WS_PORT", 8080)
+	cfg.WebSocket.NodeID = getEnv("WS_NODE_ID", defaultNodeID())
+	cfg.WebSocket.RetentionSeconds = getEnvInt("WS_RETENTION_SECONDS", 120)
+	cfg.WebSocket.RetentionSize = getEnvInt("WS_RETENTION_SIZE", 256)
+
+	cfg.JWT.AccessTTLMinutes = getEnvInt("JWT_ACCESS_TTL_MINUTES", 15)
+	cfg.JWT.RefreshTTLHours = getEnvInt("JWT_REFRESH_TTL_HOURS", 7*24)
+	cfg.JWT.KeyRotationHours = getEnvInt("JWT_KEY_ROTATION_HOURS", 24)
+	cfg.JWT.KeyReloadSeconds = getEnvInt("JWT_KEY_RELOAD_SECONDS", 60)
+	cfg.JWT.KeyEncryptionKey = getEnv("JWT_KEY_ENCRYPTION_KEY", "")
+
+	cfg.Password.Argon2Time = getEnvInt("PASSWORD_ARGON2_TIME", 2)
+	cfg.Password.Argon2MemoryKiB = getEnvInt("PASSWORD_ARGON2_MEMORY_KIB", 19*1024)
+	cfg.Password.Argon2Threads = getEnvInt("PASSWORD_ARGON2_THREADS", 1)
+
+	cfg.Login.AccountThreshold = getEnvInt("LOGIN_ACCOUNT_THRESHOLD", 5)
+	cfg.Login.IPThreshold = getEnvInt("LOGIN_IP_THRESHOLD", 20)
+	cfg.Login.LockoutBaseSeconds = getEnvInt("LOGIN_LOCKOUT_BASE_SECONDS", 30)
+	cfg.Login.LockoutMaxMinutes = getEnvInt("LOGIN_LOCKOUT_MAX_MINUTES", 60)
+
+	cfg.TwoFactor.Issuer = getEnv("TWO_FACTOR_ISSUER", "RideHail")
+	cfg.TwoFactor.RequiredForAdmin = getEnvBool("TWO_FACTOR_REQUIRED_FOR_ADMIN", false)
+
+	cfg.Mail.Dir = getEnv("MAIL_DIR", "")
+	cfg.Mail.From = getEnv("MAIL_FROM", "no-reply@ride-hail.local")
+	cfg.Mail.TokenSecret = getEnv("EMAIL_TOKEN_SECRET", "")
+
+	cfg.Contact.ProxyTTLMinutes = getEnvInt("CONTACT_PROXY_TTL_MINUTES", 120)
+
+	cfg.Onboarding.BlobDir = getEnv("ONBOARDING_BLOB_DIR", "data/documents")
+	cfg.Onboarding.MaxUploadMB = getEnvInt("ONBOARDING_MAX_UPLOAD_MB", 10)
+	cfg.Onboarding.ExpiryCheckMinutes = getEnvInt("ONBOARDING_EXPIRY_CHECK_MINUTES", 60)
+
+	cfg.Admin.FeedMetricsSeconds = getEnvInt("ADMIN_FEED_METRICS_SECONDS", 5)
+	cfg.Admin.FeedLocationThrottleMs = getEnvInt("ADMIN_FEED_LOCATION_THROTTLE_MS", 2000)
 
 	cfg.Services.RideServicePort = getEnvInt("RIDE_SERVICE_PORT", 3000)
 	cfg.Services.DriverLocationServicePort = getEnvInt("DRIVER_LOCATION_SERVICE_PORT", 3001)
 	cfg.Services.AdminServicePort = getEnvInt("ADMIN_SERVICE_PORT", 3004)
 
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
+func (c *Config) validate() error {
+	accessTTL := time.Duration(c.JWT.AccessTTLMinutes) * time.Minute
+	reloadEvery := time.Duration(c.JWT.KeyReloadSeconds) * time.Second
+	if reloadEvery <= 0 {
+		return fmt.Errorf("JWT_KEY_RELOAD_SECONDS must be positive, got %d", c.JWT.KeyReloadSeconds)
+	}
+	if accessTTL <= 2*reloadEvery {
+		return fmt.Errorf("JWT_ACCESS_TTL_MINUTES (%s) must exceed twice JWT_KEY_RELOAD_SECONDS (%s)", accessTTL, reloadEvery)
+	}
+	return nil
+}
+
+// Consumer читает RMQ_<QUEUE>_PREFETCH и RMQ_<QUEUE>_WORKERS,
+// например RMQ_LOCATION_UPDATES_RIDE_V2_WORKERS=16.
+func (c *Config) Consumer(queue string) ConsumerConfig {
+	prefix := "RMQ_" + strings.ToUpper(queue)
+	return ConsumerConfig{
+		Prefetch: getEnvInt(prefix+"_PREFETCH", 0),
+		Workers:  getEnvInt(prefix+"_WORKERS", 0),
+	}
+}
+
 func (c *Config) Print() {
 	fmt.Printf("📦 Database: %s@%s:%d/%s\n", c.Database.User, c.Database.Host, c.Database.Port, c.Database.Name)
 	fmt.Printf("🐇 RabbitMQ: amqp://%s:%s@%s:%d\n", c.RabbitMQ.User, c.RabbitMQ.Password, c.RabbitMQ.Host, c.RabbitMQ.Port)
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestValidateJWTTimings(t *testing.T) {
+	tests := []struct {
+		name          string
+		accessMinutes int
+		reloadSeconds int
+		wantErr       bool
+	}{
+		{"defaults", 15, 60, false},
+		{"ttl equals two reloads", 2, 60, true},
+		{"reload longer than ttl", 1, 120, true},
+		{"reload disabled", 15, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			cfg.JWT.AccessTTLMinutes = tt.accessMinutes
+			cfg.JWT.KeyReloadSeconds = tt.reloadSeconds
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -7,14 +7,14 @@ import (
 
 	"ride-hail-system/internal/common/logger"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Postgres struct {
-	Conn *pgx.Conn
+	Pool *pgxpool.Pool
 }
 
-func NewPostgres(host string, port int, user, password, database string) (*Postgres, error) {
+func NewPostgres(host string, port int, user, password, database string, maxConns int) (*Postgres, error) {
 	dsn := fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		user, password, host, port, database,
@@ -23,27 +23,32 @@ func NewPostgres(host string, port int, user, password, database string) (*Postg
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	conn, err := pgx.Connect(ctx, dsn)
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres dsn: %w", err)
+	}
+	// Консьюмеры, heartbeat и фоновые задачи работают параллельно, каждому нужно своё соединение.
+	poolCfg.MaxConns = int32(maxConns)
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		logger.Error("db_connection_failed", "Failed to connect to Postgres", "", "", err.Error())
 		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
 
-	if err := conn.Ping(ctx); err != nil {
+	if err := pool.Ping(ctx); err != nil {
 		logger.Error("db_ping_failed", "Postgres ping failed", "", "", err.Error())
-		conn.Close(ctx)
+		pool.Close()
 		return nil, fmt.Errorf("postgres ping failed: %w", err)
 	}
 
 	logger.Info("db_connected", "Connected to PostgreSQL successfully", "", "")
-	return &Postgres{Conn: conn}, nil
+	return &Postgres{Pool: pool}, nil
 }
 
 func (p *Postgres) Close() {
-	if p.Conn != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		p.Conn.Close(ctx)
+	if p.Pool != nil {
+		p.Pool.Close()
 		logger.Info("db_connection_closed", "PostgreSQL connection closed", "", "")
 	}
 }
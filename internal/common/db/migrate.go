@@ -23,7 +23,7 @@ func (p *Postgres) RunMigrations(migrationsDir string) error {
 		return fmt.Errorf("failed to read migration files: %w", err)
 	}
 
-	_, err = p.Conn.Exec(context.Background(), `
+	_, err = p.Pool.Exec(context.Background(), `
 		CREATE TABLE IF NOT EXISTS _migrations (
 			id SERIAL PRIMARY KEY,
 			filename TEXT UNIQUE NOT NULL,
@@ -36,7 +36,7 @@ func (p *Postgres) RunMigrations(migrationsDir string) error {
 	}
 
 	executed := make(map[string]bool)
-	rows, err := p.Conn.Query(context.Background(), "SELECT filename FROM _migrations")
+	rows, err := p.Pool.Query(context.Background(), "SELECT filename FROM _migrations")
 	if err != nil {
 		logger.Error("db_migrations_query_failed", "Failed to fetch applied migrations", "", "", err.Error())
 		return fmt.Errorf("failed to query applied migrations: %w", err)
@@ -65,7 +65,7 @@ func (p *Postgres) RunMigrations(migrationsDir string) error {
 		}
 
 		logger.Info("db_migration_apply", fmt.Sprintf("Applying migration: %s", name), "", "")
-		tx, err := p.Conn.Begin(context.Background())
+		tx, err := p.Pool.Begin(context.Background())
 		if err != nil {
 			return fmt.Errorf("failed to start migration transaction: %w", err)
 		}
@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store хранит обработанные message_id в памяти и в Postgres.
+type Store struct {
+	db   *pgxpool.Pool
+	ttl  time.Duration
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func NewStore(db *pgxpool.Pool, ttl time.Duration) *Store {
+	return &Store{
+		db:   db,
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+func (s *Store) Seen(ctx context.Context, consumer, messageID string) (bool, error) {
+	key := consumer + ":" + messageID
+	now := time.Now()
+
+	s.mu.Lock()
+	expiresAt, ok := s.seen[key]
+	s.mu.Unlock()
+	if ok && now.Before(expiresAt) {
+		return true, nil
+	}
+
+	if s.db == nil {
+		return false, nil
+	}
+
+	err := s.db.QueryRow(ctx, `
+		SELECT expires_at
+		FROM processed_messages
+		WHERE consumer = $1 AND message_id = $2 AND expires_at > now()
+	`, consumer, messageID).Scan(&expiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to query processed message: %w", err)
+	}
+
+	s.mu.Lock()
+	s.seen[key] = expiresAt
+	s.mu.Unlock()
+	return true, nil
+}
+
+func (s *Store) MarkProcessed(ctx context.Context, consumer, messageID string) error {
+	expiresAt := time.Now().Add(s.ttl)
+
+	s.mu.Lock()
+	s.seen[consumer+":"+messageID] = expiresAt
+	s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO processed_messages (consumer, message_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (consumer, message_id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+	`, consumer, messageID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert processed message: %w", err)
+	}
+	return nil
+}
+
+// Run периодически удаляет просроченные записи из памяти и из таблицы.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.purge(ctx)
+		}
+	}
+}
+
+func (s *Store) purge(ctx context.Context) {
+	now := time.Now()
+
+	s.mu.Lock()
+	for key, expiresAt := range s.seen {
+		if now.After(expiresAt) {
+			delete(s.seen, key)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.db == nil {
+		return
+	}
+
+	tag, err := s.db.Exec(ctx, `DELETE FROM processed_messages WHERE expires_at <= now()`)
+	if err != nil {
+		logger.Warn("idempotency_purge", "Failed to purge processed messages", "", "", err.Error())
+		return
+	}
+	logger.Debug("idempotency_purge", fmt.Sprintf("Purged %d processed messages", tag.RowsAffected()), "", "")
+}
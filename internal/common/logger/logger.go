@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,12 +27,16 @@ type LogEntry struct {
 // hostname сервиса
 var hostname, _ = os.Hostname()
 
-// Имя сервиса (можно установить при старте)
-var serviceName = "unknown-service"
+// Имя сервиса (можно установить при старте).
+var serviceName atomic.Pointer[string]
+
+func init() {
+	SetServiceName("unknown-service")
+}
 
 // Установить имя сервиса
 func SetServiceName(name string) {
-	serviceName = name
+	serviceName.Store(&name)
 }
 
 // INFO лог
@@ -39,7 +44,7 @@ func Info(action, message, requestID, rideID string) {
 	entry := LogEntry{
 		Timestamp: time.Now().Format(time.RFC3339),
 		Level:     "INFO",
-		Service:   serviceName,
+		Service:   *serviceName.Load(),
 		Action:    action,
 		Message:   message,
 		Hostname:  hostname,
@@ -54,7 +59,7 @@ func Debug(action, message, requestID, rideID string) {
 	entry := LogEntry{
 		Timestamp: time.Now().Format(time.RFC3339),
 		Level:     "DEBUG",
-		Service:   serviceName,
+		Service:   *serviceName.Load(),
 		Action:    action,
 		Message:   message,
 		Hostname:  hostname,
@@ -69,7 +74,7 @@ func Warn(action, message, requestID, rideID, errMsg string) {
 	entry := LogEntry{
 		Timestamp: time.Now().Format(time.RFC3339),
 		Level:     "WARN",
-		Service:   serviceName,
+		Service:   *serviceName.Load(),
 		Action:    action,
 		Message:   message,
 		Hostname:  hostname,
@@ -91,7 +96,7 @@ func Error(action, message, requestID, rideID, errStack string) {
 	entry := LogEntry{
 		Timestamp: time.Now().Format(time.RFC3339),
 		Level:     "ERROR",
-		Service:   serviceName,
+		Service:   *serviceName.Load(),
 		Action:    action,
 		Message:   message,
 		Hostname:  hostname,
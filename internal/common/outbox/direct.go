@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"ride-hail-system/internal/common/rmq"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Direct публикует сразу, минуя таблицу outbox; только для тестов и in-memory шины.
+type Direct struct {
+	pub rmq.Publisher
+}
+
+func NewDirect(pub rmq.Publisher) *Direct {
+	return &Direct{pub: pub}
+}
+
+func (d *Direct) Enqueue(ctx context.Context, _ pgx.Tx, exchange, routingKey string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	msg := rmq.Publishing{Body: body}
+	if env, ok := payload.(rmq.Envelope); ok {
+		msg.MessageID = env.MessageID
+		msg.Type = env.Type
+	}
+
+	if err := d.pub.Publish(ctx, exchange, routingKey, msg); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	return nil
+}
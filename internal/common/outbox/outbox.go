@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"ride-hail-system/internal/common/rmq"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Message struct {
+	ID         string    `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	Exchange   string    `json:"exchange" db:"exchange"`
+	RoutingKey string    `json:"routing_key" db:"routing_key"`
+	Payload    []byte    `json:"payload" db:"payload"`
+	Attempts   int       `json:"attempts" db:"attempts"`
+}
+
+// Enqueuer записывает сообщение для публикации в рамках транзакции вызывающего кода.
+type Enqueuer = rmq.Enqueuer
+
+type Store struct {
+	db *pgxpool.Pool
+}
+
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+// Enqueue записывает сообщение в outbox в рамках транзакции вызывающего кода.
+func (s *Store) Enqueue(ctx context.Context, tx pgx.Tx, exchange, routingKey string, payload any) error {
+	if tx == nil {
+		return fmt.Errorf("transaction is nil")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO outbox (exchange, routing_key, payload)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.Exec(ctx, query, exchange, routingKey, body); err != nil {
+		return fmt.Errorf("failed to insert outbox message: %w", err)
+	}
+	return nil
+}
+
+// ClaimPending арендует пачку сообщений на lease. Строки не остаются
+// заблокированными, пока релей ждёт подтверждений брокера.
+func (s *Store) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]Message, error) {
+	rows, err := s.db.Query(ctx, `
+		UPDATE outbox
+		SET claimed_until = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE dead_at IS NULL AND (claimed_until IS NULL OR claimed_until < now())
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, created_at, exchange, routing_key, payload, attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.CreatedAt, &m.Exchange, &m.RoutingKey, &m.Payload, &m.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox messages: %w", err)
+	}
+
+	// RETURNING не сохраняет порядок подзапроса.
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+	return messages, nil
+}
+
+// DeleteSent удаляет опубликованные сообщения.
+func (s *Store) DeleteSent(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("failed to delete sent outbox messages: %w", err)
+	}
+	return nil
+}
+
+// Release возвращает неопубликованные сообщения без учёта попытки.
+func (s *Store) Release(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(ctx, `UPDATE outbox SET claimed_until = NULL WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("failed to release outbox messages: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed учитывает неудачную попытку; true — попытки исчерпаны.
+func (s *Store) MarkFailed(ctx context.Context, id, reason string, maxAttempts int) (bool, error) {
+	var dead bool
+	err := s.db.QueryRow(ctx, `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    claimed_until = NULL,
+		    dead_at = CASE WHEN attempts + 1 >= $3 THEN now() END
+		WHERE id = $1
+		RETURNING dead_at IS NOT NULL
+	`, id, reason, maxAttempts).Scan(&dead)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark outbox message as failed: %w", err)
+	}
+	return dead, nil
+}
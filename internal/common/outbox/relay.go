@@ -0,0 +1,223 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RelayOptions — параметры релея; нулевые поля заменяются значениями по умолчанию.
+type RelayOptions struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	// ConfirmTimeout — сколько ждать подтверждений брокера для пачки.
+	ConfirmTimeout time.Duration
+	// Lease — на сколько пачка закрепляется за релеем; должна быть больше ConfirmTimeout.
+	Lease time.Duration
+}
+
+func DefaultRelayOptions() RelayOptions {
+	return RelayOptions{
+		Interval:       200 * time.Millisecond,
+		BatchSize:      500,
+		MaxAttempts:    10,
+		ConfirmTimeout: 5 * time.Second,
+		Lease:          30 * time.Second,
+	}
+}
+
+type Relay struct {
+	store   *Store
+	url     string
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	opts    RelayOptions
+}
+
+// NewRelay создаёт релей с собственным соединением к брокеру по url.
+func NewRelay(store *Store, url string, opts RelayOptions) *Relay {
+	def := DefaultRelayOptions()
+	if opts.Interval <= 0 {
+		opts.Interval = def.Interval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = def.BatchSize
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = def.MaxAttempts
+	}
+	if opts.ConfirmTimeout <= 0 {
+		opts.ConfirmTimeout = def.ConfirmTimeout
+	}
+	if opts.Lease <= opts.ConfirmTimeout {
+		opts.Lease = max(def.Lease, 2*opts.ConfirmTimeout)
+	}
+	return &Relay{store: store, url: url, opts: opts}
+}
+
+// Run публикует неотправленные сообщения, пока ctx не отменён. Полные пачки
+// выбираются подряд, без ожидания следующего тика.
+func (r *Relay) Run(ctx context.Context) {
+	logger.Info("outbox_relay_start", "Outbox relay started", "", "")
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.close()
+			logger.Info("outbox_relay_stop", "Outbox relay stopped", "", "")
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				n, err := r.flush(ctx)
+				if err != nil {
+					logger.Warn("outbox_relay_flush", "Failed to flush outbox", "", "", err.Error())
+					break
+				}
+				if n < r.opts.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// flush публикует одну пачку и возвращает её размер. Сообщения отправляются
+// подряд, подтверждения брокера собираются после отправки всей пачки.
+func (r *Relay) flush(ctx context.Context) (int, error) {
+	if err := r.ensureChannel(); err != nil {
+		return 0, err
+	}
+
+	messages, err := r.store.ClaimPending(ctx, r.opts.BatchSize, r.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	confirmCtx, cancel := context.WithTimeout(ctx, r.opts.ConfirmTimeout)
+	defer cancel()
+
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(messages))
+	var publishErr error
+	for _, m := range messages {
+		confirm, err := r.publish(confirmCtx, m)
+		if err != nil {
+			publishErr = err
+			break
+		}
+		confirms = append(confirms, confirm)
+	}
+
+	var sent, unsent []string
+	blamed := false
+	for i, m := range messages {
+		if i >= len(confirms) {
+			unsent = append(unsent, m.ID)
+			continue
+		}
+		acked, err := confirms[i].WaitContext(confirmCtx)
+		switch {
+		case acked:
+			sent = append(sent, m.ID)
+		case err != nil || blamed:
+			unsent = append(unsent, m.ID)
+		default:
+			// При закрытии канала брокер отклоняет все неподтверждённые
+			// сообщения; попытка засчитывается только первому из них.
+			blamed = r.channel.IsClosed()
+			r.fail(ctx, m, errors.New("message was nacked by broker"))
+		}
+	}
+
+	if err := r.store.DeleteSent(ctx, sent); err != nil {
+		return 0, err
+	}
+	if err := r.store.Release(ctx, unsent); err != nil {
+		return 0, err
+	}
+
+	logger.Debug("outbox_relay_flush", fmt.Sprintf("Published %d of %d outbox messages", len(sent), len(messages)), "", "")
+	return len(messages), publishErr
+}
+
+func (r *Relay) fail(ctx context.Context, m Message, cause error) {
+	dead, err := r.store.MarkFailed(ctx, m.ID, cause.Error(), r.opts.MaxAttempts)
+	switch {
+	case err != nil:
+		logger.Error("outbox_relay_publish", fmt.Sprintf("Failed to record failed attempt for %s", m.ID), "", "", err.Error())
+	case dead:
+		logger.Error("outbox_relay_dead", fmt.Sprintf("Outbox message %s parked after %d attempts", m.ID, m.Attempts+1), "", "", cause.Error())
+	default:
+		logger.Warn("outbox_relay_publish", fmt.Sprintf("Failed to publish outbox message %s", m.ID), "", "", cause.Error())
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, m Message) (*amqp.DeferredConfirmation, error) {
+	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		m.Exchange,
+		m.RoutingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    m.ID,
+			Timestamp:    m.CreatedAt,
+			Body:         m.Payload,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish: %w", err)
+	}
+	return confirm, nil
+}
+
+// ensureChannel заново открывает соединение и канал в режиме подтверждений,
+// если брокер их закрыл.
+func (r *Relay) ensureChannel() error {
+	if r.conn == nil || r.conn.IsClosed() {
+		conn, err := amqp.Dial(r.url)
+		if err != nil {
+			return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		}
+		r.conn, r.channel = conn, nil
+		logger.Info("outbox_relay_connect", "Relay connected to RabbitMQ", "", "")
+	}
+	if r.channel != nil && !r.channel.IsClosed() {
+		return nil
+	}
+
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	r.channel = ch
+	logger.Info("outbox_relay_channel", "Confirm channel opened", "", "")
+	return nil
+}
+
+func (r *Relay) close() {
+	if r.channel != nil {
+		_ = r.channel.Close()
+	}
+	if r.conn != nil {
+		_ = r.conn.Close()
+	}
+}
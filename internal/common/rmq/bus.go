@@ -0,0 +1,304 @@
+package rmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// headerAttempt — заголовок с номером повторной попытки обработки.
+	headerAttempt       = "x-attempt"
+	retryConfirmTimeout = 5 * time.Second
+)
+
+type Publishing struct {
+	MessageID string
+	Type      string
+	Body      []byte
+	Attempt   int
+}
+
+type Delivery struct {
+	Exchange   string
+	RoutingKey string
+	MessageID  string
+	Type       string
+	Body       []byte
+	// Attempt — сколько раз сообщение уже возвращалось на повтор.
+	Attempt int
+
+	ack   func() error
+	nack  func() error
+	retry func() error
+}
+
+func (d Delivery) Ack() error {
+	if d.ack == nil {
+		return nil
+	}
+	return d.ack()
+}
+
+// Nack отклоняет доставку без возврата в очередь; если у очереди настроен
+// DLX, сообщение попадёт туда.
+func (d Delivery) Nack() error {
+	if d.nack == nil {
+		return nil
+	}
+	return d.nack()
+}
+
+// Retry кладёт копию сообщения в конец исходной очереди с увеличенным
+// Attempt и подтверждает текущую доставку.
+func (d Delivery) Retry() error {
+	if d.retry == nil {
+		return d.Nack()
+	}
+	return d.retry()
+}
+
+// Publisher публикует сообщение в exchange.
+type Publisher interface {
+	Publish(ctx context.Context, exchange, routingKey string, msg Publishing) error
+}
+
+// Consumer отдаёт доставки из очереди; prefetch ограничивает число
+// неподтверждённых сообщений у консьюмера.
+type Consumer interface {
+	Consume(queue string, prefetch int) (<-chan Delivery, error)
+}
+
+type Bus interface {
+	Declarer
+	Publisher
+	Consumer
+}
+
+// AMQPBus — реализация Bus поверх RabbitMQ; у каждого консьюмера свой канал.
+type AMQPBus struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	mu   sync.Mutex
+	subs []*amqp.Channel
+}
+
+func NewAMQPBus(conn *amqp.Connection) (*AMQPBus, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return &AMQPBus{conn: conn, ch: ch}, nil
+}
+
+func (b *AMQPBus) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return b.ch.ExchangeDeclare(name, kind, durable, autoDelete, internal, noWait, args)
+}
+
+func (b *AMQPBus) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	return b.ch.QueueDeclare(name, durable, autoDelete, exclusive, noWait, args)
+}
+
+func (b *AMQPBus) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return b.ch.QueueBind(name, key, exchange, noWait, args)
+}
+
+func (b *AMQPBus) QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error) {
+	return b.ch.QueueDelete(name, ifUnused, ifEmpty, noWait)
+}
+
+func (b *AMQPBus) Publish(ctx context.Context, exchange, routingKey string, msg Publishing) error {
+	return b.ch.PublishWithContext(
+		ctx,
+		exchange,
+		routingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    msg.MessageID,
+			Type:         msg.Type,
+			Headers:      attemptHeaders(msg.Attempt),
+			Body:         msg.Body,
+		},
+	)
+}
+
+func attemptHeaders(attempt int) amqp.Table {
+	if attempt <= 0 {
+		return nil
+	}
+	return amqp.Table{headerAttempt: int32(attempt)}
+}
+
+func headerInt(v any) int {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	case int16:
+		return int(n)
+	case int8:
+		return int(n)
+	}
+	return 0
+}
+
+func (b *AMQPBus) Consume(queue string, prefetch int) (<-chan Delivery, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open consumer channel for %s: %w", queue, err)
+	}
+
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to set prefetch for %s: %w", queue, err)
+	}
+	// Повторы публикуются в этот же канал; подтверждение брокера нужно,
+	// чтобы не потерять сообщение, подтвердив исходную доставку.
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to enable confirms for %s: %w", queue, err)
+	}
+
+	deliveries, err := ch.Consume(
+		queue,
+		"",
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to start consuming %s: %w", queue, err)
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, ch)
+	b.mu.Unlock()
+
+	out := make(chan Delivery)
+	go func() {
+		defer close(out)
+		for d := range deliveries {
+			d := d
+			out <- Delivery{
+				Exchange:   d.Exchange,
+				RoutingKey: d.RoutingKey,
+				MessageID:  d.MessageId,
+				Type:       d.Type,
+				Body:       d.Body,
+				Attempt:    headerInt(d.Headers[headerAttempt]),
+				ack:        func() error { return d.Ack(false) },
+				nack:       func() error { return d.Nack(false, false) },
+				retry:      func() error { return retryAMQP(ch, queue, d) },
+			}
+		}
+	}()
+	return out, nil
+}
+
+// retryAMQP публикует копию через default exchange прямо в очередь. Если
+// брокер её не принял, доставка возвращается в очередь без счётчика.
+func retryAMQP(ch *amqp.Channel, queue string, d amqp.Delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), retryConfirmTimeout)
+	defer cancel()
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false, amqp.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Type:         d.Type,
+		Headers:      attemptHeaders(headerInt(d.Headers[headerAttempt]) + 1),
+		Body:         d.Body,
+	})
+	if err == nil {
+		var acked bool
+		if acked, err = confirm.WaitContext(ctx); err == nil && !acked {
+			err = errors.New("retry publish was nacked by broker")
+		}
+	}
+	if err != nil {
+		if nackErr := d.Nack(false, true); nackErr != nil {
+			return fmt.Errorf("failed to requeue after %v: %w", err, nackErr)
+		}
+		return fmt.Errorf("retry publish failed, message requeued: %w", err)
+	}
+	return d.Ack(false)
+}
+
+// Depth запрашивает глубину очереди пассивным объявлением.
+func (b *AMQPBus) Depth(queue string) (int, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(queue, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect queue %s: %w", queue, err)
+	}
+	return q.Messages, nil
+}
+
+// Close закрывает каналы шины; соединение принадлежит вызывающему коду.
+func (b *AMQPBus) Close() error {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, ch := range subs {
+		_ = ch.Close()
+	}
+	return b.ch.Close()
+}
+
+// ErrPermanent помечает ошибки, которые не исчезнут при повторе: битый
+// конверт, неверный тип или payload.
+var ErrPermanent = errors.New("permanent failure")
+
+// Permanent оборачивает ошибку как неисправимую.
+func Permanent(err error) error {
+	if err == nil || errors.Is(err, ErrPermanent) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// Retryable сообщает, стоит ли повторить обработку после handleErr.
+func Retryable(d Delivery, handleErr error, maxAttempts int) bool {
+	return handleErr != nil && !errors.Is(handleErr, ErrPermanent) && d.Attempt+1 < maxAttempts
+}
+
+// Settle подтверждает доставку, отправляет временную ошибку на повтор, а
+// постоянную или исчерпавшую maxAttempts попыток — в DLX.
+func Settle(d Delivery, handleErr error, maxAttempts int) {
+	switch {
+	case handleErr == nil:
+		if err := d.Ack(); err != nil {
+			logger.Warn("rmq_ack", "Failed to ack delivery", "", "", err.Error())
+		}
+	case Retryable(d, handleErr, maxAttempts):
+		if err := d.Retry(); err != nil {
+			logger.Warn("rmq_retry", "Failed to retry delivery", "", "", err.Error())
+		}
+	default:
+		if err := d.Nack(); err != nil {
+			logger.Warn("rmq_nack", "Failed to nack delivery", "", "", err.Error())
+		}
+	}
+}
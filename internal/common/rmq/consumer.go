@@ -0,0 +1,181 @@
+package rmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultRetryDelay  = 200 * time.Millisecond
+	maxRetryDelay      = 10 * time.Second
+)
+
+// ConsumerOptions — общие зависимости всех консьюмеров сервиса.
+type ConsumerOptions struct {
+	Topology Topology
+	Dedupe   Deduplicator
+	Metrics  *Metrics
+	// MaxAttempts — сколько раз обрабатывать сообщение с временной ошибкой,
+	// прежде чем отправить его в DLX.
+	MaxAttempts int
+	// RetryDelay — пауза перед первым повтором, дальше она удваивается.
+	RetryDelay time.Duration
+}
+
+func (o ConsumerOptions) maxAttempts() int {
+	if o.MaxAttempts > 0 {
+		return o.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+// retryDelay растёт с номером попытки. Пауза держит воркер, и при сбое
+// зависимости это заодно снижает на неё нагрузку.
+func (o ConsumerOptions) retryDelay(attempt int) time.Duration {
+	delay := o.RetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// ConsumerSpec описывает консьюмер. Сообщения с одним PartitionBy идут по порядку.
+type ConsumerSpec struct {
+	Queue       string
+	Type        string
+	PartitionBy string
+}
+
+// RunConsumer запускает чтение очереди и пул воркеров.
+func RunConsumer(ctx context.Context, bus Consumer, opts ConsumerOptions, spec ConsumerSpec, handle func(env Envelope) error) error {
+	prefetch := opts.Topology.Prefetch(spec.Queue)
+	workers := opts.Topology.Workers(spec.Queue)
+
+	deliveries, err := bus.Consume(spec.Queue, prefetch)
+	if err != nil {
+		return err
+	}
+
+	stats := opts.Metrics.consumer(spec.Queue, prefetch, workers)
+
+	// Буфер каждого воркера равен prefetch: больше неподтверждённых
+	// сообщений брокер всё равно не отдаст.
+	lanes := make([]chan Delivery, workers)
+	var wg sync.WaitGroup
+	for i := range lanes {
+		lanes[i] = make(chan Delivery, prefetch)
+		wg.Add(1)
+		go func(lane <-chan Delivery) {
+			defer wg.Done()
+			for d := range lane {
+				process(ctx, opts, spec, stats, d, handle)
+			}
+		}(lanes[i])
+	}
+
+	go func() {
+		defer func() {
+			for _, lane := range lanes {
+				close(lane)
+			}
+			wg.Wait()
+			logger.Info("rmq_consumer_stop", "Consumer stopped", spec.Queue, "")
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+				stats.received()
+				lane := lanes[partition(partitionKey(d.Body, spec.PartitionBy), workers)]
+				select {
+				case lane <- d:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	logger.Info("rmq_consumer_start",
+		fmt.Sprintf("Consuming %s with %d workers, prefetch %d", spec.Queue, workers, prefetch), spec.Queue, "")
+	return nil
+}
+
+func process(ctx context.Context, opts ConsumerOptions, spec ConsumerSpec, stats *consumerMetrics, d Delivery, handle func(env Envelope) error) {
+	start := time.Now()
+
+	handled, err := HandleOnce(ctx, opts.Dedupe, spec.Queue, d.Body, func(env Envelope) error {
+		if env.Type != spec.Type {
+			return Permanent(fmt.Errorf("unexpected message type %q, want %q", env.Type, spec.Type))
+		}
+		return handle(env)
+	})
+	stats.done(time.Since(start), err)
+
+	maxAttempts := opts.maxAttempts()
+	if Retryable(d, err, maxAttempts) {
+		logger.Warn("rmq_consume", fmt.Sprintf("Failed to handle message, attempt %d of %d", d.Attempt+1, maxAttempts),
+			spec.Queue, "", err.Error())
+		// При остановке доставка остаётся неподтверждённой, и брокер вернёт её.
+		select {
+		case <-time.After(opts.retryDelay(d.Attempt)):
+		case <-ctx.Done():
+			return
+		}
+		Settle(d, err, maxAttempts)
+		return
+	}
+
+	Settle(d, err, maxAttempts)
+	if err != nil {
+		logger.Warn("rmq_consume", "Failed to handle message, sent to dead letters", spec.Queue, "", err.Error())
+		return
+	}
+	if !handled {
+		logger.Info("rmq_consume", "Duplicate message skipped", spec.Queue, "")
+	}
+}
+
+// partitionKey достаёт поле payload без полного разбора сообщения.
+func partitionKey(body []byte, field string) string {
+	if field == "" {
+		return ""
+	}
+
+	var env struct {
+		Payload map[string]json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return ""
+	}
+
+	var key string
+	if err := json.Unmarshal(env.Payload[field], &key); err != nil {
+		return ""
+	}
+	return key
+}
+
+func partition(key string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,121 @@
+package rmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type memoryDedupe struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (d *memoryDedupe) Seen(_ context.Context, consumer, messageID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.seen[consumer+"/"+messageID], nil
+}
+
+func (d *memoryDedupe) MarkProcessed(_ context.Context, consumer, messageID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seen[consumer+"/"+messageID] = true
+	return nil
+}
+
+func (d *memoryDedupe) processed(consumer, messageID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.seen[consumer+"/"+messageID]
+}
+
+func publishEnvelope(t *testing.T, bus *MemoryBus, env Envelope) {
+	t.Helper()
+	body, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	if err := bus.Publish(context.Background(), "", "jobs", Publishing{MessageID: env.MessageID, Type: env.Type, Body: body}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+func TestRunConsumerSettlesByHandlerResult(t *testing.T) {
+	bus := NewMemoryBus()
+	top := Topology{
+		Exchanges: []ExchangeSpec{{Name: "dlx", Kind: amqp.ExchangeFanout}},
+		Queues:    []QueueSpec{{Name: "jobs", DeadLetter: "dlx", Workers: 2}, {Name: "dead"}},
+		Bindings:  []BindingSpec{{Queue: "dead", Exchange: "dlx"}},
+	}
+	declare(t, bus, top)
+	dedupe := &memoryDedupe{seen: map[string]bool{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	handled := make(chan string, 16)
+	opts := ConsumerOptions{Topology: top, Dedupe: dedupe, MaxAttempts: 3, RetryDelay: time.Millisecond}
+	err := RunConsumer(ctx, bus, opts, ConsumerSpec{Queue: "jobs", Type: "job"}, func(env Envelope) error {
+		mu.Lock()
+		calls[env.MessageID]++
+		mu.Unlock()
+		defer func() { handled <- env.MessageID }()
+		switch env.MessageID {
+		case "bad":
+			return errors.New("database is down")
+		case "broken":
+			return Permanent(errors.New("invalid payload"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunConsumer: %v", err)
+	}
+
+	good := Envelope{MessageID: "good", Type: "job", Payload: json.RawMessage(`{}`)}
+	publishEnvelope(t, bus, good)
+	for id := ""; id != "good"; {
+		select {
+		case id = <-handled:
+		case <-time.After(time.Second):
+			t.Fatal("handler was not called")
+		}
+	}
+
+	// Повтор уже обработанного сообщения подтверждается без вызова обработчика.
+	publishEnvelope(t, bus, good)
+	// Временная ошибка повторяется MaxAttempts раз, постоянная — сразу в DLX.
+	publishEnvelope(t, bus, Envelope{MessageID: "bad", Type: "job", Payload: json.RawMessage(`{}`)})
+	publishEnvelope(t, bus, Envelope{MessageID: "broken", Type: "job", Payload: json.RawMessage(`{}`)})
+	// Сообщение чужого типа отклоняется до обработчика.
+	publishEnvelope(t, bus, Envelope{MessageID: "alien", Type: "other", Payload: json.RawMessage(`{}`)})
+
+	dead := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		dead[receive(t, bus, "dead").MessageID] = true
+	}
+	if !dead["bad"] || !dead["broken"] || !dead["alien"] {
+		t.Fatalf("dead letters = %v, want bad, broken, alien", dead)
+	}
+
+	if !dedupe.processed("jobs", "good") {
+		t.Fatal("successful message was not marked processed")
+	}
+	if dedupe.processed("jobs", "bad") {
+		t.Fatal("failed message was marked processed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["good"] != 1 || calls["bad"] != 3 || calls["broken"] != 1 || calls["alien"] != 0 {
+		t.Fatalf("handler calls = %v, want good:1 bad:3 broken:1", calls)
+	}
+}
@@ -71,18 +71,28 @@ type DriverLocationUpdateMessage struct {
 }
 
 type RideStatusUpdateMessage struct {
-	Type    string `json:"type"`
-	RideID  string `json:"ride_id"`
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Type      string    `json:"type"`
+	RideID    string    `json:"ride_id"`
+	Status    string    `json:"status"`
+	DriverID  string    `json:"driver_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// DriverStateMessage — смена статуса водителя, публикуется по ключу driver.state.{status}.
+type DriverStateMessage struct {
+	DriverID  string    `json:"driver_id"`
+	Status    string    `json:"status"`
+	RideID    string    `json:"ride_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type PassiNFO struct {
-	Type           string         `json:"type"`            // тип сообщения, например "ride_details"
-	RideID         string         `json:"ride_id"`         // ID поездки
-	PassengerName  string         `json:"passenger_name"`  // имя пассажира
-	PassengerPhone string         `json:"passenger_phone"` // телефон пассажира
-	PickupLocation PickupLocation `json:"pickup_location"` // место посадки
+	Type             string         `json:"type"`                        // тип сообщения, например "ride_details"
+	RideID           string         `json:"ride_id"`                     // ID поездки
+	PassengerName    string         `json:"passenger_name"`              // имя пассажира
+	PassengerContact string         `json:"passenger_contact,omitempty"` // прокси-идентификатор для звонка через /contact/{proxy_id}
+	PickupLocation   PickupLocation `json:"pickup_location"`             // место посадки
 }
 
 type PickupLocation struct {
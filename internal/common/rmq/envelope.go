@@ -0,0 +1,115 @@
+package rmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/pkg/uuid"
+)
+
+const (
+	TypeRideRequested  = "ride.requested"
+	TypeRideStatus     = "ride.status"
+	TypeDriverResponse = "driver.response"
+	TypeDriverStatus   = "driver.status"
+	// TypeDriverState — смена статуса самого водителя (в сети, занят, не в сети).
+	TypeDriverState    = "driver.state"
+	TypeLocationUpdate = "location.update"
+	TypePassengerInfo  = "passenger.info"
+	TypeWSDelivery     = "ws.delivery"
+)
+
+// Envelope оборачивает каждое сообщение шины.
+type Envelope struct {
+	MessageID     string          `json:"message_id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	ProducedAt    time.Time       `json:"produced_at"`
+	CorrelationID string          `json:"correlation_id"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func NewEnvelope(msgType string, version int, correlationID string, payload any) (Envelope, error) {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	if correlationID == "" {
+		correlationID = id
+	}
+
+	return Envelope{
+		MessageID:     id,
+		Type:          msgType,
+		Version:       version,
+		ProducedAt:    time.Now().UTC(),
+		CorrelationID: correlationID,
+		Payload:       body,
+	}, nil
+}
+
+func (e Envelope) Decode(v any) error {
+	if len(e.Payload) == 0 {
+		return Permanent(errors.New("envelope payload is empty"))
+	}
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return Permanent(err)
+	}
+	return nil
+}
+
+// DecodeAs проверяет тип сообщения перед разбором payload.
+func (e Envelope) DecodeAs(msgType string, v any) error {
+	if e.Type != msgType {
+		return Permanent(fmt.Errorf("unexpected message type %q, want %q", e.Type, msgType))
+	}
+	return e.Decode(v)
+}
+
+// Deduplicator запоминает обработанные message_id для конкретного консьюмера.
+type Deduplicator interface {
+	Seen(ctx context.Context, consumer, messageID string) (bool, error)
+	MarkProcessed(ctx context.Context, consumer, messageID string) error
+}
+
+// HandleOnce разбирает конверт, пропускает уже обработанные сообщения и
+// отмечает сообщение обработанным только после успешного handle.
+func HandleOnce(ctx context.Context, dedupe Deduplicator, consumer string, body []byte, handle func(env Envelope) error) (bool, error) {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return false, Permanent(fmt.Errorf("failed to unmarshal envelope: %w", err))
+	}
+	if env.MessageID == "" {
+		return false, Permanent(errors.New("envelope has no message_id"))
+	}
+
+	if dedupe != nil {
+		seen, err := dedupe.Seen(ctx, consumer, env.MessageID)
+		if err != nil {
+			return false, fmt.Errorf("failed to check idempotency store: %w", err)
+		}
+		if seen {
+			return false, nil
+		}
+	}
+
+	if err := handle(env); err != nil {
+		return false, err
+	}
+
+	if dedupe != nil {
+		if err := dedupe.MarkProcessed(ctx, consumer, env.MessageID); err != nil {
+			return true, fmt.Errorf("failed to mark message as processed: %w", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,269 @@
+package rmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const defaultMemoryQueueSize = 1024
+
+var ErrBusClosed = errors.New("bus is closed")
+
+// MemoryBus — реализация Bus в памяти процесса для тестов и локального запуска.
+type MemoryBus struct {
+	mu        sync.RWMutex
+	exchanges map[string]string
+	queues    map[string]*memoryQueue
+	bindings  []BindingSpec
+	queueSize int
+	closed    bool
+}
+
+type memoryQueue struct {
+	name       string
+	deadLetter string
+	deliveries chan Delivery
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		exchanges: make(map[string]string),
+		queues:    make(map[string]*memoryQueue),
+		queueSize: defaultMemoryQueueSize,
+	}
+}
+
+func (b *MemoryBus) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if existing, ok := b.exchanges[name]; ok && existing != kind {
+		return fmt.Errorf("exchange %s already declared as %s", name, existing)
+	}
+	switch kind {
+	case amqp.ExchangeTopic, amqp.ExchangeFanout, amqp.ExchangeDirect:
+	default:
+		return fmt.Errorf("exchange kind %s is not supported", kind)
+	}
+	b.exchanges[name] = kind
+	return nil
+}
+
+func (b *MemoryBus) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[name]
+	if !ok {
+		q = &memoryQueue{name: name, deliveries: make(chan Delivery, b.queueSize)}
+		if dlx, ok := args["x-dead-letter-exchange"].(string); ok {
+			q.deadLetter = dlx
+		}
+		b.queues[name] = q
+	}
+	return amqp.Queue{Name: name, Messages: len(q.deliveries)}, nil
+}
+
+func (b *MemoryBus) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.exchanges[exchange]; !ok {
+		return fmt.Errorf("exchange %s is not declared", exchange)
+	}
+	if _, ok := b.queues[name]; !ok {
+		return fmt.Errorf("queue %s is not declared", name)
+	}
+	for _, bind := range b.bindings {
+		if bind.Queue == name && bind.Exchange == exchange && bind.RoutingKey == key {
+			return nil
+		}
+	}
+	b.bindings = append(b.bindings, BindingSpec{Queue: name, Exchange: exchange, RoutingKey: key})
+	return nil
+}
+
+func (b *MemoryBus) QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[name]
+	if !ok {
+		return 0, nil
+	}
+	delete(b.queues, name)
+	kept := b.bindings[:0]
+	for _, bind := range b.bindings {
+		if bind.Queue != name {
+			kept = append(kept, bind)
+		}
+	}
+	b.bindings = kept
+	return len(q.deliveries), nil
+}
+
+// Publish раскладывает сообщение по всем подходящим очередям.
+func (b *MemoryBus) Publish(ctx context.Context, exchange, routingKey string, msg Publishing) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrBusClosed
+	}
+
+	targets, err := b.route(exchange, routingKey)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range targets {
+		d := Delivery{
+			Exchange:   exchange,
+			RoutingKey: routingKey,
+			MessageID:  msg.MessageID,
+			Type:       msg.Type,
+			Body:       msg.Body,
+			Attempt:    msg.Attempt,
+		}
+		queue := q.name
+		d.retry = func() error {
+			next := msg
+			next.Attempt++
+			return b.Publish(context.Background(), "", queue, next)
+		}
+		if q.deadLetter != "" {
+			dlx := q.deadLetter
+			d.nack = func() error {
+				return b.Publish(context.Background(), dlx, routingKey, msg)
+			}
+		}
+
+		select {
+		case q.deliveries <- d:
+		default:
+			return fmt.Errorf("queue %s is full", q.name)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) route(exchange, routingKey string) ([]*memoryQueue, error) {
+	if exchange == "" {
+		if q, ok := b.queues[routingKey]; ok {
+			return []*memoryQueue{q}, nil
+		}
+		return nil, nil
+	}
+
+	kind, ok := b.exchanges[exchange]
+	if !ok {
+		return nil, fmt.Errorf("exchange %s is not declared", exchange)
+	}
+
+	seen := make(map[string]bool)
+	var targets []*memoryQueue
+	for _, bind := range b.bindings {
+		if bind.Exchange != exchange || seen[bind.Queue] {
+			continue
+		}
+
+		var matched bool
+		switch kind {
+		case amqp.ExchangeFanout:
+			matched = true
+		case amqp.ExchangeDirect:
+			matched = bind.RoutingKey == routingKey
+		case amqp.ExchangeTopic:
+			matched = MatchTopic(bind.RoutingKey, routingKey)
+		}
+		if matched {
+			seen[bind.Queue] = true
+			targets = append(targets, b.queues[bind.Queue])
+		}
+	}
+	return targets, nil
+}
+
+// Consume отдаёт канал очереди.
+func (b *MemoryBus) Consume(queue string, prefetch int) (<-chan Delivery, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil, fmt.Errorf("queue %s is not declared", queue)
+	}
+	return q.deliveries, nil
+}
+
+// Depth возвращает число сообщений, ожидающих в очереди.
+func (b *MemoryBus) Depth(queue string) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return 0, fmt.Errorf("queue %s is not declared", queue)
+	}
+	return len(q.deliveries), nil
+}
+
+// Close закрывает все очереди, завершая циклы консьюмеров.
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for _, q := range b.queues {
+		close(q.deliveries)
+	}
+	return nil
+}
+
+// MatchTopic сопоставляет routing key с шаблоном topic-привязки:
+// '*' заменяет ровно одно слово, '#' — ноль или больше слов.
+func MatchTopic(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchWords(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(key) == 0 {
+				return false
+			}
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
@@ -0,0 +1,249 @@
+package rmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"ride.status.*", "ride.status.MATCHED", true},
+		{"ride.status.*", "ride.status", false},
+		{"ride.status.*", "ride.status.MATCHED.extra", false},
+		{"ride.*.MATCHED", "ride.status.MATCHED", true},
+		{"*", "ride", true},
+		{"*", "", true},
+		{"*", "ride.status", false},
+		{"#", "", true},
+		{"#", "ride.status.MATCHED", true},
+		{"ride.#", "ride", true},
+		{"ride.#", "ride.status.MATCHED", true},
+		{"ride.#", "driver.status", false},
+		{"#.MATCHED", "ride.status.MATCHED", true},
+		{"#.MATCHED", "MATCHED", true},
+		{"#.MATCHED", "ride.status.CANCELLED", false},
+		{"ride.#.MATCHED", "ride.MATCHED", true},
+		{"ride.#.MATCHED", "ride.a.b.MATCHED", true},
+		{"ride.#.*", "ride", false},
+		{"ride.#.*", "ride.status", true},
+		{"#.#", "a.b", true},
+		{"ride.status.MATCHED", "ride.status.MATCHED", true},
+		{"ride.status.MATCHED", "ride.status.matched", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+// declare объявляет exchange, очереди и привязки, останавливая тест при ошибке.
+func declare(t *testing.T, bus *MemoryBus, top Topology) {
+	t.Helper()
+	if err := top.Apply(bus); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+}
+
+func receive(t *testing.T, bus *MemoryBus, queue string) Delivery {
+	t.Helper()
+	ch, err := bus.Consume(queue, 1)
+	if err != nil {
+		t.Fatalf("Consume(%s): %v", queue, err)
+	}
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(time.Second):
+		t.Fatalf("no delivery in %s", queue)
+		return Delivery{}
+	}
+}
+
+func assertDepth(t *testing.T, bus *MemoryBus, queue string, want int) {
+	t.Helper()
+	n, err := bus.Depth(queue)
+	if err != nil {
+		t.Fatalf("Depth(%s): %v", queue, err)
+	}
+	if n != want {
+		t.Fatalf("Depth(%s) = %d, want %d", queue, n, want)
+	}
+}
+
+func TestMemoryBusFanout(t *testing.T) {
+	bus := NewMemoryBus()
+	declare(t, bus, Topology{
+		Exchanges: []ExchangeSpec{{Name: "fan", Kind: amqp.ExchangeFanout}},
+		Queues:    []QueueSpec{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Bindings: []BindingSpec{
+			{Queue: "a", Exchange: "fan", RoutingKey: "one"},
+			{Queue: "b", Exchange: "fan", RoutingKey: "two"},
+			// Повторная привязка той же очереди не дублирует доставку.
+			{Queue: "b", Exchange: "fan", RoutingKey: "three"},
+		},
+	})
+
+	if err := bus.Publish(context.Background(), "fan", "any.key", Publishing{MessageID: "m1", Body: []byte("x")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	assertDepth(t, bus, "a", 1)
+	assertDepth(t, bus, "b", 1)
+	assertDepth(t, bus, "c", 0)
+
+	d := receive(t, bus, "a")
+	if d.Exchange != "fan" || d.RoutingKey != "any.key" || d.MessageID != "m1" || string(d.Body) != "x" {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+}
+
+func TestMemoryBusDirect(t *testing.T) {
+	bus := NewMemoryBus()
+	declare(t, bus, Topology{
+		Exchanges: []ExchangeSpec{{Name: "direct", Kind: amqp.ExchangeDirect}},
+		Queues:    []QueueSpec{{Name: "economy"}, {Name: "premium"}},
+		Bindings: []BindingSpec{
+			{Queue: "economy", Exchange: "direct", RoutingKey: "ECONOMY"},
+			{Queue: "premium", Exchange: "direct", RoutingKey: "PREMIUM"},
+		},
+	})
+	ctx := context.Background()
+
+	for _, key := range []string{"ECONOMY", "ECONOMY", "PREMIUM", "XL", "*"} {
+		if err := bus.Publish(ctx, "direct", key, Publishing{Body: []byte(key)}); err != nil {
+			t.Fatalf("Publish(%s): %v", key, err)
+		}
+	}
+	assertDepth(t, bus, "economy", 2)
+	assertDepth(t, bus, "premium", 1)
+}
+
+func TestMemoryBusDefaultExchange(t *testing.T) {
+	bus := NewMemoryBus()
+	declare(t, bus, Topology{Queues: []QueueSpec{{Name: "replies"}}})
+	ctx := context.Background()
+
+	if err := bus.Publish(ctx, "", "replies", Publishing{Body: []byte("hi")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := bus.Publish(ctx, "", "missing", Publishing{Body: []byte("lost")}); err != nil {
+		t.Fatalf("Publish to missing queue: %v", err)
+	}
+	assertDepth(t, bus, "replies", 1)
+
+	if err := bus.Publish(ctx, "undeclared", "key", Publishing{}); err == nil {
+		t.Fatal("Publish to undeclared exchange succeeded")
+	}
+}
+
+func TestMemoryBusDeadLetterOnNack(t *testing.T) {
+	bus := NewMemoryBus()
+	declare(t, bus, Topology{
+		Exchanges: []ExchangeSpec{
+			{Name: "work", Kind: amqp.ExchangeTopic},
+			{Name: "dlx", Kind: amqp.ExchangeTopic},
+		},
+		Queues: []QueueSpec{
+			{Name: "jobs", DeadLetter: "dlx"},
+			{Name: "no_dlx"},
+			{Name: "dead"},
+		},
+		Bindings: []BindingSpec{
+			{Queue: "jobs", Exchange: "work", RoutingKey: "job.*"},
+			{Queue: "no_dlx", Exchange: "work", RoutingKey: "job.*"},
+			{Queue: "dead", Exchange: "dlx", RoutingKey: "#"},
+		},
+	})
+
+	if err := bus.Publish(context.Background(), "work", "job.resize", Publishing{MessageID: "m1", Type: "job", Body: []byte("payload")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	if err := receive(t, bus, "jobs").Nack(); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	if err := receive(t, bus, "no_dlx").Nack(); err != nil {
+		t.Fatalf("Nack without DLX: %v", err)
+	}
+	assertDepth(t, bus, "jobs", 0)
+	assertDepth(t, bus, "no_dlx", 0)
+	assertDepth(t, bus, "dead", 1)
+
+	// Сообщение в DLX сохраняет исходный routing key и идентификатор.
+	d := receive(t, bus, "dead")
+	if d.Exchange != "dlx" || d.RoutingKey != "job.resize" || d.MessageID != "m1" || d.Type != "job" || string(d.Body) != "payload" {
+		t.Fatalf("unexpected dead letter %+v", d)
+	}
+	if err := d.Ack(); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+}
+
+func TestMemoryBusSettle(t *testing.T) {
+	bus := NewMemoryBus()
+	declare(t, bus, Topology{
+		Exchanges: []ExchangeSpec{{Name: "dlx", Kind: amqp.ExchangeFanout}},
+		Queues:    []QueueSpec{{Name: "jobs", DeadLetter: "dlx"}, {Name: "dead"}},
+		Bindings:  []BindingSpec{{Queue: "dead", Exchange: "dlx"}},
+	})
+	ctx := context.Background()
+
+	for _, id := range []string{"ok", "transient", "broken"} {
+		if err := bus.Publish(ctx, "", "jobs", Publishing{MessageID: id}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	Settle(receive(t, bus, "jobs"), nil, 2)
+	Settle(receive(t, bus, "jobs"), errors.New("database is down"), 2)
+	Settle(receive(t, bus, "jobs"), Permanent(errors.New("bad payload")), 2)
+
+	// Временная ошибка возвращает сообщение в конец очереди с новым номером попытки.
+	retried := receive(t, bus, "jobs")
+	if retried.MessageID != "transient" || retried.Attempt != 1 {
+		t.Fatalf("retried %q attempt %d, want transient attempt 1", retried.MessageID, retried.Attempt)
+	}
+	if d := receive(t, bus, "dead"); d.MessageID != "broken" {
+		t.Fatalf("dead letter %q, want broken", d.MessageID)
+	}
+
+	// Попытки исчерпаны — сообщение уходит в DLX.
+	Settle(retried, errors.New("database is down"), 2)
+	assertDepth(t, bus, "jobs", 0)
+	if d := receive(t, bus, "dead"); d.MessageID != "transient" {
+		t.Fatalf("dead letter %q, want transient", d.MessageID)
+	}
+}
+
+func TestMemoryBusClose(t *testing.T) {
+	bus := NewMemoryBus()
+	declare(t, bus, Topology{Queues: []QueueSpec{{Name: "q"}}})
+
+	ch, err := bus.Consume("q", 1)
+	if err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("delivery channel is still open")
+	}
+	if err := bus.Publish(context.Background(), "", "q", Publishing{}); !errors.Is(err, ErrBusClosed) {
+		t.Fatalf("Publish after Close = %v, want ErrBusClosed", err)
+	}
+	if _, err := bus.Consume("q", 1); !errors.Is(err, ErrBusClosed) {
+		t.Fatalf("Consume after Close = %v, want ErrBusClosed", err)
+	}
+}
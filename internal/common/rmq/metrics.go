@@ -0,0 +1,142 @@
+package rmq
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DepthInspector сообщает, сколько сообщений ждёт в очереди брокера.
+type DepthInspector interface {
+	Depth(queue string) (int, error)
+}
+
+type ConsumerStats struct {
+	Queue          string    `json:"queue"`
+	Workers        int       `json:"workers"`
+	Prefetch       int       `json:"prefetch"`
+	QueueDepth     int       `json:"queue_depth"`
+	InFlight       int64     `json:"in_flight"`
+	Processed      int64     `json:"processed"`
+	Failed         int64     `json:"failed"`
+	AvgLatencyMs   float64   `json:"avg_latency_ms"`
+	MaxLatencyMs   float64   `json:"max_latency_ms"`
+	LastLatencyMs  float64   `json:"last_latency_ms"`
+	LastHandledAt  time.Time `json:"last_handled_at,omitempty"`
+	DepthAvailable bool      `json:"depth_available"`
+}
+
+// Metrics собирает статистику консьюмеров процесса для подбора prefetch и числа воркеров.
+type Metrics struct {
+	mu        sync.Mutex
+	consumers map[string]*consumerMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{consumers: make(map[string]*consumerMetrics)}
+}
+
+type consumerMetrics struct {
+	mu        sync.Mutex
+	queue     string
+	workers   int
+	prefetch  int
+	inFlight  int64
+	processed int64
+	failed    int64
+	total     time.Duration
+	max       time.Duration
+	last      time.Duration
+	lastAt    time.Time
+}
+
+func (m *Metrics) consumer(queue string, prefetch, workers int) *consumerMetrics {
+	c := &consumerMetrics{queue: queue, prefetch: prefetch, workers: workers}
+	if m == nil {
+		return c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.consumers[queue]; ok {
+		existing.mu.Lock()
+		existing.workers += workers
+		existing.mu.Unlock()
+		return existing
+	}
+	m.consumers[queue] = c
+	return c
+}
+
+func (c *consumerMetrics) received() {
+	c.mu.Lock()
+	c.inFlight++
+	c.mu.Unlock()
+}
+
+func (c *consumerMetrics) done(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	if err != nil {
+		c.failed++
+	} else {
+		c.processed++
+	}
+	c.total += latency
+	c.last = latency
+	c.lastAt = time.Now()
+	if latency > c.max {
+		c.max = latency
+	}
+}
+
+// Snapshot возвращает статистику по всем консьюмерам.
+func (m *Metrics) Snapshot(depth DepthInspector) []ConsumerStats {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	consumers := make([]*consumerMetrics, 0, len(m.consumers))
+	for _, c := range m.consumers {
+		consumers = append(consumers, c)
+	}
+	m.mu.Unlock()
+
+	stats := make([]ConsumerStats, 0, len(consumers))
+	for _, c := range consumers {
+		c.mu.Lock()
+		s := ConsumerStats{
+			Queue:         c.queue,
+			Workers:       c.workers,
+			Prefetch:      c.prefetch,
+			InFlight:      c.inFlight,
+			Processed:     c.processed,
+			Failed:        c.failed,
+			MaxLatencyMs:  millis(c.max),
+			LastLatencyMs: millis(c.last),
+			LastHandledAt: c.lastAt,
+		}
+		if n := c.processed + c.failed; n > 0 {
+			s.AvgLatencyMs = millis(c.total / time.Duration(n))
+		}
+		c.mu.Unlock()
+
+		if depth != nil {
+			if n, err := depth.Depth(c.queue); err == nil {
+				s.QueueDepth = n
+				s.DepthAvailable = true
+			}
+		}
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Queue < stats[j].Queue })
+	return stats
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
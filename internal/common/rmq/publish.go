@@ -0,0 +1,33 @@
+package rmq
+
+import (
+	"context"
+	"fmt"
+
+	"ride-hail-system/internal/common/logger"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Enqueuer записывает сообщение для публикации в рамках транзакции вызывающего кода.
+type Enqueuer interface {
+	Enqueue(ctx context.Context, tx pgx.Tx, exchange, routingKey string, payload any) error
+}
+
+// EnqueueRideStatus записывает смену статуса поездки в outbox в той же tx, что и сам статус.
+func EnqueueRideStatus(ctx context.Context, enq Enqueuer, tx pgx.Tx, msg RideStatusUpdateMessage) error {
+	env, err := NewEnvelope(TypeRideStatus, 1, msg.RideID, msg)
+	if err != nil {
+		logger.Error("publish_ride_status", "failed to build envelope", "", msg.RideID, err.Error())
+		return fmt.Errorf("failed to build envelope: %w", err)
+	}
+
+	routingKey := fmt.Sprintf("ride.status.%s", msg.Status)
+	if err := enq.Enqueue(ctx, tx, ExchangeRide, routingKey, env); err != nil {
+		logger.Error("publish_ride_status", "failed to enqueue ride status", "", msg.RideID, err.Error())
+		return fmt.Errorf("failed to enqueue ride status: %w", err)
+	}
+
+	logger.Info("publish_ride_status", "ride status enqueued to outbox: "+msg.Status, "", msg.RideID)
+	return nil
+}
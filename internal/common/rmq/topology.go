@@ -0,0 +1,256 @@
+package rmq
+
+import (
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	ExchangeRide     = "ride_topic"
+	ExchangeDriver   = "driver_topic"
+	ExchangeLocation = "location_fanout"
+	ExchangeDead     = "dead_letter"
+	// ExchangeWSDelivery доставляет сообщения WebSocket между репликами.
+	ExchangeWSDelivery = "ws_delivery"
+
+	// Очереди с DLX и TTL объявлены под новыми именами: RabbitMQ не позволяет
+	// поменять аргументы уже существующей очереди.
+	QueueRideRequests    = "ride_requests_v2"
+	QueueDriverMatching  = "driver_matching_v2"
+	QueueDriverResponses = "driver_responses_v2"
+	QueueLocationUpdates = "location_updates_ride_v2"
+	QueueDeadLetters     = "dead_letters"
+	QueueChatRideStatus  = "chat_ride_status"
+	// Очереди живой ленты администратора.
+	QueueAdminRideStatus   = "admin_ride_status"
+	QueueAdminDriverStatus = "admin_driver_status"
+	QueueAdminLocations    = "admin_locations"
+)
+
+const (
+	defaultPrefetch = 10
+	defaultWorkers  = 1
+)
+
+type ExchangeSpec struct {
+	Name    string
+	Kind    string
+	Durable bool
+}
+
+type QueueSpec struct {
+	Name       string
+	Durable    bool
+	DeadLetter string
+	MessageTTL time.Duration
+	// MaxLength — сколько сообщений держит очередь; старые вытесняются.
+	MaxLength int
+	Prefetch  int
+	Workers   int
+}
+
+type BindingSpec struct {
+	Queue      string
+	Exchange   string
+	RoutingKey string
+}
+
+// Topology описывает все exchange, очереди и привязки системы в одном месте.
+type Topology struct {
+	Exchanges []ExchangeSpec
+	Queues    []QueueSpec
+	Bindings  []BindingSpec
+	// Retired — очереди прежних версий; удаляются вместе с привязками.
+	Retired []string
+}
+
+// Declarer — часть *amqp.Channel, нужная для применения топологии.
+type Declarer interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error)
+}
+
+func DefaultTopology() Topology {
+	return Topology{
+		Exchanges: []ExchangeSpec{
+			{Name: ExchangeRide, Kind: amqp.ExchangeTopic, Durable: true},
+			{Name: ExchangeDriver, Kind: amqp.ExchangeTopic, Durable: true},
+			{Name: ExchangeLocation, Kind: amqp.ExchangeFanout, Durable: true},
+			{Name: ExchangeDead, Kind: amqp.ExchangeTopic, Durable: true},
+			{Name: ExchangeWSDelivery, Kind: amqp.ExchangeTopic, Durable: true},
+		},
+		Queues: []QueueSpec{
+			// Предложение поездки теряет смысл после таймаута поиска водителя.
+			{Name: QueueRideRequests, Durable: true, DeadLetter: ExchangeDead, MessageTTL: 30 * time.Second, Prefetch: 20, Workers: 4},
+			{Name: QueueDriverMatching, Durable: true, DeadLetter: ExchangeDead, Prefetch: 20, Workers: 4},
+			{Name: QueueDriverResponses, Durable: true, DeadLetter: ExchangeDead, Prefetch: 20, Workers: 4},
+			// Устаревшие координаты бесполезны, следующая точка придёт через секунды.
+			{Name: QueueLocationUpdates, Durable: true, DeadLetter: ExchangeDead, MessageTTL: 10 * time.Second, Prefetch: 50, Workers: 8},
+			// Отклонённые сообщения хранятся для разбора, но не бесконечно.
+			{Name: QueueDeadLetters, Durable: true, MessageTTL: 72 * time.Hour, MaxLength: 10000},
+			// Чат закрывается по завершению или отмене поездки.
+			{Name: QueueChatRideStatus, Durable: true, DeadLetter: ExchangeDead},
+			{Name: QueueAdminRideStatus, Durable: true, DeadLetter: ExchangeDead},
+			{Name: QueueAdminDriverStatus, Durable: true, DeadLetter: ExchangeDead},
+			// Лента показывает только свежие позиции, старые отбрасываются без DLX.
+			{Name: QueueAdminLocations, Durable: true, MessageTTL: 5 * time.Second, Prefetch: 50, Workers: 4},
+		},
+		Bindings: []BindingSpec{
+			{Queue: QueueRideRequests, Exchange: ExchangeRide, RoutingKey: "ride.request.*"},
+			{Queue: QueueDriverMatching, Exchange: ExchangeRide, RoutingKey: "ride.passenger.*"},
+			{Queue: QueueDriverResponses, Exchange: ExchangeDriver, RoutingKey: "driver.response.*"},
+			{Queue: QueueLocationUpdates, Exchange: ExchangeLocation, RoutingKey: ""},
+			{Queue: QueueDeadLetters, Exchange: ExchangeDead, RoutingKey: "#"},
+			{Queue: QueueChatRideStatus, Exchange: ExchangeRide, RoutingKey: "ride.status.*"},
+			{Queue: QueueAdminRideStatus, Exchange: ExchangeRide, RoutingKey: "ride.status.*"},
+			{Queue: QueueAdminDriverStatus, Exchange: ExchangeDriver, RoutingKey: "driver.state.*"},
+			{Queue: QueueAdminLocations, Exchange: ExchangeLocation, RoutingKey: ""},
+		},
+		// ride_status и driver_status ни один сервис не читал.
+		Retired: []string{"ride_requests", "driver_matching", "driver_responses", "location_updates_ride", "ride_status", "driver_status"},
+	}
+}
+
+// Validate проверяет, что привязки и DLX ссылаются на объявленные объекты.
+func (t Topology) Validate() error {
+	exchanges := make(map[string]string, len(t.Exchanges))
+	for _, e := range t.Exchanges {
+		if kind, ok := exchanges[e.Name]; ok && kind != e.Kind {
+			return fmt.Errorf("exchange %s declared as both %s and %s", e.Name, kind, e.Kind)
+		}
+		exchanges[e.Name] = e.Kind
+	}
+
+	queues := make(map[string]bool, len(t.Queues))
+	for _, q := range t.Queues {
+		if queues[q.Name] {
+			return fmt.Errorf("queue %s declared twice", q.Name)
+		}
+		queues[q.Name] = true
+		if q.DeadLetter != "" {
+			if _, ok := exchanges[q.DeadLetter]; !ok {
+				return fmt.Errorf("queue %s uses undeclared dead letter exchange %s", q.Name, q.DeadLetter)
+			}
+		}
+	}
+
+	for _, name := range t.Retired {
+		if queues[name] {
+			return fmt.Errorf("queue %s is both declared and retired", name)
+		}
+	}
+
+	for _, b := range t.Bindings {
+		if _, ok := exchanges[b.Exchange]; !ok {
+			return fmt.Errorf("binding %s -> %s references undeclared exchange", b.Queue, b.Exchange)
+		}
+		if !queues[b.Queue] {
+			return fmt.Errorf("binding %s -> %s references undeclared queue", b.Queue, b.Exchange)
+		}
+	}
+	return nil
+}
+
+func (t Topology) Apply(d Declarer) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("invalid topology: %w", err)
+	}
+
+	for _, e := range t.Exchanges {
+		if err := d.ExchangeDeclare(e.Name, e.Kind, e.Durable, false, false, false, nil); err != nil {
+			logger.Error("rmq_topology", "Failed to declare exchange "+e.Name, "", "", err.Error())
+			return fmt.Errorf("failed to declare exchange %s: %w", e.Name, err)
+		}
+	}
+
+	for _, q := range t.Queues {
+		if _, err := d.QueueDeclare(q.Name, q.Durable, false, false, false, q.args()); err != nil {
+			logger.Error("rmq_topology", "Failed to declare queue "+q.Name, "", "", err.Error())
+			return fmt.Errorf("failed to declare queue %s: %w", q.Name, err)
+		}
+	}
+
+	for _, b := range t.Bindings {
+		if err := d.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, nil); err != nil {
+			logger.Error("rmq_topology", "Failed to bind queue "+b.Queue, "", "", err.Error())
+			return fmt.Errorf("failed to bind queue %s to %s: %w", b.Queue, b.Exchange, err)
+		}
+	}
+
+	// Удаление несуществующей очереди в RabbitMQ не ошибка. Сообщения в
+	// прежних очередях кратковременные и после переезда не нужны.
+	for _, name := range t.Retired {
+		purged, err := d.QueueDelete(name, false, false, false)
+		if err != nil {
+			logger.Error("rmq_topology", "Failed to delete retired queue "+name, "", "", err.Error())
+			return fmt.Errorf("failed to delete retired queue %s: %w", name, err)
+		}
+		if purged > 0 {
+			logger.Warn("rmq_topology", fmt.Sprintf("Retired queue %s deleted with %d messages", name, purged), "", "", "")
+		}
+	}
+
+	logger.Info("rmq_topology", fmt.Sprintf("Topology applied: %d exchanges, %d queues, %d bindings",
+		len(t.Exchanges), len(t.Queues), len(t.Bindings)), "", "")
+	return nil
+}
+
+func (t Topology) Prefetch(queue string) int {
+	for _, q := range t.Queues {
+		if q.Name == queue && q.Prefetch > 0 {
+			return q.Prefetch
+		}
+	}
+	return defaultPrefetch
+}
+
+func (t Topology) Workers(queue string) int {
+	for _, q := range t.Queues {
+		if q.Name == queue && q.Workers > 0 {
+			return q.Workers
+		}
+	}
+	return defaultWorkers
+}
+
+// Tune возвращает копию топологии с переопределёнными prefetch и числом воркеров очереди.
+func (t Topology) Tune(queue string, prefetch, workers int) Topology {
+	queues := make([]QueueSpec, len(t.Queues))
+	copy(queues, t.Queues)
+	for i := range queues {
+		if queues[i].Name != queue {
+			continue
+		}
+		if prefetch > 0 {
+			queues[i].Prefetch = prefetch
+		}
+		if workers > 0 {
+			queues[i].Workers = workers
+		}
+	}
+	t.Queues = queues
+	return t
+}
+
+func (q QueueSpec) args() amqp.Table {
+	args := amqp.Table{}
+	if q.DeadLetter != "" {
+		args["x-dead-letter-exchange"] = q.DeadLetter
+	}
+	if q.MessageTTL > 0 {
+		args["x-message-ttl"] = int64(q.MessageTTL / time.Millisecond)
+	}
+	if q.MaxLength > 0 {
+		args["x-max-length"] = int64(q.MaxLength)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
@@ -0,0 +1,266 @@
+package rmq
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func newTopologyBus(t *testing.T) *MemoryBus {
+	t.Helper()
+	bus := NewMemoryBus()
+	if err := DefaultTopology().Apply(bus); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	return bus
+}
+
+func depths(t *testing.T, bus *MemoryBus) map[string]int {
+	t.Helper()
+	out := make(map[string]int)
+	for _, q := range DefaultTopology().Queues {
+		n, err := bus.Depth(q.Name)
+		if err != nil {
+			t.Fatalf("Depth(%s): %v", q.Name, err)
+		}
+		if n > 0 {
+			out[q.Name] = n
+		}
+	}
+	return out
+}
+
+func TestDefaultTopologyExchangeKinds(t *testing.T) {
+	bus := newTopologyBus(t)
+
+	want := map[string]string{
+		ExchangeRide:       amqp.ExchangeTopic,
+		ExchangeDriver:     amqp.ExchangeTopic,
+		ExchangeLocation:   amqp.ExchangeFanout,
+		ExchangeDead:       amqp.ExchangeTopic,
+		ExchangeWSDelivery: amqp.ExchangeTopic,
+	}
+	for name, kind := range want {
+		if got := bus.exchanges[name]; got != kind {
+			t.Errorf("exchange %s is %q, want %q", name, got, kind)
+		}
+	}
+
+	// Повторное применение идемпотентно, а смена типа exchange — ошибка.
+	if err := DefaultTopology().Apply(bus); err != nil {
+		t.Fatalf("second Apply: %v", err)
+	}
+	if err := bus.ExchangeDeclare(ExchangeLocation, amqp.ExchangeTopic, true, false, false, false, nil); err == nil {
+		t.Fatal("redeclaring fanout exchange as topic succeeded")
+	}
+}
+
+func TestDefaultTopologyRouting(t *testing.T) {
+	tests := []struct {
+		exchange   string
+		routingKey string
+		want       map[string]int
+	}{
+		{ExchangeRide, "ride.request.ECONOMY", map[string]int{QueueRideRequests: 1}},
+		{ExchangeRide, "ride.status.COMPLETED", map[string]int{QueueChatRideStatus: 1, QueueAdminRideStatus: 1}},
+		{ExchangeRide, "ride.passenger.matched", map[string]int{QueueDriverMatching: 1}},
+		{ExchangeRide, "ride.request.ECONOMY.extra", map[string]int{}},
+		{ExchangeDriver, "driver.response.ride-1", map[string]int{QueueDriverResponses: 1}},
+		{ExchangeDriver, "driver.status.d-1", map[string]int{}},
+		{ExchangeDriver, "driver.state.d-1", map[string]int{QueueAdminDriverStatus: 1}},
+		{ExchangeLocation, "", map[string]int{QueueLocationUpdates: 1, QueueAdminLocations: 1}},
+		{ExchangeLocation, "ignored.by.fanout", map[string]int{QueueLocationUpdates: 1, QueueAdminLocations: 1}},
+		{ExchangeDead, "ride.request.ECONOMY", map[string]int{QueueDeadLetters: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exchange+"/"+tt.routingKey, func(t *testing.T) {
+			bus := newTopologyBus(t)
+			if err := bus.Publish(context.Background(), tt.exchange, tt.routingKey, Publishing{Body: []byte("{}")}); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			got := depths(t, bus)
+			if len(got) != len(tt.want) {
+				t.Fatalf("routed to %v, want %v", got, tt.want)
+			}
+			for q, n := range tt.want {
+				if got[q] != n {
+					t.Fatalf("routed to %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultTopologyDeadLetters(t *testing.T) {
+	bus := newTopologyBus(t)
+	ctx := context.Background()
+
+	if err := bus.Publish(ctx, ExchangeRide, "ride.request.ECONOMY", Publishing{MessageID: "m1", Body: []byte("{}")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := bus.Publish(ctx, ExchangeLocation, "", Publishing{MessageID: "m2", Body: []byte("{}")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	nackFrom := func(queue string) {
+		ch, err := bus.Consume(queue, 1)
+		if err != nil {
+			t.Fatalf("Consume(%s): %v", queue, err)
+		}
+		if err := (<-ch).Nack(); err != nil {
+			t.Fatalf("Nack from %s: %v", queue, err)
+		}
+	}
+	nackFrom(QueueRideRequests)
+	nackFrom(QueueAdminLocations)
+	nackFrom(QueueLocationUpdates)
+
+	// У admin_locations нет DLX: отклонённая позиция просто пропадает.
+	if got := depths(t, bus); len(got) != 1 || got[QueueDeadLetters] != 2 {
+		t.Fatalf("depths after nack = %v, want dead_letters: 2", got)
+	}
+}
+
+func TestDefaultTopologyRetiresLegacyQueues(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx := context.Background()
+
+	// Очереди в том виде, в каком их объявляла прежняя версия: без аргументов.
+	if err := bus.ExchangeDeclare(ExchangeRide, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		t.Fatalf("ExchangeDeclare: %v", err)
+	}
+	for _, name := range []string{"ride_requests", "ride_status"} {
+		if _, err := bus.QueueDeclare(name, true, false, false, false, nil); err != nil {
+			t.Fatalf("QueueDeclare(%s): %v", name, err)
+		}
+	}
+	if err := bus.QueueBind("ride_requests", "ride.request.*", ExchangeRide, false, nil); err != nil {
+		t.Fatalf("QueueBind: %v", err)
+	}
+
+	if err := DefaultTopology().Apply(bus); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for _, name := range []string{"ride_requests", "ride_status"} {
+		if _, ok := bus.queues[name]; ok {
+			t.Errorf("retired queue %s still declared", name)
+		}
+	}
+
+	if err := bus.Publish(ctx, ExchangeRide, "ride.request.ECONOMY", Publishing{Body: []byte("{}")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got := depths(t, bus); len(got) != 1 || got[QueueRideRequests] != 1 {
+		t.Fatalf("routed to %v, want %s: 1", got, QueueRideRequests)
+	}
+}
+
+func TestDeadLettersBounded(t *testing.T) {
+	for _, q := range DefaultTopology().Queues {
+		if q.Name != QueueDeadLetters {
+			continue
+		}
+		args := q.args()
+		if args["x-max-length"] == nil || args["x-message-ttl"] == nil {
+			t.Fatalf("dead letters args = %v, want max length and ttl", args)
+		}
+		return
+	}
+	t.Fatalf("queue %s not declared", QueueDeadLetters)
+}
+
+func TestDefaultTopologyValidate(t *testing.T) {
+	if err := DefaultTopology().Validate(); err != nil {
+		t.Fatalf("DefaultTopology is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Topology)
+		want   string
+	}{
+		{
+			name: "conflicting exchange kinds",
+			mutate: func(t *Topology) {
+				t.Exchanges = append(t.Exchanges, ExchangeSpec{Name: ExchangeLocation, Kind: amqp.ExchangeTopic, Durable: true})
+			},
+			want: "declared as both",
+		},
+		{
+			name: "duplicate queue",
+			mutate: func(t *Topology) {
+				t.Queues = append(t.Queues, QueueSpec{Name: QueueChatRideStatus, Durable: true})
+			},
+			want: "declared twice",
+		},
+		{
+			name: "undeclared dead letter exchange",
+			mutate: func(t *Topology) {
+				t.Queues = append(t.Queues, QueueSpec{Name: "orphan", DeadLetter: "missing_dlx"})
+			},
+			want: "undeclared dead letter exchange",
+		},
+		{
+			name: "binding to undeclared exchange",
+			mutate: func(t *Topology) {
+				t.Bindings = append(t.Bindings, BindingSpec{Queue: QueueChatRideStatus, Exchange: "missing", RoutingKey: "#"})
+			},
+			want: "undeclared exchange",
+		},
+		{
+			name: "binding of undeclared queue",
+			mutate: func(t *Topology) {
+				t.Bindings = append(t.Bindings, BindingSpec{Queue: "missing", Exchange: ExchangeRide, RoutingKey: "#"})
+			},
+			want: "undeclared queue",
+		},
+		{
+			name: "retired queue still declared",
+			mutate: func(t *Topology) {
+				t.Retired = append(t.Retired, QueueChatRideStatus)
+			},
+			want: "both declared and retired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top := DefaultTopology()
+			tt.mutate(&top)
+
+			err := top.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want error containing %q", err, tt.want)
+			}
+
+			bus := NewMemoryBus()
+			if err := top.Apply(bus); err == nil {
+				t.Fatal("Apply accepted invalid topology")
+			}
+			if len(bus.exchanges) != 0 || len(bus.queues) != 0 {
+				t.Fatal("Apply declared objects of invalid topology")
+			}
+		})
+	}
+}
+
+func TestTopologyTune(t *testing.T) {
+	base := DefaultTopology()
+	tuned := base.Tune(QueueLocationUpdates, 100, 0)
+
+	if got := tuned.Prefetch(QueueLocationUpdates); got != 100 {
+		t.Fatalf("tuned prefetch = %d, want 100", got)
+	}
+	if got := tuned.Workers(QueueLocationUpdates); got != base.Workers(QueueLocationUpdates) {
+		t.Fatalf("tuned workers = %d, want unchanged %d", got, base.Workers(QueueLocationUpdates))
+	}
+	if got := base.Prefetch(QueueLocationUpdates); got == 100 {
+		t.Fatal("Tune modified the original topology")
+	}
+	if got := base.Workers(QueueChatRideStatus); got != defaultWorkers {
+		t.Fatalf("default workers = %d, want %d", got, defaultWorkers)
+	}
+}
@@ -0,0 +1,389 @@
+package websocket
+
+// Типы сообщений протокола.
+const (
+	MsgError = "error"
+
+	// Доставка с подтверждением: клиент подтверждает seq и после
+	// переподключения запрашивает пропущенное.
+	MsgAck     = "ack"
+	MsgResume  = "resume"
+	MsgResumed = "resumed"
+
+	// От водителя.
+	MsgRideResponse   = "ride_response"
+	MsgLocationUpdate = "location_update"
+
+	// От пассажира.
+	MsgPassengerDetails = "passenger_details"
+
+	// Водителю.
+	MsgRideOffer   = "ride_offer"
+	MsgRideDetails = "ride_details"
+
+	// Пассажиру.
+	MsgRideMatched          = "ride_matched"
+	MsgDriverLocationUpdate = "driver_location_update"
+	MsgRideStatusUpdate     = "ride_status_update"
+
+	// Чат поездки, в обе стороны между пассажиром и водителем.
+	MsgChatSend    = "chat_send"
+	MsgChatMark    = "chat_mark"
+	MsgChatReceipt = "chat_receipt"
+	MsgChatMessage = "chat_message"
+	MsgChatClosed  = "chat_closed"
+
+	// Живая лента администратора (топик admin:ops).
+	MsgAdminRideStatus     = "admin_ride_status"
+	MsgAdminDriverStatus   = "admin_driver_status"
+	MsgAdminDriverLocation = "admin_driver_location"
+	MsgAdminMetrics        = "admin_metrics"
+)
+
+func latLng() *Schema {
+	return Object(map[string]*Schema{
+		"lat": Number().Between(-90, 90),
+		"lng": Number().Between(-180, 180),
+	}, "lat", "lng")
+}
+
+func latitudeLongitude() *Schema {
+	return Object(map[string]*Schema{
+		"latitude":  Number().Between(-90, 90),
+		"longitude": Number().Between(-180, 180),
+	}, "latitude", "longitude")
+}
+
+func address() *Schema {
+	return Object(map[string]*Schema{
+		"lat":     Number().Between(-90, 90),
+		"lng":     Number().Between(-180, 180),
+		"address": String(),
+	}, "lat", "lng")
+}
+
+// DefaultRegistry возвращает каталог всех сообщений системы.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.MustRegister(
+		MessageType{
+			Type:        MsgError,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver, RolePassenger, RoleAdmin},
+			Description: "Reply to an unknown, invalid or rejected client message.",
+			Schema: Object(map[string]*Schema{
+				"code": String().OneOf(ErrCodeInvalidJSON, ErrCodeUnknownType, ErrCodeUnsupportedVersion,
+					ErrCodeInvalidPayload, ErrCodeRejected),
+				"message": String(),
+				"ref_id":  String().Describe("id of the client message that caused the error"),
+				"details": ArrayOf(Object(map[string]*Schema{
+					"path":    String(),
+					"message": String(),
+				}, "path", "message")),
+			}, "code", "message"),
+		},
+		MessageType{
+			Type:        MsgAck,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Client confirms all messages up to seq; they are dropped from the replay buffer.",
+			Schema: Object(map[string]*Schema{
+				"seq": Integer().Min(0),
+			}, "seq"),
+		},
+		MessageType{
+			Type:        MsgResume,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Sent after reconnecting: replay retained messages with seq greater than since. Clients must ignore seq they already have.",
+			Schema: Object(map[string]*Schema{
+				"since": Integer().Min(0).Describe("last seq the client processed, 0 if none"),
+			}, "since"),
+		},
+		MessageType{
+			Type:        MsgResumed,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Ends a replay. On gap or reset the client should reload ride state over HTTP.",
+			Schema: Object(map[string]*Schema{
+				"since":    Integer().Min(0),
+				"last_seq": Integer().Min(0),
+				"replayed": Integer().Min(0),
+				"gap":      Boolean().Describe("some messages after since have expired"),
+				"reset":    Boolean().Describe("the server no longer has this stream, seq restarted"),
+			}, "since", "last_seq", "replayed", "gap", "reset"),
+		},
+		MessageType{
+			Type:        MsgRideResponse,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RoleDriver},
+			Description: "Driver accepts or declines a ride offer.",
+			Schema: Object(map[string]*Schema{
+				"offer_id":         String(),
+				"ride_id":          String().WithFormat("uuid"),
+				"accepted":         Boolean(),
+				"current_location": latitudeLongitude(),
+			}, "ride_id", "accepted", "current_location"),
+		},
+		MessageType{
+			Type:        MsgLocationUpdate,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RoleDriver},
+			Description: "Driver position during a ride.",
+			Schema: Object(map[string]*Schema{
+				"ride_id":         String().WithFormat("uuid"),
+				"location":        latLng(),
+				"speed_kmh":       Number().Min(0),
+				"heading_degrees": Number().Between(0, 360),
+				"timestamp":       String().WithFormat("date-time"),
+			}, "ride_id", "location"),
+		},
+		MessageType{
+			Type:        MsgPassengerDetails,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RolePassenger},
+			Description: "Passenger pickup details forwarded to the matched driver.",
+			Schema: Object(map[string]*Schema{
+				"ride_id":         String().WithFormat("uuid"),
+				"passenger_name":  String().Length(1, 100),
+				"passenger_phone": String().Length(0, 32).Describe("kept server-side; the driver receives a proxy id instead"),
+				"pickup_location": Object(map[string]*Schema{
+					"latitude":  Number().Between(-90, 90),
+					"longitude": Number().Between(-180, 180),
+					"address":   String(),
+					"notes":     String().Length(0, 500),
+				}, "latitude", "longitude"),
+			}, "ride_id", "pickup_location"),
+		},
+		MessageType{
+			Type:        MsgRideOffer,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver},
+			Description: "New ride available for nearby drivers.",
+			Schema: Object(map[string]*Schema{
+				"ride_id":              String().WithFormat("uuid"),
+				"ride_number":          String(),
+				"pickup_location":      address(),
+				"destination_location": address(),
+				"ride_type":            String().OneOf("ECONOMY", "PREMIUM", "XL"),
+				"estimated_fare":       Number().Min(0),
+				"max_distance_km":      Number().Min(0),
+				"timeout_seconds":      Integer().Min(0),
+				"correlation_id":       String(),
+			}, "ride_id", "pickup_location", "destination_location", "ride_type"),
+		},
+		MessageType{
+			Type:        MsgRideDetails,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver},
+			Description: "Passenger pickup details after the driver accepted the ride.",
+			Replay:      ReplayCritical,
+			Schema: Object(map[string]*Schema{
+				"ride_id":           String().WithFormat("uuid"),
+				"passenger_name":    String(),
+				"passenger_contact": String().Describe("proxy id for POST /contact/{proxy_id}/call or /sms; the real number is never sent"),
+				"pickup_location": Object(map[string]*Schema{
+					"latitude":  Number(),
+					"longitude": Number(),
+					"address":   String(),
+					"notes":     String(),
+				}, "latitude", "longitude"),
+			}, "ride_id", "pickup_location"),
+		},
+		MessageType{
+			Type:        MsgRideMatched,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RolePassenger},
+			Description: "A driver accepted the passenger's ride.",
+			Replay:      ReplayCritical,
+			Schema: Object(map[string]*Schema{
+				"ride_id":                   String().WithFormat("uuid"),
+				"offer_id":                  String(),
+				"driver_id":                 String().WithFormat("uuid"),
+				"accepted":                  Boolean(),
+				"estimated_arrival_minutes": Integer().Min(0),
+				"driver_location":           latLng(),
+				"driver_info": Object(map[string]*Schema{
+					"rating": Number().Between(0, 5),
+					"vehicle": Object(map[string]*Schema{
+						"year":  Integer(),
+						"uuid":  String().Describe("vehicle model"),
+						"color": String(),
+						"brand": String(),
+					}),
+				}),
+				"estimated_arrival": String().WithFormat("date-time"),
+				"responded_at":      String().WithFormat("date-time"),
+			}, "ride_id", "driver_id"),
+		},
+		MessageType{
+			Type:        MsgDriverLocationUpdate,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RolePassenger},
+			Description: "Current position of the passenger's driver.",
+			Replay:      ReplayLatest,
+			Schema: Object(map[string]*Schema{
+				"driver_id":       String().WithFormat("uuid"),
+				"ride_id":         String().WithFormat("uuid"),
+				"location":        latLng(),
+				"speed_kmh":       Number().Min(0),
+				"heading_degrees": Number().Between(0, 360),
+				"timestamp":       String().WithFormat("date-time"),
+			}, "ride_id", "location"),
+		},
+		MessageType{
+			Type:        MsgRideStatusUpdate,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RolePassenger},
+			Description: "Ride status changed: driver arrived, ride started or completed.",
+			Replay:      ReplayCritical,
+			Schema: Object(map[string]*Schema{
+				"ride_id":   String().WithFormat("uuid"),
+				"status":    String().OneOf("MATCHED", "EN_ROUTE", "ARRIVED", "IN_PROGRESS", "COMPLETED", "CANCELLED"),
+				"driver_id": String().WithFormat("uuid"),
+				"timestamp": String().WithFormat("date-time"),
+				"message":   String(),
+			}, "ride_id", "status", "timestamp"),
+		},
+		MessageType{
+			Type:        MsgChatSend,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Send a chat message in an active ride. Either text or a quick-reply template code is required; client_id makes resends idempotent.",
+			Schema: Object(map[string]*Schema{
+				"ride_id":   String().WithFormat("uuid"),
+				"client_id": String().Length(1, 64),
+				"text":      String().Length(0, 1000),
+				"template":  String().Describe("quick-reply code from GET /chat/templates"),
+			}, "ride_id"),
+		},
+		MessageType{
+			Type:        MsgChatMark,
+			Version:     1,
+			Direction:   Inbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Mark messages from the other participant as delivered or read.",
+			Schema: Object(map[string]*Schema{
+				"ride_id":     String().WithFormat("uuid"),
+				"message_ids": ArrayOf(String().WithFormat("uuid")),
+				"status":      String().OneOf("delivered", "read"),
+			}, "ride_id", "message_ids", "status"),
+		},
+		MessageType{
+			Type:        MsgChatMessage,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Chat message in the ride, sent to both participants including the sender's other devices.",
+			Replay:      ReplayCritical,
+			Schema: Object(map[string]*Schema{
+				"message_id":   String().WithFormat("uuid"),
+				"ride_id":      String().WithFormat("uuid"),
+				"sender_id":    String().WithFormat("uuid"),
+				"sender_role":  String().OneOf("PASSENGER", "DRIVER"),
+				"client_id":    String(),
+				"text":         String(),
+				"template":     String(),
+				"sent_at":      String().WithFormat("date-time"),
+				"delivered_at": String().WithFormat("date-time"),
+				"read_at":      String().WithFormat("date-time"),
+			}, "message_id", "ride_id", "sender_id", "sender_role", "text", "sent_at"),
+		},
+		MessageType{
+			Type:        MsgChatReceipt,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "Messages were delivered to or read by the other participant.",
+			Replay:      ReplayCritical,
+			Schema: Object(map[string]*Schema{
+				"ride_id":     String().WithFormat("uuid"),
+				"message_ids": ArrayOf(String().WithFormat("uuid")),
+				"status":      String().OneOf("delivered", "read"),
+				"at":          String().WithFormat("date-time"),
+			}, "ride_id", "message_ids", "status", "at"),
+		},
+		MessageType{
+			Type:        MsgChatClosed,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleDriver, RolePassenger},
+			Description: "The ride ended and its chat no longer accepts messages.",
+			Replay:      ReplayCritical,
+			Schema: Object(map[string]*Schema{
+				"ride_id": String().WithFormat("uuid"),
+				"status":  String().OneOf("COMPLETED", "CANCELLED"),
+				"reason":  String(),
+			}, "ride_id", "status"),
+		},
+		MessageType{
+			Type:        MsgAdminRideStatus,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleAdmin},
+			Description: "A ride changed status (MATCHED, IN_PROGRESS, COMPLETED, CANCELLED).",
+			Schema: Object(map[string]*Schema{
+				"ride_id":   String().WithFormat("uuid"),
+				"status":    String(),
+				"driver_id": String(),
+				"timestamp": String().WithFormat("date-time"),
+				"message":   String(),
+			}, "ride_id", "status"),
+		},
+		MessageType{
+			Type:        MsgAdminDriverStatus,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleAdmin},
+			Description: "A driver went online, offline, became busy or available again.",
+			Schema: Object(map[string]*Schema{
+				"driver_id": String().WithFormat("uuid"),
+				"status":    String().OneOf("OFFLINE", "AVAILABLE", "BUSY", "EN_ROUTE"),
+				"ride_id":   String(),
+				"timestamp": String().WithFormat("date-time"),
+			}, "driver_id", "status", "timestamp"),
+		},
+		MessageType{
+			Type:        MsgAdminDriverLocation,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleAdmin},
+			Description: "Driver position for the live map, throttled per driver.",
+			Schema: Object(map[string]*Schema{
+				"driver_id":       String(),
+				"ride_id":         String(),
+				"location":        latLng(),
+				"speed_kmh":       Number().Min(0),
+				"heading_degrees": Number().Between(0, 360),
+				"timestamp":       String().WithFormat("date-time"),
+			}, "driver_id", "location"),
+		},
+		MessageType{
+			Type:        MsgAdminMetrics,
+			Version:     1,
+			Direction:   Outbound,
+			Roles:       []string{RoleAdmin},
+			Description: "Periodic snapshot of the system overview and consumer statistics.",
+			Replay:      ReplayLatest,
+			Schema: Object(map[string]*Schema{
+				"timestamp": String().WithFormat("date-time"),
+				"overview":  Object(map[string]*Schema{}),
+				"queues":    ArrayOf(Object(map[string]*Schema{})),
+			}, "timestamp"),
+		},
+	)
+	return r
+}
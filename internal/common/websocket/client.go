@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = 30 * time.Second
+)
+
+// Conn — часть *websocket.Conn, с которой работают pump-циклы.
+type Conn interface {
+	ReadMessage() (int, []byte, error)
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+type Client struct {
+	ID     string
+	UserID string
+	Role   string
+	Conn   Conn
+
+	ConnectedAt time.Time
+	RemoteAddr  string
+	UserAgent   string
+	// NoAck — транспорт не умеет подтверждать seq (SSE), поэтому соединение
+	// не удерживает буфер повтора; хранение ограничено Retention.
+	NoAck bool
+
+	hub     *Hub
+	send    chan []byte
+	topics  map[string]struct{} // защищено hub.mu
+	dropped atomic.Int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	reason    string
+}
+
+func (c *Client) identity() string {
+	return UserTopic(c.Role, c.UserID)
+}
+
+// Session описывает соединение для реестра сессий; NodeID заполняет Cluster.
+func (c *Client) Session() SessionInfo {
+	return SessionInfo{
+		ID:          c.ID,
+		UserID:      c.UserID,
+		Role:        c.Role,
+		RemoteAddr:  c.RemoteAddr,
+		UserAgent:   c.UserAgent,
+		ConnectedAt: c.ConnectedAt,
+	}
+}
+
+// Done закрывается, когда клиент отключён хабом или read-циклом.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) close(reason string) {
+	c.closeOnce.Do(func() {
+		c.reason = reason
+		close(c.done)
+	})
+}
+
+// Send ставит сообщение только этому клиенту с той же политикой переполнения,
+// что и рассылка по топику.
+func (c *Client) Send(data []byte) bool {
+	return c.hub.enqueue(c, data)
+}
+
+// ReadPump читает сообщения и передаёт их зарегистрированным обработчикам.
+func (c *Client) ReadPump(ctx context.Context) {
+	defer c.close("read loop finished")
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.close("server shutting down")
+		case <-c.done:
+		}
+	}()
+
+	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	if wsConn, ok := c.Conn.(*websocket.Conn); ok {
+		wsConn.SetPongHandler(func(string) error {
+			return wsConn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+
+	for {
+		_, msg, err := c.Conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.Warn("ws_read", "Unexpected close", "", c.ID, err.Error())
+			}
+			return
+		}
+
+		if perr := c.hub.dispatch(ctx, c, msg); perr != nil {
+			logger.Warn("ws_dispatch", "Failed to handle client message", "", c.ID, perr.Error())
+			c.replyError(perr)
+		}
+	}
+}
+
+// WritePump — единственный писатель в соединение: сообщения из очереди и ping.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.Conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, c.reason), time.Now().Add(writeWait))
+			return
+
+		case msg := <-c.send:
+			c.dropped.Store(0)
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				logger.Warn("ws_write", "Failed to write message", "", c.ID, err.Error())
+				c.close("write failed")
+				return
+			}
+
+		case <-ticker.C:
+			if err := c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				logger.Warn("ws_ping", "Ping failed", "", c.ID, err.Error())
+				c.close("ping failed")
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) replyError(perr *ProtocolError) {
+	data, err := c.hub.encode(MsgError, perr)
+	if err != nil {
+		logger.Error("ws_reply", "Failed to encode error reply", "", c.ID, err.Error())
+		return
+	}
+	c.Send(data)
+}
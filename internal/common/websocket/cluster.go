@@ -0,0 +1,260 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/rmq"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	routingBroadcast = "broadcast"
+	nodeQueueExpiry  = time.Minute
+)
+
+// Cluster доставляет сообщения хаба клиентам, подключённым к другим репликам.
+type Cluster struct {
+	hub       *Hub
+	nodeID    string
+	bus       rmq.Bus
+	presence  Presence
+	sessions  SessionStore
+	opts      rmq.ConsumerOptions
+	heartbeat time.Duration
+
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+type clusterMessage struct {
+	Origin string `json:"origin"`
+	Topic  string `json:"topic,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+	// Disconnect — ID сессии, которую нужно закрыть на узле-получателе.
+	Disconnect string `json:"disconnect,omitempty"`
+}
+
+func NewCluster(hub *Hub, nodeID string, bus rmq.Bus, presence Presence, sessions SessionStore, opts rmq.ConsumerOptions, heartbeat time.Duration) *Cluster {
+	// Доставка между узлами не идемпотентна по смыслу: повтор безвреден,
+	// а запись каждого сообщения в processed_messages — лишняя нагрузка.
+	opts.Dedupe = nil
+	return &Cluster{
+		hub:       hub,
+		nodeID:    nodeID,
+		bus:       bus,
+		presence:  presence,
+		sessions:  sessions,
+		opts:      opts,
+		heartbeat: heartbeat,
+		ctx:       context.Background(),
+	}
+}
+
+func (c *Cluster) NodeID() string {
+	return c.nodeID
+}
+
+func (c *Cluster) queue() string {
+	return rmq.ExchangeWSDelivery + "." + c.nodeID
+}
+
+// Start объявляет очередь узла, начинает её читать и подключается к хабу.
+func (c *Cluster) Start(ctx context.Context) error {
+	c.ctx = ctx
+
+	if err := c.presence.Reset(ctx, c.nodeID); err != nil {
+		return err
+	}
+	if err := c.sessions.Reset(ctx, c.nodeID); err != nil {
+		return err
+	}
+
+	queue := c.queue()
+	args := amqp.Table{"x-expires": int64(nodeQueueExpiry / time.Millisecond)}
+	if _, err := c.bus.QueueDeclare(queue, false, true, false, false, args); err != nil {
+		return fmt.Errorf("failed to declare node queue %s: %w", queue, err)
+	}
+	for _, key := range []string{"node." + c.nodeID, routingBroadcast} {
+		if err := c.bus.QueueBind(queue, key, rmq.ExchangeWSDelivery, false, nil); err != nil {
+			return fmt.Errorf("failed to bind node queue %s: %w", queue, err)
+		}
+	}
+
+	spec := rmq.ConsumerSpec{Queue: queue, Type: rmq.TypeWSDelivery, PartitionBy: "topic"}
+	if err := rmq.RunConsumer(ctx, c.bus, c.opts, spec, c.receive); err != nil {
+		return err
+	}
+
+	c.hub.OnPresence(c.syncPresence)
+	c.hub.OnSession(c.syncSession)
+	c.hub.SetRelay(c)
+	go c.runHeartbeat(ctx)
+
+	logger.Info("ws_cluster_start", "WebSocket cluster delivery started on node "+c.nodeID, "", "")
+	return nil
+}
+
+func (c *Cluster) publishing(msg clusterMessage) (rmq.Publishing, error) {
+	env, err := rmq.NewEnvelope(rmq.TypeWSDelivery, 1, "", msg)
+	if err != nil {
+		return rmq.Publishing{}, fmt.Errorf("failed to build envelope: %w", err)
+	}
+	body, err := json.Marshal(env)
+	if err != nil {
+		return rmq.Publishing{}, fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+	return rmq.Publishing{MessageID: env.MessageID, Type: env.Type, Body: body}, nil
+}
+
+// Forward реализует Relay.
+func (c *Cluster) Forward(topic string, data []byte) {
+	msg, err := c.publishing(clusterMessage{Origin: c.nodeID, Topic: topic, Data: data})
+	if err != nil {
+		logger.Warn("ws_cluster_forward", "Failed to build cluster message", "", topic, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	if !isUserTopic(topic) {
+		if err := c.bus.Publish(ctx, rmq.ExchangeWSDelivery, routingBroadcast, msg); err != nil {
+			logger.Warn("ws_cluster_forward", "Failed to broadcast to nodes", "", topic, err.Error())
+		}
+		return
+	}
+
+	nodes, err := c.presence.Nodes(ctx, topic)
+	if err != nil {
+		logger.Warn("ws_cluster_forward", "Failed to look up presence", "", topic, err.Error())
+		return
+	}
+	for _, node := range nodes {
+		if node == c.nodeID {
+			continue
+		}
+		if err := c.bus.Publish(ctx, rmq.ExchangeWSDelivery, "node."+node, msg); err != nil {
+			logger.Warn("ws_cluster_forward", "Failed to forward to node "+node, "", topic, err.Error())
+		}
+	}
+}
+
+func (c *Cluster) receive(env rmq.Envelope) error {
+	var msg clusterMessage
+	if err := env.Decode(&msg); err != nil {
+		return err
+	}
+	if msg.Disconnect != "" {
+		c.hub.Disconnect(msg.Disconnect, "disconnected by admin")
+		return nil
+	}
+	if msg.Origin == c.nodeID {
+		return nil
+	}
+	c.hub.PublishLocal(msg.Topic, msg.Data)
+	return nil
+}
+
+func (c *Cluster) syncSession(s SessionInfo, active bool) {
+	s.NodeID = c.nodeID
+	var err error
+	if active {
+		err = c.sessions.Add(c.ctx, s)
+	} else {
+		err = c.sessions.Remove(c.ctx, s.ID)
+	}
+	if err != nil {
+		logger.Warn("ws_cluster_session", "Failed to update session registry", "", s.ID, err.Error())
+	}
+}
+
+// Sessions возвращает соединения пользователя на всех узлах.
+func (c *Cluster) Sessions(ctx context.Context, userID string) ([]SessionInfo, error) {
+	return c.sessions.List(ctx, userID)
+}
+
+// Disconnect закрывает сессию на том узле, где она открыта.
+func (c *Cluster) Disconnect(ctx context.Context, sessionID string) error {
+	s, err := c.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if s.NodeID == c.nodeID {
+		if !c.hub.Disconnect(sessionID, "disconnected by admin") {
+			return ErrSessionNotFound
+		}
+		return nil
+	}
+
+	msg, err := c.publishing(clusterMessage{Origin: c.nodeID, Disconnect: sessionID})
+	if err != nil {
+		return err
+	}
+	if err := c.bus.Publish(ctx, rmq.ExchangeWSDelivery, "node."+s.NodeID, msg); err != nil {
+		return fmt.Errorf("failed to send disconnect to node %s: %w", s.NodeID, err)
+	}
+	return nil
+}
+
+// syncPresence сверяет реестр с фактическим состоянием хаба: события
+// подключения и отключения могут прийти не по порядку при быстром переподключении.
+func (c *Cluster) syncPresence(topic string, _ bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, subscribers := c.hub.Count(topic)
+	var err error
+	if subscribers > 0 {
+		err = c.presence.Join(c.ctx, topic, c.nodeID)
+	} else {
+		err = c.presence.Leave(c.ctx, topic, c.nodeID)
+	}
+	if err != nil {
+		logger.Warn("ws_cluster_presence", "Failed to update presence", "", topic, err.Error())
+	}
+}
+
+func (c *Cluster) runHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := c.presence.Reset(context.Background(), c.nodeID); err != nil {
+				logger.Warn("ws_cluster_stop", "Failed to clear presence", "", "", err.Error())
+			}
+			if err := c.sessions.Reset(context.Background(), c.nodeID); err != nil {
+				logger.Warn("ws_cluster_stop", "Failed to clear sessions", "", "", err.Error())
+			}
+			return
+		case <-ticker.C:
+			if err := c.presence.Heartbeat(ctx, c.nodeID); err != nil {
+				logger.Warn("ws_cluster_heartbeat", "Failed to refresh presence", "", "", err.Error())
+			}
+			if err := c.sessions.Heartbeat(ctx, c.nodeID); err != nil {
+				logger.Warn("ws_cluster_heartbeat", "Failed to refresh sessions", "", "", err.Error())
+			}
+		}
+	}
+}
+
+// isUserTopic отличает топики конкретного пользователя (driver:{id},
+// passenger:{id}, admin:{id}) от общих топиков.
+func isUserTopic(topic string) bool {
+	if topic == TopicAdminOps {
+		return false
+	}
+	role, id, ok := strings.Cut(topic, ":")
+	if !ok || id == "" {
+		return false
+	}
+	return role == RoleDriver || role == RolePassenger || role == RoleAdmin
+}
@@ -1,163 +1,610 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"strings"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"ride-hail-system/internal/common/logger"
-	"ride-hail-system/internal/common/rmq"
-	DriverModel "ride-hail-system/internal/driver/model"
+	"ride-hail-system/pkg/uuid"
+)
+
+const (
+	RoleDriver    = "driver"
+	RolePassenger = "passenger"
+	RoleAdmin     = "admin"
+
+	// TopicDrivers — все подключённые водители (рассылка предложений поездок).
+	TopicDrivers  = "role:" + RoleDriver
+	TopicAdminOps = "admin:ops"
+)
+
+func RideTopic(rideID string) string         { return "ride:" + rideID }
+func DriverTopic(driverID string) string     { return "driver:" + driverID }
+func PassengerTopic(userID string) string    { return "passenger:" + userID }
+func UserTopic(role, userID string) string   { return role + ":" + userID }
+func RoleTopic(role string) string           { return "role:" + role }
+func handlerKey(role, msgType string) string { return role + "|" + msgType }
 
-	"github.com/gorilla/websocket"
+// OverflowPolicy определяет, что делать, когда очередь отправки клиента полна.
+type OverflowPolicy int
+
+const (
+	// DropOldest вытесняет самое старое сообщение: клиенту важнее свежие данные.
+	DropOldest OverflowPolicy = iota
+	// DropNewest отбрасывает новое сообщение.
+	DropNewest
+	// CloseSlow сразу отключает клиента, не успевающего читать.
+	CloseSlow
 )
 
-type Client struct {
-	ID   string
-	Conn *websocket.Conn
-	Send chan []byte
+type Config struct {
+	QueueSize int
+	Overflow  OverflowPolicy
+	// MaxDropped — сколько сообщений подряд можно потерять, прежде чем клиент будет отключён.
+	MaxDropped int64
+	// Retention — срок хранения сообщений для повтора; 0 — без хранения и seq.
+	Retention     time.Duration
+	RetentionSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		QueueSize:  256,
+		Overflow:   DropOldest,
+		MaxDropped: 128,
+
+		Retention:     2 * time.Minute,
+		RetentionSize: 256,
+	}
 }
 
+// HandlerFunc обрабатывает входящее сообщение клиента, уже прошедшее проверку схемой.
+type HandlerFunc func(ctx context.Context, c *Client, msg Message) error
+
+var ErrNoHandler = errors.New("unsupported message type")
+
+// Relay пересылает опубликованное сообщение подписчикам на других узлах.
+type Relay interface {
+	Forward(topic string, data []byte)
+}
+
+// PresenceFunc вызывается, когда на узле появляется первое или исчезает
+// последнее соединение пользователя (топик вида driver:{id}).
+type PresenceFunc func(topic string, present bool)
+
+// SessionFunc вызывается при подключении и отключении каждого соединения.
+type SessionFunc func(s SessionInfo, active bool)
+
+// Hub — реестр соединений с подпиской на топики.
 type Hub struct {
-	Clients            map[string]*Client
-	Mu                 sync.RWMutex
-	Register           chan *Client
-	Unregister         chan *Client
-	Broadcast          chan []byte
-	DriverResponses    chan DriverModel.DriverResponceWS
-	PassengerResponses chan rmq.PassiNFO
-	UpdateLocation     chan rmq.LocationUpdateMessage
+	cfg      Config
+	mu       sync.RWMutex
+	clients  map[string]*Client
+	topics   map[string]map[*Client]struct{}
+	handlers map[string]HandlerFunc
+	aliases  map[string]string
+	registry *Registry
+	streams  map[string]*stream
+
+	relay      Relay
+	onPresence PresenceFunc
+	onSession  SessionFunc
 }
 
 func NewHub() *Hub {
+	return NewHubWithConfig(DefaultConfig())
+}
+
+func NewHubWithConfig(cfg Config) *Hub {
 	logger.SetServiceName("websocket-hub")
 
-	return &Hub{
-		Clients:            make(map[string]*Client),
-		Register:           make(chan *Client),
-		Unregister:         make(chan *Client),
-		Broadcast:          make(chan []byte),
-		DriverResponses:    make(chan DriverModel.DriverResponceWS, 10),
-		PassengerResponses: make(chan rmq.PassiNFO, 10),
-		UpdateLocation:     make(chan rmq.LocationUpdateMessage, 10),
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultConfig().QueueSize
 	}
+	if cfg.RetentionSize <= 0 {
+		cfg.RetentionSize = DefaultConfig().RetentionSize
+	}
+	h := &Hub{
+		cfg:      cfg,
+		clients:  make(map[string]*Client),
+		topics:   make(map[string]map[*Client]struct{}),
+		handlers: make(map[string]HandlerFunc),
+		aliases:  make(map[string]string),
+		registry: DefaultRegistry(),
+		streams:  make(map[string]*stream),
+	}
+	for _, role := range []string{RoleDriver, RolePassenger} {
+		h.handlers[handlerKey(role, MsgAck)] = h.handleAck
+		h.handlers[handlerKey(role, MsgResume)] = h.handleResume
+	}
+	return h
 }
 
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.Register:
-			h.Mu.Lock()
-			h.Clients[client.ID] = client
-			h.Mu.Unlock()
-			logger.Info("client_register", "Client connected", "", client.ID)
-
-		case client := <-h.Unregister:
-			h.Mu.Lock()
-			delete(h.Clients, client.ID)
-			h.Mu.Unlock()
-			logger.Info("client_unregister", "Client disconnected", "", client.ID)
-
-		case message := <-h.Broadcast:
-			h.Mu.RLock()
-			for _, c := range h.Clients {
-				select {
-				case c.Send <- message:
-				default:
-					logger.Warn("broadcast", "Client send buffer full", "", c.ID, "send channel full")
-				}
-			}
-			h.Mu.RUnlock()
-		}
+// Registry возвращает каталог типов сообщений хаба.
+func (h *Hub) Registry() *Registry {
+	return h.registry
+}
+
+// Handle регистрирует обработчик входящих сообщений типа msgType от клиентов роли role.
+func (h *Hub) Handle(role, msgType string, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[handlerKey(role, msgType)] = fn
+}
+
+// Alias сопоставляет устаревшее имя типа (или пустое — для кадров без type)
+// актуальному типу каталога, чтобы старые клиенты продолжали работать.
+func (h *Hub) Alias(role, legacy, msgType string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.aliases[handlerKey(role, legacy)] = msgType
+}
+
+func (h *Hub) SetRelay(r Relay) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.relay = r
+}
+
+func (h *Hub) OnPresence(fn PresenceFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onPresence = fn
+}
+
+func (h *Hub) OnSession(fn SessionFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onSession = fn
+}
+
+// NewClient создаёт клиента с очередью отправки по конфигурации хаба.
+func (h *Hub) NewClient(role, userID string, conn Conn) *Client {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		id = fmt.Sprintf("%s_%s_%d", role, userID, time.Now().UnixNano())
+	}
+	return &Client{
+		ID:          id,
+		UserID:      userID,
+		Role:        role,
+		Conn:        conn,
+		ConnectedAt: time.Now().UTC(),
+		hub:         h,
+		send:        make(chan []byte, h.cfg.QueueSize),
+		done:        make(chan struct{}),
 	}
 }
 
-func (h *Hub) SendToClient(clientID string, message []byte) {
-	h.Mu.RLock()
-	client, ok := h.Clients[clientID]
-	h.Mu.RUnlock()
+// Register добавляет клиента в реестр.
+func (h *Hub) Register(c *Client) {
+	identity := c.identity()
 
-	if ok {
-		select {
-		case client.Send <- message:
-			logger.Info("send_to_client", "Message sent to client", "", clientID)
-		default:
-			logger.Warn("send_to_client", "Client channel full, unregistering", "", clientID, "send channel full")
-			go func() {
-				h.Unregister <- client
-			}()
+	h.mu.Lock()
+	joined := len(h.topics[identity]) == 0
+	h.clients[c.ID] = c
+	h.subscribeLocked(c, identity, RoleTopic(c.Role))
+	if h.cfg.Retention > 0 && h.streams[identity] == nil {
+		h.streams[identity] = &stream{}
+	}
+	st := h.streams[identity]
+	onPresence, onSession := h.onPresence, h.onSession
+	h.mu.Unlock()
+
+	if st != nil && !c.NoAck {
+		st.mu.Lock()
+		st.join(c.ID)
+		st.mu.Unlock()
+	}
+
+	if joined && onPresence != nil {
+		onPresence(identity, true)
+	}
+	if onSession != nil {
+		onSession(c.Session(), true)
+	}
+	logger.Info("client_register", "Client connected: "+identity, "", c.ID)
+}
+
+func (h *Hub) Unregister(c *Client) {
+	identity := c.identity()
+
+	h.mu.Lock()
+	removed := h.clients[c.ID] == c
+	hadTopic := c.topics != nil
+	h.removeLocked(c)
+	left := hadTopic && len(h.topics[identity]) == 0
+	onPresence, onSession := h.onPresence, h.onSession
+	st := h.streams[identity]
+	h.mu.Unlock()
+
+	c.close("unregistered")
+	if removed && st != nil {
+		st.mu.Lock()
+		st.leave(c.ID, time.Now())
+		st.mu.Unlock()
+	}
+	if left {
+		h.release(identity, st, onPresence)
+	}
+	if removed {
+		if onSession != nil {
+			onSession(c.Session(), false)
 		}
-	} else {
-		logger.Warn("send_to_client", "Client not found in Hub", "", clientID, "not found")
+		logger.Info("client_unregister", "Client disconnected: "+identity, "", c.ID)
 	}
 }
 
-func (h *Hub) BroadcastRideOffer(msg rmq.RideRequestedMessage) {
-	data, _ := json.Marshal(msg)
+// Attach подписывает клиента на его топик без регистрации сессии — для
+// long-poll, где каждый запрос открывает короткое соединение. Присутствие
+// объявляется, только если узел ещё не держит поток пользователя.
+func (h *Hub) Attach(c *Client) {
+	identity := c.identity()
 
-	h.Mu.RLock()
-	defer h.Mu.RUnlock()
+	h.mu.Lock()
+	owned := len(h.topics[identity]) > 0 || h.streams[identity] != nil
+	h.subscribeLocked(c, identity)
+	if h.cfg.Retention > 0 && h.streams[identity] == nil {
+		h.streams[identity] = &stream{}
+	}
+	onPresence := h.onPresence
+	h.mu.Unlock()
 
-	for _, client := range h.Clients {
-		if strings.HasPrefix(client.ID, "driver_") {
-			select {
-			case client.Send <- data:
-				logger.Info("broadcast_ride_offer", "Ride offer sent to driver", msg.RideID, client.ID)
-			default:
-				logger.Warn("broadcast_ride_offer", "Driver channel full, disconnecting", msg.RideID, client.ID, "channel full")
-				go func(c *Client) { h.Unregister <- c }(client)
-			}
+	if !owned && onPresence != nil {
+		onPresence(identity, true)
+	}
+}
+
+// Detach снимает подписку, сделанную Attach.
+func (h *Hub) Detach(c *Client) {
+	identity := c.identity()
+
+	h.mu.Lock()
+	hadTopic := c.topics != nil
+	h.removeLocked(c)
+	left := hadTopic && len(h.topics[identity]) == 0
+	st := h.streams[identity]
+	onPresence := h.onPresence
+	h.mu.Unlock()
+
+	c.close("detached")
+	if left {
+		h.release(identity, st, onPresence)
+	}
+}
+
+// release вызывается, когда у пользователя не осталось подписчиков на узле.
+func (h *Hub) release(identity string, st *stream, onPresence PresenceFunc) {
+	if st == nil {
+		if onPresence != nil {
+			onPresence(identity, false)
 		}
+		return
 	}
+	// Узел остаётся владельцем потока ещё Retention, чтобы сообщения,
+	// отправленные во время короткого обрыва, дождались переподключения.
+	st.mu.Lock()
+	st.idle = time.Now()
+	st.mu.Unlock()
+	time.AfterFunc(h.cfg.Retention, func() { h.expireStream(identity, st) })
 }
 
-func (h *Hub) ListenDriverMessages(client *Client) {
-	for {
-		_, msg, err := client.Conn.ReadMessage()
-		if err != nil {
-			logger.Error("driver_ws_read", "Error reading from driver", "", client.ID, err.Error())
-			return
+// Sessions возвращает соединения пользователя на этом узле.
+func (h *Hub) Sessions(userID string) []SessionInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var sessions []SessionInfo
+	for _, c := range h.clients {
+		if c.UserID == userID {
+			sessions = append(sessions, c.Session())
 		}
+	}
+	return sessions
+}
+
+// Disconnect закрывает соединение sessionID на этом узле.
+func (h *Hub) Disconnect(sessionID, reason string) bool {
+	h.mu.RLock()
+	c, ok := h.clients[sessionID]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	c.close(reason)
+	h.Unregister(c)
+	logger.Info("client_disconnect", "Client disconnected by server: "+reason, "", c.ID)
+	return true
+}
+
+// expireStream удаляет поток пользователя, если он так и не переподключился.
+func (h *Hub) expireStream(identity string, st *stream) {
+	// Пользователь переподключался и снова ушёл: сработает более поздний таймер.
+	st.mu.Lock()
+	idle := time.Since(st.idle)
+	st.mu.Unlock()
+	if idle < h.cfg.Retention {
+		return
+	}
 
-		var loc rmq.LocationUpdateMessage
-		if err := json.Unmarshal(msg, &loc); err == nil && loc.DriverID != "" && loc.RideID != "" {
-			if strings.HasPrefix(loc.DriverID, "driver_") {
-				loc.DriverID = strings.TrimPrefix(loc.DriverID, "driver_")
+	h.mu.Lock()
+	expired := h.streams[identity] == st && len(h.topics[identity]) == 0
+	if expired {
+		delete(h.streams, identity)
+	}
+	onPresence := h.onPresence
+	h.mu.Unlock()
+
+	if expired && onPresence != nil {
+		onPresence(identity, false)
+	}
+}
+
+func (h *Hub) removeLocked(c *Client) {
+	if h.clients[c.ID] == c {
+		delete(h.clients, c.ID)
+	}
+	for topic := range c.topics {
+		if subs, ok := h.topics[topic]; ok {
+			delete(subs, c)
+			if len(subs) == 0 {
+				delete(h.topics, topic)
 			}
-			h.UpdateLocation <- loc
-			logger.Info("driver_location", "Received location update", loc.RideID, loc.DriverID)
-			continue
 		}
+	}
+	c.topics = nil
+}
+
+func (h *Hub) Subscribe(c *Client, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[c.ID] != c {
+		return
+	}
+	h.subscribeLocked(c, topics...)
+}
 
-		var resp DriverModel.DriverResponceWS
-		if err := json.Unmarshal(msg, &resp); err == nil && resp.Type == "ride_response" {
-			resp.DriverID = client.ID
-			if strings.HasPrefix(resp.DriverID, "driver_") {
-				resp.DriverID = strings.TrimPrefix(resp.DriverID, "driver_")
+func (h *Hub) subscribeLocked(c *Client, topics ...string) {
+	if c.topics == nil {
+		c.topics = make(map[string]struct{})
+	}
+	for _, topic := range topics {
+		subs, ok := h.topics[topic]
+		if !ok {
+			subs = make(map[*Client]struct{})
+			h.topics[topic] = subs
+		}
+		subs[c] = struct{}{}
+		c.topics[topic] = struct{}{}
+	}
+}
+
+func (h *Hub) Unsubscribe(c *Client, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		if subs, ok := h.topics[topic]; ok {
+			delete(subs, c)
+			if len(subs) == 0 {
+				delete(h.topics, topic)
 			}
-			h.DriverResponses <- resp
-			logger.Info("driver_response", "Received driver response", "", resp.DriverID)
-			continue
 		}
+		delete(c.topics, topic)
+	}
+}
+
+// Publish доставляет сообщение локальным подписчикам топика и, если задан
+// Relay, пересылает его на другие узлы. Возвращает число локальных получателей.
+func (h *Hub) Publish(topic string, data []byte) int {
+	delivered := h.PublishLocal(topic, data)
 
-		logger.Warn("driver_ws_message", "Unrecognized message from driver", "", client.ID, string(msg))
+	h.mu.RLock()
+	relay := h.relay
+	h.mu.RUnlock()
+	if relay != nil {
+		relay.Forward(topic, data)
 	}
+	return delivered
 }
 
-func (h *Hub) ListenPassengerMessages(client *Client) {
-	for {
-		_, msg, err := client.Conn.ReadMessage()
-		if err != nil {
-			logger.Error("passenger_ws_read", "Error reading from passenger", "", client.ID, err.Error())
-			return
+// PublishLocal доставляет сообщение только подписчикам этого узла.
+func (h *Hub) PublishLocal(topic string, data []byte) int {
+	h.mu.RLock()
+	subs := make([]*Client, 0, len(h.topics[topic]))
+	for c := range h.topics[topic] {
+		subs = append(subs, c)
+	}
+	st := h.streams[topic]
+	h.mu.RUnlock()
+
+	if st != nil {
+		// Нумерация и постановка в очереди под одной блокировкой, чтобы
+		// клиенты получали сообщения в порядке seq.
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		data = st.append(h.registry, h.cfg, data, time.Now())
+	}
+
+	delivered := 0
+	for _, c := range subs {
+		if h.enqueue(c, data) {
+			delivered++
 		}
+	}
+	return delivered
+}
+
+// PublishMessage упаковывает payload в конверт последней версии типа msgType и публикует в топик.
+func (h *Hub) PublishMessage(topic, msgType string, payload any) (int, error) {
+	data, err := h.encode(msgType, payload)
+	if err != nil {
+		return 0, err
+	}
+	return h.Publish(topic, data), nil
+}
+
+// PublishLocalMessage — как PublishMessage, но только подписчикам этого узла.
+func (h *Hub) PublishLocalMessage(topic, msgType string, payload any) (int, error) {
+	data, err := h.encode(msgType, payload)
+	if err != nil {
+		return 0, err
+	}
+	return h.PublishLocal(topic, data), nil
+}
+
+func (h *Hub) encode(msgType string, payload any) ([]byte, error) {
+	mt, ok := h.registry.Latest(msgType)
+	if !ok || mt.Direction != Outbound {
+		return nil, fmt.Errorf("%w: outbound %q is not registered", ErrNoHandler, msgType)
+	}
+	msg, err := NewMessage(msgType, mt.Version, payload)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ws message: %w", err)
+	}
+	return data, nil
+}
 
-		var resp rmq.PassiNFO
-		if err := json.Unmarshal(msg, &resp); err == nil {
-			h.PassengerResponses <- resp
-			logger.Info("passenger_response", "Received passenger response", "", client.ID)
-		} else {
-			logger.Warn("passenger_ws_message", "Failed to parse passenger message", "", client.ID, string(msg))
+func (h *Hub) enqueue(c *Client, data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+	}
+
+	switch h.cfg.Overflow {
+	case CloseSlow:
+		logger.Warn("ws_overflow", "Client send queue full, disconnecting", "", c.ID, "send queue full")
+		go h.Unregister(c)
+		return false
+	case DropOldest:
+		select {
+		case <-c.send:
+		default:
 		}
+		select {
+		case c.send <- data:
+		default:
+		}
+	}
+
+	dropped := c.dropped.Add(1)
+	if h.cfg.MaxDropped > 0 && dropped >= h.cfg.MaxDropped {
+		logger.Warn("ws_overflow", fmt.Sprintf("Client dropped %d messages, disconnecting", dropped), "", c.ID, "send queue full")
+		go h.Unregister(c)
+		return false
 	}
+	return h.cfg.Overflow == DropOldest
+}
+
+// dispatch разбирает конверт, проверяет его по каталогу и передаёт обработчику.
+func (h *Hub) dispatch(ctx context.Context, c *Client, raw []byte) *ProtocolError {
+	msg, perr := decodeMessage(raw)
+	if perr != nil {
+		return perr
+	}
+
+	h.mu.RLock()
+	if msgType, ok := h.aliases[handlerKey(c.Role, msg.Type)]; ok {
+		msg.Type = msgType
+	}
+	fn, ok := h.handlers[handlerKey(c.Role, msg.Type)]
+	h.mu.RUnlock()
+
+	if perr := h.registry.Validate(c.Role, msg); perr != nil {
+		return perr
+	}
+	if !ok {
+		return &ProtocolError{Code: ErrCodeUnknownType, Message: fmt.Sprintf("%s: %q", ErrNoHandler, msg.Type), RefID: msg.ID}
+	}
+	if err := fn(ctx, c, msg); err != nil {
+		return &ProtocolError{Code: ErrCodeRejected, Message: err.Error(), RefID: msg.ID}
+	}
+	return nil
+}
+
+func (h *Hub) clientStream(c *Client) (*stream, error) {
+	h.mu.RLock()
+	st := h.streams[c.identity()]
+	h.mu.RUnlock()
+	if st == nil {
+		return nil, errors.New("message replay is disabled")
+	}
+	return st, nil
+}
+
+func (h *Hub) handleAck(_ context.Context, c *Client, msg Message) error {
+	var ack struct {
+		Seq uint64 `json:"seq"`
+	}
+	if err := json.Unmarshal(msg.Payload, &ack); err != nil {
+		return err
+	}
+	st, err := h.clientStream(c)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	st.ack(c.ID, ack.Seq, time.Now(), h.cfg.Retention)
+	st.mu.Unlock()
+	return nil
+}
+
+// Resume повторно отправляет клиенту сохранённые сообщения с seq больше since.
+func (h *Hub) Resume(c *Client, since uint64) (ResumeResult, error) {
+	st, err := h.clientStream(c)
+	if err != nil {
+		return ResumeResult{}, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	replay, res := st.since(since, time.Now(), h.cfg.Retention)
+	for _, data := range replay {
+		c.Send(data)
+	}
+	logger.Info("ws_resume", fmt.Sprintf("Replayed %d messages since seq %d (gap=%v reset=%v)", res.Replayed, res.Since, res.Gap, res.Reset), "", c.ID)
+	return res, nil
+}
+
+// handleResume отвечает на resume повтором и сообщением resumed.
+func (h *Hub) handleResume(_ context.Context, c *Client, msg Message) error {
+	var req struct {
+		Since uint64 `json:"since"`
+	}
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return err
+	}
+	res, err := h.Resume(c, req.Since)
+	if err != nil {
+		return err
+	}
+	data, err := h.encode(MsgResumed, res)
+	if err != nil {
+		return err
+	}
+	c.Send(data)
+	return nil
+}
+
+// Count возвращает число подключённых клиентов и подписчиков топика.
+func (h *Hub) Count(topic string) (clients, subscribers int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients), len(h.topics[topic])
 }
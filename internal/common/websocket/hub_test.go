@@ -0,0 +1,460 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestHub(queue int, overflow OverflowPolicy, maxDropped int64) *Hub {
+	return NewHubWithConfig(Config{QueueSize: queue, Overflow: overflow, MaxDropped: maxDropped})
+}
+
+func drain(c *Client) []string {
+	var out []string
+	for {
+		select {
+		case data := <-c.send:
+			out = append(out, string(data))
+		default:
+			return out
+		}
+	}
+}
+
+func waitDone(t *testing.T, c *Client) {
+	t.Helper()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("client was not disconnected")
+	}
+}
+
+func TestRegisterUnregister(t *testing.T) {
+	h := newTestHub(8, DropOldest, 0)
+
+	var mu sync.Mutex
+	presence := map[string][]bool{}
+	sessions := 0
+	h.OnPresence(func(topic string, present bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		presence[topic] = append(presence[topic], present)
+	})
+	h.OnSession(func(_ SessionInfo, active bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if active {
+			sessions++
+		} else {
+			sessions--
+		}
+	})
+
+	phone := h.NewClient(RoleDriver, "d1", nil)
+	laptop := h.NewClient(RoleDriver, "d1", nil)
+	h.Register(phone)
+	h.Register(laptop)
+
+	if clients, subs := h.Count(DriverTopic("d1")); clients != 2 || subs != 2 {
+		t.Fatalf("Count = %d clients, %d subscribers; want 2, 2", clients, subs)
+	}
+	if _, subs := h.Count(TopicDrivers); subs != 2 {
+		t.Fatalf("role topic subscribers = %d, want 2", subs)
+	}
+	if got := len(h.Sessions("d1")); got != 2 {
+		t.Fatalf("Sessions = %d, want 2", got)
+	}
+	if n := h.Publish(DriverTopic("d1"), []byte("hello")); n != 2 {
+		t.Fatalf("Publish delivered to %d, want 2", n)
+	}
+
+	h.Unregister(phone)
+	waitDone(t, phone)
+	if clients, subs := h.Count(DriverTopic("d1")); clients != 1 || subs != 1 {
+		t.Fatalf("after first unregister Count = %d, %d; want 1, 1", clients, subs)
+	}
+
+	h.Unregister(laptop)
+	h.Unregister(laptop)
+	if clients, subs := h.Count(DriverTopic("d1")); clients != 0 || subs != 0 {
+		t.Fatalf("after unregister Count = %d, %d; want 0, 0", clients, subs)
+	}
+	if n := h.Publish(DriverTopic("d1"), []byte("bye")); n != 0 {
+		t.Fatalf("Publish after unregister delivered to %d, want 0", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := presence[DriverTopic("d1")]; len(got) != 2 || !got[0] || got[1] {
+		t.Fatalf("presence events = %v, want [true false]", got)
+	}
+	if sessions != 0 {
+		t.Fatalf("active sessions = %d, want 0", sessions)
+	}
+}
+
+func TestPublishConcurrentFanOut(t *testing.T) {
+	const (
+		clients    = 8
+		publishers = 4
+		perPub     = 50
+	)
+	h := newTestHub(publishers*perPub, DropNewest, 0)
+
+	var cs []*Client
+	for i := 0; i < clients; i++ {
+		c := h.NewClient(RolePassenger, fmt.Sprintf("p%d", i), nil)
+		h.Register(c)
+		h.Subscribe(c, RideTopic("r1"))
+		cs = append(cs, c)
+	}
+
+	var wg sync.WaitGroup
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perPub; i++ {
+				if n := h.Publish(RideTopic("r1"), []byte(fmt.Sprintf("%d-%d", p, i))); n != clients {
+					t.Errorf("Publish delivered to %d, want %d", n, clients)
+				}
+			}
+		}(p)
+	}
+	// Подписки меняются параллельно с рассылкой.
+	extra := h.NewClient(RolePassenger, "late", nil)
+	h.Register(extra)
+	h.Subscribe(extra, RideTopic("r2"))
+	h.Unsubscribe(extra, RideTopic("r2"))
+	wg.Wait()
+
+	for _, c := range cs {
+		got := drain(c)
+		if len(got) != publishers*perPub {
+			t.Fatalf("client %s received %d messages, want %d", c.UserID, len(got), publishers*perPub)
+		}
+		seen := make(map[string]bool, len(got))
+		for _, m := range got {
+			if seen[m] {
+				t.Fatalf("client %s received %q twice", c.UserID, m)
+			}
+			seen[m] = true
+		}
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name       string
+		overflow   OverflowPolicy
+		maxDropped int64
+		publish    int
+		delivered  []int
+		queue      []string
+		closed     bool
+	}{
+		{
+			name:      "drop oldest",
+			overflow:  DropOldest,
+			publish:   4,
+			delivered: []int{1, 1, 1, 1},
+			queue:     []string{"m2", "m3"},
+		},
+		{
+			name:      "drop newest",
+			overflow:  DropNewest,
+			publish:   4,
+			delivered: []int{1, 1, 0, 0},
+			queue:     []string{"m0", "m1"},
+		},
+		{
+			name:      "close slow",
+			overflow:  CloseSlow,
+			publish:   3,
+			delivered: []int{1, 1, 0},
+			closed:    true,
+		},
+		{
+			name:       "max dropped",
+			overflow:   DropNewest,
+			maxDropped: 2,
+			publish:    4,
+			delivered:  []int{1, 1, 0, 0},
+			closed:     true,
+		},
+		{
+			name:       "max dropped with drop oldest",
+			overflow:   DropOldest,
+			maxDropped: 3,
+			publish:    4,
+			delivered:  []int{1, 1, 1, 1},
+			queue:      []string{"m2", "m3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHub(2, tt.overflow, tt.maxDropped)
+			c := h.NewClient(RoleDriver, "d1", nil)
+			h.Register(c)
+
+			for i := 0; i < tt.publish; i++ {
+				if n := h.Publish(DriverTopic("d1"), []byte(fmt.Sprintf("m%d", i))); n != tt.delivered[i] {
+					t.Fatalf("publish #%d delivered to %d, want %d", i, n, tt.delivered[i])
+				}
+			}
+
+			if tt.closed {
+				waitDone(t, c)
+				deadline := time.Now().Add(time.Second)
+				for {
+					if clients, _ := h.Count(DriverTopic("d1")); clients == 0 {
+						break
+					}
+					if time.Now().After(deadline) {
+						t.Fatal("slow client is still registered")
+					}
+					time.Sleep(5 * time.Millisecond)
+				}
+				if n := h.Publish(DriverTopic("d1"), []byte("late")); n != 0 {
+					t.Fatalf("publish after disconnect delivered to %d, want 0", n)
+				}
+				return
+			}
+
+			got := drain(c)
+			if fmt.Sprint(got) != fmt.Sprint(tt.queue) {
+				t.Fatalf("queue = %v, want %v", got, tt.queue)
+			}
+			select {
+			case <-c.Done():
+				t.Fatal("client was disconnected")
+			default:
+			}
+		})
+	}
+}
+
+func TestSubscribeUnsubscribe(t *testing.T) {
+	h := newTestHub(8, DropOldest, 0)
+	driver := h.NewClient(RoleDriver, "d1", nil)
+	passenger := h.NewClient(RolePassenger, "p1", nil)
+	h.Register(driver)
+	h.Register(passenger)
+
+	ride := RideTopic("r1")
+	h.Subscribe(driver, ride)
+	h.Subscribe(passenger, ride)
+	if n := h.Publish(ride, []byte("started")); n != 2 {
+		t.Fatalf("Publish delivered to %d, want 2", n)
+	}
+
+	h.Unsubscribe(driver, ride)
+	if n := h.Publish(ride, []byte("chat")); n != 1 {
+		t.Fatalf("after unsubscribe delivered to %d, want 1", n)
+	}
+	if got := drain(driver); fmt.Sprint(got) != "[started]" {
+		t.Fatalf("driver queue = %v, want [started]", got)
+	}
+	if got := drain(passenger); fmt.Sprint(got) != "[started chat]" {
+		t.Fatalf("passenger queue = %v, want [started chat]", got)
+	}
+
+	h.Unsubscribe(passenger, ride)
+	if _, subs := h.Count(ride); subs != 0 {
+		t.Fatalf("topic still has %d subscribers", subs)
+	}
+
+	// Отключённый клиент не может подписаться заново.
+	h.Unregister(passenger)
+	h.Subscribe(passenger, ride)
+	if _, subs := h.Count(ride); subs != 0 {
+		t.Fatalf("unregistered client subscribed, %d subscribers", subs)
+	}
+	// Собственный топик и топик роли снимаются при отключении.
+	if _, subs := h.Count(PassengerTopic("p1")); subs != 0 {
+		t.Fatalf("user topic still has %d subscribers", subs)
+	}
+	if _, subs := h.Count(RoleTopic(RolePassenger)); subs != 0 {
+		t.Fatalf("role topic still has %d subscribers", subs)
+	}
+}
+
+func ack(t *testing.T, h *Hub, c *Client, seq uint64) {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]uint64{"seq": seq})
+	if err := h.handleAck(context.Background(), c, Message{Type: MsgAck, Payload: payload}); err != nil {
+		t.Fatalf("ack %d: %v", seq, err)
+	}
+}
+
+func resume(t *testing.T, h *Hub, c *Client, since uint64) ResumeResult {
+	t.Helper()
+	drain(c)
+	res, err := h.Resume(c, since)
+	if err != nil {
+		t.Fatalf("Resume(%d): %v", since, err)
+	}
+	drain(c)
+	return res
+}
+
+func TestAckTrimsOnlyWhatAllSessionsAcked(t *testing.T) {
+	h := NewHubWithConfig(Config{QueueSize: 16, Retention: time.Minute})
+	phone := h.NewClient(RolePassenger, "p1", nil)
+	laptop := h.NewClient(RolePassenger, "p1", nil)
+	h.Register(phone)
+	h.Register(laptop)
+
+	for i := 0; i < 2; i++ {
+		if _, err := h.PublishMessage(PassengerTopic("p1"), MsgChatMessage, map[string]string{"text": "hi"}); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+
+	// Подтверждение телефона не удаляет сообщения, которые ноутбук ещё не получил.
+	ack(t, h, phone, 2)
+	if res := resume(t, h, laptop, 0); res.Replayed != 2 || res.Gap {
+		t.Fatalf("laptop resume = %+v, want 2 replayed without gap", res)
+	}
+
+	ack(t, h, laptop, 1)
+	if res := resume(t, h, laptop, 1); res.Replayed != 1 || res.Gap {
+		t.Fatalf("laptop resume after ack 1 = %+v, want 1 replayed without gap", res)
+	}
+
+	// Ноутбук ненадолго отключился: его подтверждение держит буфер, пока
+	// телефон подтверждает новые сообщения.
+	h.Unregister(laptop)
+	if _, err := h.PublishMessage(PassengerTopic("p1"), MsgChatMessage, map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	ack(t, h, phone, 3)
+
+	back := h.NewClient(RolePassenger, "p1", nil)
+	h.Register(back)
+	if res := resume(t, h, back, 1); res.Replayed != 2 || res.Gap {
+		t.Fatalf("laptop resume after reconnect = %+v, want 2 replayed without gap", res)
+	}
+}
+
+func TestDepartedSessionAckExpires(t *testing.T) {
+	now := time.Now()
+	st := &stream{seq: 3, entries: []streamEntry{{seq: 1, at: now}, {seq: 2, at: now}, {seq: 3, at: now}}}
+	st.join("phone")
+	st.join("laptop")
+	st.ack("laptop", 1, now, time.Minute)
+	st.leave("laptop", now)
+
+	// Отключённое соединение больше не подтверждает, но держит буфер.
+	st.ack("laptop", 3, now, time.Minute)
+	st.ack("phone", 3, now, time.Minute)
+	if len(st.entries) != 2 || st.lost != 1 {
+		t.Fatalf("entries = %d lost = %d, want 2 kept after seq 1", len(st.entries), st.lost)
+	}
+
+	// По истечении Retention подтверждение ноутбука забывается.
+	st.trim(now.Add(2*time.Minute), time.Minute)
+	if len(st.entries) != 0 || st.lost != 3 {
+		t.Fatalf("entries = %d lost = %d, want all trimmed", len(st.entries), st.lost)
+	}
+	if _, ok := st.acked["laptop"]; ok {
+		t.Fatal("expired session ack is still kept")
+	}
+}
+
+func TestAckKeepsEntriesWhileNoSessionIsLive(t *testing.T) {
+	h := NewHubWithConfig(Config{QueueSize: 16, Retention: time.Minute})
+	phone := h.NewClient(RolePassenger, "p1", nil)
+	h.Register(phone)
+
+	if _, err := h.PublishMessage(PassengerTopic("p1"), MsgChatMessage, map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	h.Unregister(phone)
+	if _, err := h.PublishMessage(PassengerTopic("p1"), MsgChatMessage, map[string]string{"text": "are you there?"}); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+
+	again := h.NewClient(RolePassenger, "p1", nil)
+	h.Register(again)
+	if res := resume(t, h, again, 0); res.Replayed != 2 || res.Gap || res.LastSeq != 2 {
+		t.Fatalf("resume after reconnect = %+v, want 2 replayed without gap", res)
+	}
+}
+
+func TestAttachSkipsSessionHooks(t *testing.T) {
+	h := NewHubWithConfig(Config{QueueSize: 16, Retention: time.Minute})
+
+	var mu sync.Mutex
+	var presence []bool
+	sessions := 0
+	h.OnPresence(func(_ string, present bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		presence = append(presence, present)
+	})
+	h.OnSession(func(SessionInfo, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		sessions++
+	})
+
+	// Два опроса подряд: второй застаёт поток, оставшийся от первого.
+	for i := 0; i < 2; i++ {
+		poll := h.NewClient(RolePassenger, "p1", nil)
+		h.Attach(poll)
+		if n := h.Publish(PassengerTopic("p1"), []byte(`{"type":"chat_message"}`)); n != 1 {
+			t.Fatalf("poll %d: Publish delivered to %d, want 1", i, n)
+		}
+		h.Detach(poll)
+		waitDone(t, poll)
+	}
+
+	if clients, subs := h.Count(PassengerTopic("p1")); clients != 0 || subs != 0 {
+		t.Fatalf("Count = %d clients, %d subscribers; want 0, 0", clients, subs)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sessions != 0 {
+		t.Fatalf("session hook called %d times, want 0", sessions)
+	}
+	if len(presence) != 1 || !presence[0] {
+		t.Fatalf("presence events = %v, want [true]", presence)
+	}
+}
+
+func TestNoAckSessionDoesNotPinBuffer(t *testing.T) {
+	h := NewHubWithConfig(Config{QueueSize: 16, Retention: time.Minute})
+	phone := h.NewClient(RolePassenger, "p1", nil)
+	sse := h.NewClient(RolePassenger, "p1", nil)
+	sse.NoAck = true
+	h.Register(phone)
+	h.Register(sse)
+
+	for i := 0; i < 2; i++ {
+		if _, err := h.PublishMessage(PassengerTopic("p1"), MsgChatMessage, map[string]string{"text": "hi"}); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+
+	// Подтверждения SSE не приходят, но буфер очищается по подтверждению телефона.
+	ack(t, h, phone, 2)
+	if res := resume(t, h, phone, 2); res.Replayed != 0 || res.Gap {
+		t.Fatalf("resume after ack = %+v, want nothing replayed", res)
+	}
+	st, err := h.clientStream(phone)
+	if err != nil {
+		t.Fatalf("clientStream: %v", err)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.entries) != 0 {
+		t.Fatalf("buffer keeps %d entries, want 0", len(st.entries))
+	}
+}
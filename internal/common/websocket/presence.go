@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Presence знает, на каких узлах есть соединения пользователя.
+type Presence interface {
+	Join(ctx context.Context, topic, nodeID string) error
+	Leave(ctx context.Context, topic, nodeID string) error
+	Nodes(ctx context.Context, topic string) ([]string, error)
+	// Heartbeat продлевает записи узла; записи без heartbeat дольше TTL
+	// считаются принадлежащими упавшему узлу и игнорируются.
+	Heartbeat(ctx context.Context, nodeID string) error
+	// Reset удаляет записи узла, оставшиеся от предыдущего запуска.
+	Reset(ctx context.Context, nodeID string) error
+}
+
+type PostgresPresence struct {
+	db  *pgxpool.Pool
+	ttl time.Duration
+}
+
+func NewPostgresPresence(db *pgxpool.Pool, ttl time.Duration) *PostgresPresence {
+	return &PostgresPresence{db: db, ttl: ttl}
+}
+
+func (p *PostgresPresence) Join(ctx context.Context, topic, nodeID string) error {
+	_, err := p.db.Exec(ctx, `
+		INSERT INTO ws_presence (topic, node_id)
+		VALUES ($1, $2)
+		ON CONFLICT (topic, node_id) DO UPDATE SET seen_at = now()
+	`, topic, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to insert presence: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresPresence) Leave(ctx context.Context, topic, nodeID string) error {
+	_, err := p.db.Exec(ctx, `DELETE FROM ws_presence WHERE topic = $1 AND node_id = $2`, topic, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete presence: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresPresence) Nodes(ctx context.Context, topic string) ([]string, error) {
+	rows, err := p.db.Query(ctx, `
+		SELECT node_id
+		FROM ws_presence
+		WHERE topic = $1 AND seen_at > now() - make_interval(secs => $2)
+	`, topic, p.ttl.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query presence: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []string
+	for rows.Next() {
+		var node string
+		if err := rows.Scan(&node); err != nil {
+			return nil, fmt.Errorf("failed to scan presence: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+func (p *PostgresPresence) Heartbeat(ctx context.Context, nodeID string) error {
+	_, err := p.db.Exec(ctx, `UPDATE ws_presence SET seen_at = now() WHERE node_id = $1`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh presence: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresPresence) Reset(ctx context.Context, nodeID string) error {
+	_, err := p.db.Exec(ctx, `DELETE FROM ws_presence WHERE node_id = $1`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to reset presence: %w", err)
+	}
+	return nil
+}
+
+// MemoryPresence — реестр в памяти для тестов и запуска в одном процессе.
+type MemoryPresence struct {
+	mu    sync.Mutex
+	nodes map[string]map[string]struct{}
+}
+
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{nodes: make(map[string]map[string]struct{})}
+}
+
+func (p *MemoryPresence) Join(_ context.Context, topic, nodeID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.nodes[topic] == nil {
+		p.nodes[topic] = make(map[string]struct{})
+	}
+	p.nodes[topic][nodeID] = struct{}{}
+	return nil
+}
+
+func (p *MemoryPresence) Leave(_ context.Context, topic, nodeID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.nodes[topic], nodeID)
+	if len(p.nodes[topic]) == 0 {
+		delete(p.nodes, topic)
+	}
+	return nil
+}
+
+func (p *MemoryPresence) Nodes(_ context.Context, topic string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nodes := make([]string, 0, len(p.nodes[topic]))
+	for node := range p.nodes[topic] {
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (p *MemoryPresence) Heartbeat(context.Context, string) error { return nil }
+
+func (p *MemoryPresence) Reset(_ context.Context, nodeID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for topic, nodes := range p.nodes {
+		delete(nodes, nodeID)
+		if len(nodes) == 0 {
+			delete(p.nodes, topic)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"ride-hail-system/pkg/uuid"
+)
+
+// Message — конверт протокола WebSocket в обе стороны.
+type Message struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	ID      string          `json:"id"`
+	Seq     uint64          `json:"seq,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type Direction string
+
+const (
+	Inbound  Direction = "client_to_server"
+	Outbound Direction = "server_to_client"
+)
+
+// MessageType описывает одну версию одного типа сообщения.
+type MessageType struct {
+	Type        string    `json:"type"`
+	Version     int       `json:"version"`
+	Direction   Direction `json:"direction"`
+	Roles       []string  `json:"roles"`
+	Description string    `json:"description,omitempty"`
+	// Replay — для исходящих: сохраняется ли сообщение для повторной отправки.
+	Replay ReplayPolicy `json:"replay,omitempty"`
+	Schema *Schema      `json:"schema"`
+}
+
+const (
+	ErrCodeInvalidJSON        = "invalid_json"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeInvalidPayload     = "invalid_payload"
+	ErrCodeRejected           = "rejected"
+)
+
+// ProtocolError отправляется клиенту сообщением типа error.
+type ProtocolError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	RefID   string            `json:"ref_id,omitempty"`
+	Details []ValidationError `json:"details,omitempty"`
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Registry — каталог типов сообщений с их схемами.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]map[int]MessageType
+}
+
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]map[int]MessageType)}
+}
+
+func (r *Registry) Register(mt MessageType) error {
+	if mt.Type == "" || mt.Version < 1 || mt.Schema == nil {
+		return fmt.Errorf("message type %q v%d: type, version and schema are required", mt.Type, mt.Version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, ok := r.types[mt.Type]
+	if !ok {
+		versions = make(map[int]MessageType)
+		r.types[mt.Type] = versions
+	}
+	if _, dup := versions[mt.Version]; dup {
+		return fmt.Errorf("message type %q v%d already registered", mt.Type, mt.Version)
+	}
+	versions[mt.Version] = mt
+	return nil
+}
+
+func (r *Registry) MustRegister(types ...MessageType) {
+	for _, mt := range types {
+		if err := r.Register(mt); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *Registry) Lookup(msgType string, version int) (MessageType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mt, ok := r.types[msgType][version]
+	return mt, ok
+}
+
+// Latest возвращает последнюю версию типа; используется при отправке.
+func (r *Registry) Latest(msgType string) (MessageType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest MessageType
+	for v, mt := range r.types[msgType] {
+		if v > latest.Version {
+			latest = mt
+		}
+	}
+	return latest, latest.Version > 0
+}
+
+// Validate проверяет входящее сообщение клиента роли role.
+func (r *Registry) Validate(role string, msg Message) *ProtocolError {
+	r.mu.RLock()
+	var supported []int
+	for v, t := range r.types[msg.Type] {
+		if t.Direction == Inbound && hasRole(t.Roles, role) {
+			supported = append(supported, v)
+		}
+	}
+	mt, ok := r.types[msg.Type][msg.Version]
+	r.mu.RUnlock()
+
+	if len(supported) == 0 {
+		return &ProtocolError{Code: ErrCodeUnknownType, Message: fmt.Sprintf("unknown message type %q", msg.Type), RefID: msg.ID}
+	}
+	if !ok || mt.Direction != Inbound || !hasRole(mt.Roles, role) {
+		sort.Ints(supported)
+		return &ProtocolError{
+			Code:    ErrCodeUnsupportedVersion,
+			Message: fmt.Sprintf("version %d of %q is not supported, supported: %v", msg.Version, msg.Type, supported),
+			RefID:   msg.ID,
+		}
+	}
+
+	if err := mt.Schema.Validate(msg.Payload); err != nil {
+		perr := &ProtocolError{Code: ErrCodeInvalidPayload, Message: "payload does not match schema", RefID: msg.ID}
+		if verrs, ok := err.(ValidationErrors); ok {
+			perr.Details = verrs
+		}
+		return perr
+	}
+	return nil
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Types возвращает все зарегистрированные типы, отсортированные по имени и версии.
+func (r *Registry) Types() []MessageType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var all []MessageType
+	for _, versions := range r.types {
+		for _, mt := range versions {
+			all = append(all, mt)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Type != all[j].Type {
+			return all[i].Type < all[j].Type
+		}
+		return all[i].Version < all[j].Version
+	})
+	return all
+}
+
+// ServeHTTP публикует каталог схем для генерации клиентов.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Envelope *Schema       `json:"envelope"`
+		Messages []MessageType `json:"messages"`
+	}{
+		Envelope: envelopeSchema,
+		Messages: r.Types(),
+	})
+}
+
+var envelopeSchema = Object(map[string]*Schema{
+	"type":    String().Describe("message type from the catalog"),
+	"version": Integer().Min(1),
+	"id":      String().Describe("sender-assigned message id, echoed as ref_id in errors"),
+	"seq":     Integer().Min(1).Describe("per-user sequence number of server messages, used in ack and resume"),
+	"payload": Object(nil).Describe("message body, see the schema of the type"),
+}, "type", "version", "payload")
+
+// NewMessage собирает исходящее сообщение с новым ID.
+func NewMessage(msgType string, version int, payload any) (Message, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to generate message id: %w", err)
+	}
+	return Message{Type: msgType, Version: version, ID: id, Payload: body}, nil
+}
+
+// decodeMessage разбирает входящий кадр.
+func decodeMessage(raw []byte) (Message, *ProtocolError) {
+	var probe struct {
+		Type    string          `json:"type"`
+		Version int             `json:"version"`
+		ID      string          `json:"id"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return Message{}, &ProtocolError{Code: ErrCodeInvalidJSON, Message: err.Error()}
+	}
+
+	if probe.Payload == nil {
+		return Message{Type: probe.Type, Version: 1, ID: probe.ID, Payload: raw}, nil
+	}
+	return Message{Type: probe.Type, Version: probe.Version, ID: probe.ID, Payload: probe.Payload}, nil
+}
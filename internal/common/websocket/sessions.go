@@ -0,0 +1,189 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionInfo — одно соединение пользователя.
+type SessionInfo struct {
+	ID          string    `json:"session_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	NodeID      string    `json:"node_id"`
+	RemoteAddr  string    `json:"remote_addr,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// SessionStore — общий для узлов реестр сессий.
+type SessionStore interface {
+	Add(ctx context.Context, s SessionInfo) error
+	Remove(ctx context.Context, sessionID string) error
+	Get(ctx context.Context, sessionID string) (SessionInfo, error)
+	List(ctx context.Context, userID string) ([]SessionInfo, error)
+	Heartbeat(ctx context.Context, nodeID string) error
+	Reset(ctx context.Context, nodeID string) error
+}
+
+type PostgresSessions struct {
+	db  *pgxpool.Pool
+	ttl time.Duration
+}
+
+func NewPostgresSessions(db *pgxpool.Pool, ttl time.Duration) *PostgresSessions {
+	return &PostgresSessions{db: db, ttl: ttl}
+}
+
+func (p *PostgresSessions) Add(ctx context.Context, s SessionInfo) error {
+	_, err := p.db.Exec(ctx, `
+		INSERT INTO ws_sessions (id, user_id, role, node_id, remote_addr, user_agent, connected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET node_id = EXCLUDED.node_id, seen_at = now()
+	`, s.ID, s.UserID, s.Role, s.NodeID, s.RemoteAddr, s.UserAgent, s.ConnectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresSessions) Remove(ctx context.Context, sessionID string) error {
+	_, err := p.db.Exec(ctx, `DELETE FROM ws_sessions WHERE id = $1`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+const sessionColumns = `id, user_id, role, node_id, coalesce(remote_addr, ''), coalesce(user_agent, ''), connected_at`
+
+func scanSession(row pgx.Row) (SessionInfo, error) {
+	var s SessionInfo
+	err := row.Scan(&s.ID, &s.UserID, &s.Role, &s.NodeID, &s.RemoteAddr, &s.UserAgent, &s.ConnectedAt)
+	return s, err
+}
+
+func (p *PostgresSessions) Get(ctx context.Context, sessionID string) (SessionInfo, error) {
+	row := p.db.QueryRow(ctx, `
+		SELECT `+sessionColumns+`
+		FROM ws_sessions
+		WHERE id = $1 AND seen_at > now() - make_interval(secs => $2)
+	`, sessionID, p.ttl.Seconds())
+
+	s, err := scanSession(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SessionInfo{}, ErrSessionNotFound
+		}
+		return SessionInfo{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	return s, nil
+}
+
+func (p *PostgresSessions) List(ctx context.Context, userID string) ([]SessionInfo, error) {
+	rows, err := p.db.Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM ws_sessions
+		WHERE user_id = $1 AND seen_at > now() - make_interval(secs => $2)
+		ORDER BY connected_at
+	`, userID, p.ttl.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionInfo
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (p *PostgresSessions) Heartbeat(ctx context.Context, nodeID string) error {
+	_, err := p.db.Exec(ctx, `UPDATE ws_sessions SET seen_at = now() WHERE node_id = $1`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh sessions: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresSessions) Reset(ctx context.Context, nodeID string) error {
+	_, err := p.db.Exec(ctx, `DELETE FROM ws_sessions WHERE node_id = $1`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to reset sessions: %w", err)
+	}
+	return nil
+}
+
+// MemorySessions — реестр сессий в памяти для тестов и запуска в одном процессе.
+type MemorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]SessionInfo
+}
+
+func NewMemorySessions() *MemorySessions {
+	return &MemorySessions{sessions: make(map[string]SessionInfo)}
+}
+
+func (m *MemorySessions) Add(_ context.Context, s SessionInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *MemorySessions) Remove(_ context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+func (m *MemorySessions) Get(_ context.Context, sessionID string) (SessionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return SessionInfo{}, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+func (m *MemorySessions) List(_ context.Context, userID string) ([]SessionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []SessionInfo
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ConnectedAt.Before(sessions[j].ConnectedAt) })
+	return sessions, nil
+}
+
+func (m *MemorySessions) Heartbeat(context.Context, string) error { return nil }
+
+func (m *MemorySessions) Reset(_ context.Context, nodeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if s.NodeID == nodeID {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
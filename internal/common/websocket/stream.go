@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ReplayPolicy определяет, сохраняется ли сообщение для повторной отправки
+// после переподключения клиента.
+type ReplayPolicy string
+
+const (
+	// ReplayNone — сообщение не сохраняется (ошибки, предложения поездок).
+	ReplayNone ReplayPolicy = "none"
+	// ReplayCritical — хранится до подтверждения клиентом или истечения срока.
+	ReplayCritical ReplayPolicy = "critical"
+	// ReplayLatest — хранится только последнее сообщение этого типа:
+	// устаревшие координаты при переподключении не нужны.
+	ReplayLatest ReplayPolicy = "latest"
+)
+
+type streamEntry struct {
+	seq     uint64
+	msgType string
+	data    []byte
+	at      time.Time
+}
+
+// stream — исходящий поток пользователя на узле: счётчик seq и буфер для повтора.
+type stream struct {
+	mu      sync.Mutex
+	seq     uint64
+	entries []streamEntry
+	// lost — наибольший seq, удалённый из буфера.
+	lost uint64
+	// acked — подтверждённый seq каждого соединения пользователя.
+	acked map[string]sessionAck
+	// idle — когда ушёл последний подписчик; поток живёт ещё Retention.
+	idle time.Time
+}
+
+type sessionAck struct {
+	seq uint64
+	// left — момент отключения; подтверждение держит буфер ещё Retention.
+	left time.Time
+}
+
+// append присваивает сообщению следующий seq и при необходимости сохраняет его.
+func (st *stream) append(reg *Registry, cfg Config, data []byte, now time.Time) []byte {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+		return data
+	}
+	msg.Seq = st.seq + 1
+	stamped, err := json.Marshal(msg)
+	if err != nil {
+		return data
+	}
+	st.seq = msg.Seq
+
+	st.expire(now, cfg.Retention)
+
+	mt, _ := reg.Lookup(msg.Type, msg.Version)
+	switch mt.Replay {
+	case ReplayLatest:
+		kept := st.entries[:0]
+		for _, e := range st.entries {
+			if e.msgType != msg.Type {
+				kept = append(kept, e)
+			}
+		}
+		st.entries = kept
+	case ReplayCritical:
+	default:
+		return stamped
+	}
+
+	st.entries = append(st.entries, streamEntry{seq: msg.Seq, msgType: msg.Type, data: stamped, at: now})
+	if over := len(st.entries) - cfg.RetentionSize; over > 0 {
+		st.lost = st.entries[over-1].seq
+		st.entries = append(st.entries[:0], st.entries[over:]...)
+	}
+	return stamped
+}
+
+func (st *stream) expire(now time.Time, ttl time.Duration) {
+	n := 0
+	for n < len(st.entries) && now.Sub(st.entries[n].at) > ttl {
+		n++
+	}
+	if n > 0 {
+		st.lost = st.entries[n-1].seq
+		st.entries = append(st.entries[:0], st.entries[n:]...)
+	}
+}
+
+func (st *stream) join(sessionID string) {
+	if st.acked == nil {
+		st.acked = make(map[string]sessionAck)
+	}
+	st.acked[sessionID] = sessionAck{}
+}
+
+func (st *stream) leave(sessionID string, now time.Time) {
+	if a, ok := st.acked[sessionID]; ok && a.left.IsZero() {
+		a.left = now
+		st.acked[sessionID] = a
+	}
+}
+
+// ack запоминает подтверждение живого соединения.
+func (st *stream) ack(sessionID string, seq uint64, now time.Time, ttl time.Duration) {
+	a, ok := st.acked[sessionID]
+	if !ok || !a.left.IsZero() || seq <= a.seq {
+		return
+	}
+	a.seq = seq
+	st.acked[sessionID] = a
+	st.trim(now, ttl)
+}
+
+// trim удаляет сообщения до наименьшего seq, подтверждённого всеми
+// соединениями, включая отключившиеся не дольше ttl назад.
+func (st *stream) trim(now time.Time, ttl time.Duration) {
+	for id, a := range st.acked {
+		if !a.left.IsZero() && now.Sub(a.left) > ttl {
+			delete(st.acked, id)
+		}
+	}
+	if len(st.acked) == 0 {
+		return
+	}
+	floor := st.seq
+	for _, a := range st.acked {
+		floor = min(floor, a.seq)
+	}
+
+	n := 0
+	for n < len(st.entries) && st.entries[n].seq <= floor {
+		n++
+	}
+	if n > 0 {
+		st.lost = max(st.lost, st.entries[n-1].seq)
+		st.entries = append(st.entries[:0], st.entries[n:]...)
+	}
+}
+
+// ResumeResult — ответ на запрос resume.
+type ResumeResult struct {
+	Since    uint64 `json:"since"`
+	LastSeq  uint64 `json:"last_seq"`
+	Replayed int    `json:"replayed"`
+	// Gap — часть сообщений после since уже вытеснена из буфера.
+	Gap bool `json:"gap"`
+	// Reset — поток на узле начат заново (since больше последнего seq),
+	// клиенту нужно перечитать состояние через HTTP.
+	Reset bool `json:"reset"`
+}
+
+// since возвращает сохранённые сообщения с seq больше n.
+func (st *stream) since(n uint64, now time.Time, ttl time.Duration) ([][]byte, ResumeResult) {
+	st.expire(now, ttl)
+
+	res := ResumeResult{Since: n, LastSeq: st.seq, Reset: n > st.seq, Gap: st.lost > n}
+	if res.Reset {
+		n = 0
+		res.Gap = st.lost > 0
+	}
+
+	var replay [][]byte
+	for _, e := range st.entries {
+		if e.seq > n {
+			replay = append(replay, e.data)
+		}
+	}
+	res.Replayed = len(replay)
+	return replay, res
+}
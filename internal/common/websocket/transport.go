@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// EventFilter отбирает сообщения для HTTP-транспорта, например только события одной поездки.
+type EventFilter func(msg Message) bool
+
+// RideFilter пропускает сообщения поездки rideID и служебные сообщения без ride_id.
+func RideFilter(rideID string) EventFilter {
+	return func(msg Message) bool {
+		var payload struct {
+			RideID string `json:"ride_id"`
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.RideID == "" {
+			return true
+		}
+		return payload.RideID == rideID
+	}
+}
+
+var errTransportClosed = errors.New("transport closed")
+
+// SSEConn — Conn поверх text/event-stream; id события — seq сообщения.
+type SSEConn struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	ctx    context.Context
+	filter EventFilter
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSSEConn(w http.ResponseWriter, r *http.Request, filter EventFilter) *SSEConn {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	c := &SSEConn{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		ctx:    r.Context(),
+		filter: filter,
+		closed: make(chan struct{}),
+	}
+	c.rc.Flush()
+	return c
+}
+
+// ReadMessage блокируется до закрытия запроса: от клиента SSE сообщений нет.
+func (c *SSEConn) ReadMessage() (int, []byte, error) {
+	select {
+	case <-c.ctx.Done():
+		return 0, nil, io.EOF
+	case <-c.closed:
+		return 0, nil, errTransportClosed
+	}
+}
+
+func (c *SSEConn) WriteMessage(_ int, data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	if c.filter != nil && !c.filter(msg) {
+		return nil
+	}
+
+	if msg.Seq > 0 {
+		if _, err := fmt.Fprintf(c.w, "id: %d\n", msg.Seq); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.w, "event: %s\ndata: %s\n\n", msg.Type, data); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
+
+// WriteControl превращает ping в комментарий SSE, чтобы прокси не закрывали
+// простаивающее соединение. Кадр закрытия не нужен.
+func (c *SSEConn) WriteControl(messageType int, _ []byte, deadline time.Time) error {
+	if messageType != websocket.PingMessage {
+		return nil
+	}
+	c.SetWriteDeadline(deadline)
+	if _, err := io.WriteString(c.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
+
+func (c *SSEConn) SetReadDeadline(time.Time) error { return nil }
+
+// SetWriteDeadline продлевает запись, иначе WriteTimeout сервера оборвал бы поток.
+func (c *SSEConn) SetWriteDeadline(t time.Time) error {
+	if err := c.rc.SetWriteDeadline(t); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func (c *SSEConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// PollConn копит сообщения для одного ответа long-poll.
+type PollConn struct {
+	filter EventFilter
+
+	mu       sync.Mutex
+	messages []json.RawMessage
+	lastSeq  uint64
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewPollConn(filter EventFilter) *PollConn {
+	return &PollConn{
+		filter: filter,
+		ready:  make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *PollConn) ReadMessage() (int, []byte, error) {
+	<-c.closed
+	return 0, nil, errTransportClosed
+}
+
+func (c *PollConn) WriteMessage(_ int, data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if msg.Seq > c.lastSeq {
+		c.lastSeq = msg.Seq
+	}
+	if c.filter != nil && !c.filter(msg) {
+		return nil
+	}
+	c.messages = append(c.messages, json.RawMessage(data))
+	c.readyOnce.Do(func() { close(c.ready) })
+	return nil
+}
+
+func (c *PollConn) WriteControl(int, []byte, time.Time) error { return nil }
+func (c *PollConn) SetReadDeadline(time.Time) error           { return nil }
+func (c *PollConn) SetWriteDeadline(time.Time) error          { return nil }
+
+func (c *PollConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// pollBatchWindow — сколько ждать после первого сообщения, чтобы отдать
+// повтор и соседние события одним ответом.
+const pollBatchWindow = 100 * time.Millisecond
+
+// Wait ждёт первое сообщение, истечение timeout или отмену ctx.
+func (c *PollConn) Wait(ctx context.Context, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.ready:
+	case <-timer.C:
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	batch := time.NewTimer(pollBatchWindow)
+	defer batch.Stop()
+	select {
+	case <-batch.C:
+	case <-ctx.Done():
+	}
+}
+
+// Messages возвращает накопленные сообщения и наибольший увиденный seq,
+// включая отфильтрованные сообщения.
+func (c *PollConn) Messages() ([]json.RawMessage, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := c.messages
+	if messages == nil {
+		messages = make([]json.RawMessage, 0)
+	}
+	return messages, c.lastSeq
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/contact/model"
+	"ride-hail-system/internal/contact/service"
+	usermodel "ride-hail-system/internal/user/model"
+)
+
+type ContactHandler struct {
+	service *service.ContactService
+}
+
+func NewContactHandler(s *service.ContactService) *ContactHandler {
+	return &ContactHandler{service: s}
+}
+
+// GetCounterpart выдаёт прокси-идентификатор собеседника по поездке.
+func (h *ContactHandler) GetCounterpart(w http.ResponseWriter, r *http.Request) {
+	const action = "GetContactProxy"
+	rideID := r.PathValue("ride_id")
+
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	proxy, err := h.service.CounterpartProxy(r.Context(), usermodel.Role(claims.Role), claims.UserID, rideID)
+	if err != nil {
+		writeError(w, action, rideID, err)
+		return
+	}
+	writeJSON(w, action, http.StatusOK, proxy)
+}
+
+type smsRequest struct {
+	Text string `json:"text"`
+}
+
+// Call просит провайдера соединить звонящего с владельцем прокси.
+func (h *ContactHandler) Call(w http.ResponseWriter, r *http.Request) {
+	h.connect(w, r, model.IntentCall, "")
+}
+
+// SendSMS пересылает SMS владельцу прокси.
+func (h *ContactHandler) SendSMS(w http.ResponseWriter, r *http.Request) {
+	var req smsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	h.connect(w, r, model.IntentSMS, req.Text)
+}
+
+func (h *ContactHandler) connect(w http.ResponseWriter, r *http.Request, kind model.IntentKind, text string) {
+	const action = "ContactConnect"
+	proxyID := r.PathValue("proxy_id")
+
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	intent, err := h.service.Connect(r.Context(), claims.UserID, proxyID, kind, text)
+	if err != nil {
+		writeError(w, action, intent.RideID, err)
+		return
+	}
+	writeJSON(w, action, http.StatusAccepted, intent)
+}
+
+func writeError(w http.ResponseWriter, action, rideID string, err error) {
+	switch {
+	case errors.Is(err, service.ErrRideNotFound), errors.Is(err, service.ErrNotParticipant),
+		errors.Is(err, service.ErrProxyNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrProxyExpired), errors.Is(err, service.ErrContactClosed):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, service.ErrNoNumber):
+		http.Error(w, "counterpart has no phone number on file", http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidIntent), errors.Is(err, service.ErrEmptySMS),
+		errors.Is(err, service.ErrSMSTooLong), errors.Is(err, service.ErrInvalidNumber):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Error(action, "Failed to relay contact", "", rideID, err.Error())
+		http.Error(w, "Failed to relay contact", http.StatusBadGateway)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, action string, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error(action, "Failed to encode response", "", "", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	usermodel "ride-hail-system/internal/user/model"
+)
+
+type IntentKind string
+
+const (
+	IntentCall IntentKind = "call"
+	IntentSMS  IntentKind = "sms"
+)
+
+const (
+	IntentInitiated = "INITIATED"
+	IntentFailed    = "FAILED"
+)
+
+// Contact — настоящий номер участника поездки.
+type Contact struct {
+	RideID    string
+	UserID    string
+	Role      usermodel.Role
+	Number    string
+	ProxyID   string
+	ExpiresAt time.Time
+}
+
+// Proxy — временный идентификатор собеседника для звонка или SMS.
+type Proxy struct {
+	ID        string         `json:"proxy_id"`
+	RideID    string         `json:"ride_id"`
+	Role      usermodel.Role `json:"role"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// Ride — участники и статус поездки.
+type Ride struct {
+	ID          string
+	PassengerID string
+	DriverID    string
+	Status      string
+}
+
+type Intent struct {
+	ID          string     `json:"intent_id"`
+	RideID      string     `json:"ride_id"`
+	ProxyID     string     `json:"proxy_id"`
+	CallerID    string     `json:"caller_id"`
+	Kind        IntentKind `json:"kind"`
+	Status      string     `json:"status"`
+	ProviderRef string     `json:"provider_ref,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
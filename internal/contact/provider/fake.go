@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"ride-hail-system/internal/common/logger"
+)
+
+// Relayed — звонок или SMS, принятые фейковым провайдером.
+type Relayed struct {
+	Ref  string
+	Kind string
+	From string
+	To   string
+	Text string
+}
+
+// Fake — провайдер для локальной разработки: ничего не звонит, а пишет в лог
+// маскированные номера и запоминает операции.
+type Fake struct {
+	mu      sync.Mutex
+	relayed []Relayed
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Call(_ context.Context, from, to string) (string, error) {
+	return f.record("call", from, to, ""), nil
+}
+
+func (f *Fake) SendSMS(_ context.Context, from, to, text string) (string, error) {
+	return f.record("sms", from, to, text), nil
+}
+
+// Relayed возвращает принятые операции в порядке поступления.
+func (f *Fake) Relayed() []Relayed {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Relayed(nil), f.relayed...)
+}
+
+func (f *Fake) record(kind, from, to, text string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ref := fmt.Sprintf("fake-%s-%d", kind, len(f.relayed)+1)
+	f.relayed = append(f.relayed, Relayed{Ref: ref, Kind: kind, From: from, To: to, Text: text})
+	logger.Info("fake_provider", fmt.Sprintf("Relayed %s %s -> %s", kind, Mask(from), Mask(to)), "", ref)
+	return ref
+}
+
+// Mask оставляет от номера только последние четыре цифры.
+func Mask(number string) string {
+	if len(number) <= 4 {
+		return "****"
+	}
+	return "****" + number[len(number)-4:]
+}
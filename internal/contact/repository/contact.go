@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ride-hail-system/internal/contact/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRideNotFound    = errors.New("ride not found")
+	ErrContactNotFound = errors.New("contact not found")
+)
+
+type ContactRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewContactRepository(db *pgxpool.Pool) *ContactRepository {
+	return &ContactRepository{db: db}
+}
+
+func (r *ContactRepository) GetRide(ctx context.Context, rideID string) (model.Ride, error) {
+	ride := model.Ride{ID: rideID}
+
+	query := `
+		SELECT passenger_id::text, coalesce(driver_id::text, ''), coalesce(status, '')
+		FROM rides
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, rideID).Scan(&ride.PassengerID, &ride.DriverID, &ride.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Ride{}, ErrRideNotFound
+		}
+		return model.Ride{}, fmt.Errorf("failed to get ride: %w", err)
+	}
+	return ride, nil
+}
+
+// ProfileNumber возвращает телефон из профиля пользователя (users.attrs.phone).
+func (r *ContactRepository) ProfileNumber(ctx context.Context, userID string) (string, error) {
+	var number string
+	err := r.db.QueryRow(ctx, `SELECT coalesce(attrs->>'phone', '') FROM users WHERE id = $1`, userID).Scan(&number)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get profile phone: %w", err)
+	}
+	return number, nil
+}
+
+// UpsertContact сохраняет номер участника.
+func (r *ContactRepository) UpsertContact(ctx context.Context, c model.Contact) (model.Contact, error) {
+	query := `
+		INSERT INTO ride_contacts (ride_id, user_id, role, real_number, proxy_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (ride_id, user_id) DO UPDATE SET
+			real_number = EXCLUDED.real_number,
+			proxy_id = CASE WHEN ride_contacts.expires_at > now() THEN ride_contacts.proxy_id ELSE EXCLUDED.proxy_id END,
+			expires_at = EXCLUDED.expires_at
+		RETURNING proxy_id, expires_at
+	`
+
+	err := r.db.QueryRow(ctx, query, c.RideID, c.UserID, c.Role, c.Number, c.ProxyID, c.ExpiresAt).
+		Scan(&c.ProxyID, &c.ExpiresAt)
+	if err != nil {
+		return model.Contact{}, fmt.Errorf("failed to save contact: %w", err)
+	}
+	return c, nil
+}
+
+const contactColumns = `ride_id::text, user_id::text, role, real_number, proxy_id, expires_at`
+
+func scanContact(row pgx.Row) (model.Contact, error) {
+	var c model.Contact
+	err := row.Scan(&c.RideID, &c.UserID, &c.Role, &c.Number, &c.ProxyID, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Contact{}, ErrContactNotFound
+		}
+		return model.Contact{}, fmt.Errorf("failed to get contact: %w", err)
+	}
+	return c, nil
+}
+
+func (r *ContactRepository) GetContact(ctx context.Context, rideID, userID string) (model.Contact, error) {
+	return scanContact(r.db.QueryRow(ctx, `
+		SELECT `+contactColumns+`
+		FROM ride_contacts
+		WHERE ride_id = $1 AND user_id = $2
+	`, rideID, userID))
+}
+
+func (r *ContactRepository) GetContactByProxy(ctx context.Context, proxyID string) (model.Contact, error) {
+	return scanContact(r.db.QueryRow(ctx, `
+		SELECT `+contactColumns+`
+		FROM ride_contacts
+		WHERE proxy_id = $1
+	`, proxyID))
+}
+
+func (r *ContactRepository) InsertIntent(ctx context.Context, intent model.Intent) (model.Intent, error) {
+	query := `
+		INSERT INTO contact_intents (ride_id, proxy_id, caller_id, kind, status, provider_ref)
+		VALUES ($1, $2, $3, $4, $5, nullif($6, ''))
+		RETURNING id::text, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, intent.RideID, intent.ProxyID, intent.CallerID, intent.Kind, intent.Status, intent.ProviderRef).
+		Scan(&intent.ID, &intent.CreatedAt)
+	if err != nil {
+		return model.Intent{}, fmt.Errorf("failed to insert contact intent: %w", err)
+	}
+	return intent, nil
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/contact/model"
+	"ride-hail-system/internal/contact/repository"
+	ridemodel "ride-hail-system/internal/ride/model"
+	usermodel "ride-hail-system/internal/user/model"
+	"ride-hail-system/pkg/phone"
+)
+
+type ContactRepository interface {
+	GetRide(ctx context.Context, rideID string) (model.Ride, error)
+	ProfileNumber(ctx context.Context, userID string) (string, error)
+	UpsertContact(ctx context.Context, c model.Contact) (model.Contact, error)
+	GetContact(ctx context.Context, rideID, userID string) (model.Contact, error)
+	GetContactByProxy(ctx context.Context, proxyID string) (model.Contact, error)
+	InsertIntent(ctx context.Context, intent model.Intent) (model.Intent, error)
+}
+
+// Provider — телефония, соединяющая участников без раскрытия номеров.
+type Provider interface {
+	Call(ctx context.Context, from, to string) (string, error)
+	SendSMS(ctx context.Context, from, to, text string) (string, error)
+}
+
+var (
+	ErrRideNotFound   = repository.ErrRideNotFound
+	ErrNotParticipant = errors.New("user is not a participant of the ride")
+	ErrContactClosed  = errors.New("contact is not available for the ride in its current status")
+	ErrProxyNotFound  = errors.New("proxy not found")
+	ErrProxyExpired   = errors.New("proxy has expired")
+	ErrNoNumber       = errors.New("no phone number on file")
+	ErrInvalidNumber  = phone.ErrInvalid
+	ErrInvalidIntent  = errors.New("intent kind must be call or sms")
+	ErrEmptySMS       = errors.New("sms text is required")
+	ErrSMSTooLong     = fmt.Errorf("sms is longer than %d characters", maxSMSLength)
+)
+
+const maxSMSLength = 500
+
+// contactOpen — статусы, в которых участники могут связаться друг с другом.
+func contactOpen(status string) bool {
+	switch ridemodel.RideStatus(status) {
+	case ridemodel.RideMatched, ridemodel.RideEnRoute, ridemodel.RideArrived, ridemodel.RideInProgress:
+		return true
+	}
+	return false
+}
+
+type ContactService struct {
+	repo     ContactRepository
+	provider Provider
+	ttl      time.Duration
+}
+
+func NewContactService(repo ContactRepository, provider Provider, ttl time.Duration) *ContactService {
+	return &ContactService{repo: repo, provider: provider, ttl: ttl}
+}
+
+// RegisterNumber сохраняет номер участника поездки и возвращает его прокси-идентификатор.
+func (s *ContactService) RegisterNumber(ctx context.Context, rideID, userID string, role usermodel.Role, number string) (string, error) {
+	ride, err := s.participant(ctx, role, userID, rideID)
+	if err != nil {
+		return "", err
+	}
+	contact, err := s.register(ctx, ride, userID, role, number)
+	if err != nil {
+		return "", err
+	}
+	return contact.ProxyID, nil
+}
+
+// CounterpartProxy возвращает прокси-идентификатор собеседника по поездке.
+func (s *ContactService) CounterpartProxy(ctx context.Context, role usermodel.Role, userID, rideID string) (model.Proxy, error) {
+	ride, err := s.participant(ctx, role, userID, rideID)
+	if err != nil {
+		return model.Proxy{}, err
+	}
+	if !contactOpen(ride.Status) {
+		return model.Proxy{}, ErrContactClosed
+	}
+
+	otherID, otherRole := counterpart(ride, role)
+	contact, err := s.contact(ctx, ride, otherID, otherRole)
+	if err != nil {
+		return model.Proxy{}, err
+	}
+	return model.Proxy{ID: contact.ProxyID, RideID: ride.ID, Role: otherRole, ExpiresAt: contact.ExpiresAt}, nil
+}
+
+// Connect передаёт провайдеру звонок или SMS от участника поездки владельцу прокси.
+func (s *ContactService) Connect(ctx context.Context, callerID, proxyID string, kind model.IntentKind, text string) (model.Intent, error) {
+	const action = "contact_connect"
+
+	switch kind {
+	case model.IntentCall:
+	case model.IntentSMS:
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return model.Intent{}, ErrEmptySMS
+		}
+		if utf8.RuneCountInString(text) > maxSMSLength {
+			return model.Intent{}, ErrSMSTooLong
+		}
+	default:
+		return model.Intent{}, ErrInvalidIntent
+	}
+
+	target, err := s.repo.GetContactByProxy(ctx, proxyID)
+	if err != nil {
+		if errors.Is(err, repository.ErrContactNotFound) {
+			return model.Intent{}, ErrProxyNotFound
+		}
+		return model.Intent{}, err
+	}
+	if time.Now().After(target.ExpiresAt) {
+		return model.Intent{}, ErrProxyExpired
+	}
+
+	ride, err := s.repo.GetRide(ctx, target.RideID)
+	if err != nil {
+		return model.Intent{}, err
+	}
+	// Прокси доступен только второму участнику той же поездки.
+	var callerRole usermodel.Role
+	switch {
+	case callerID == target.UserID:
+		return model.Intent{}, ErrProxyNotFound
+	case callerID == ride.PassengerID:
+		callerRole = usermodel.RolePassenger
+	case callerID == ride.DriverID:
+		callerRole = usermodel.RoleDriver
+	default:
+		return model.Intent{}, ErrProxyNotFound
+	}
+	if !contactOpen(ride.Status) {
+		return model.Intent{}, ErrProxyExpired
+	}
+
+	caller, err := s.contact(ctx, ride, callerID, callerRole)
+	if err != nil {
+		return model.Intent{}, err
+	}
+
+	intent := model.Intent{
+		RideID:   ride.ID,
+		ProxyID:  proxyID,
+		CallerID: callerID,
+		Kind:     kind,
+		Status:   model.IntentInitiated,
+	}
+	var providerErr error
+	if kind == model.IntentCall {
+		intent.ProviderRef, providerErr = s.provider.Call(ctx, caller.Number, target.Number)
+	} else {
+		intent.ProviderRef, providerErr = s.provider.SendSMS(ctx, caller.Number, target.Number, text)
+	}
+	if providerErr != nil {
+		intent.Status = model.IntentFailed
+		logger.Error(action, "Provider rejected "+string(kind), "", ride.ID, providerErr.Error())
+	}
+
+	saved, err := s.repo.InsertIntent(ctx, intent)
+	if err != nil {
+		return model.Intent{}, err
+	}
+	if providerErr != nil {
+		return saved, fmt.Errorf("provider failed: %w", providerErr)
+	}
+	logger.Info(action, fmt.Sprintf("Relayed %s from %s to %s", kind, callerRole, target.Role), "", ride.ID)
+	return saved, nil
+}
+
+func (s *ContactService) participant(ctx context.Context, role usermodel.Role, userID, rideID string) (model.Ride, error) {
+	ride, err := s.repo.GetRide(ctx, rideID)
+	if err != nil {
+		return model.Ride{}, err
+	}
+	switch {
+	case role == usermodel.RolePassenger && ride.PassengerID == userID:
+	case role == usermodel.RoleDriver && ride.DriverID != "" && ride.DriverID == userID:
+	default:
+		return model.Ride{}, ErrNotParticipant
+	}
+	return ride, nil
+}
+
+// contact возвращает действующий контакт участника или выпускает новый.
+func (s *ContactService) contact(ctx context.Context, ride model.Ride, userID string, role usermodel.Role) (model.Contact, error) {
+	if userID == "" {
+		return model.Contact{}, ErrNoNumber
+	}
+	existing, err := s.repo.GetContact(ctx, ride.ID, userID)
+	switch {
+	case err == nil && time.Now().Before(existing.ExpiresAt):
+		return existing, nil
+	case err == nil:
+		return s.register(ctx, ride, userID, role, existing.Number)
+	case errors.Is(err, repository.ErrContactNotFound):
+		return s.register(ctx, ride, userID, role, "")
+	default:
+		return model.Contact{}, err
+	}
+}
+
+func (s *ContactService) register(ctx context.Context, ride model.Ride, userID string, role usermodel.Role, number string) (model.Contact, error) {
+	if number == "" {
+		profile, err := s.repo.ProfileNumber(ctx, userID)
+		if err != nil {
+			return model.Contact{}, err
+		}
+		if profile == "" {
+			return model.Contact{}, ErrNoNumber
+		}
+		number = profile
+	}
+	normalized, err := phone.Normalize(number)
+	if err != nil {
+		return model.Contact{}, err
+	}
+
+	proxyID, err := newProxyID()
+	if err != nil {
+		return model.Contact{}, err
+	}
+	return s.repo.UpsertContact(ctx, model.Contact{
+		RideID:    ride.ID,
+		UserID:    userID,
+		Role:      role,
+		Number:    normalized,
+		ProxyID:   proxyID,
+		ExpiresAt: time.Now().Add(s.ttl),
+	})
+}
+
+func counterpart(ride model.Ride, role usermodel.Role) (string, usermodel.Role) {
+	if role == usermodel.RolePassenger {
+		return ride.DriverID, usermodel.RoleDriver
+	}
+	return ride.PassengerID, usermodel.RolePassenger
+}
+
+func newProxyID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate proxy id: %w", err)
+	}
+	return "px_" + hex.EncodeToString(buf), nil
+}
@@ -3,25 +3,24 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
 
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/driver/handler/dto"
 	"ride-hail-system/internal/driver/model"
 	"ride-hail-system/internal/driver/service"
-	"ride-hail-system/internal/user/jwt"
 	usermodel "ride-hail-system/internal/user/model"
 	"ride-hail-system/pkg/uuid"
 )
 
 type DriverHandler struct {
-	service    *service.DriverService
-	jwtManager *jwt.Manager
+	service *service.DriverService
 }
 
-func NewHandler(s *service.DriverService, jwtManager *jwt.Manager) *DriverHandler {
-	return &DriverHandler{service: s, jwtManager: jwtManager}
+// NewHandler создаёт обработчики водителя.
+func NewHandler(s *service.DriverService) *DriverHandler {
+	return &DriverHandler{service: s}
 }
 
 func (h *DriverHandler) GetDriverInfo(ctx context.Context, driverID string) (model.DriverInfo, error) {
@@ -40,17 +39,6 @@ func (h *DriverHandler) GoOnline(w http.ResponseWriter, r *http.Request) {
 	driverID := r.PathValue("driver_id")
 	logger.Info("go_online", "Driver attempting to go online", "", driverID)
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if strings.TrimSpace(claims.UserID) != strings.TrimSpace(driverID) {
-		http.Error(w, "forbidden: token does not match driver", http.StatusForbidden)
-		return
-	}
-	if strings.TrimSpace(claims.Role) != strings.TrimSpace(string(usermodel.RoleDriver)) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
-
 	var req dto.OnlineRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Warn("go_online", "Invalid request body", "", driverID, err.Error())
@@ -61,6 +49,10 @@ func (h *DriverHandler) GoOnline(w http.ResponseWriter, r *http.Request) {
 	driverSession, err := h.service.GoOnline(ctx, uuid.UUID(driverID), req.Latitude, req.Longitude)
 	if err != nil {
 		logger.Error("go_online", "Failed to set driver online", "", driverID, err.Error())
+		if errors.Is(err, service.ErrDriverNotVerified) || errors.Is(err, service.ErrDriverBlocked) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -87,17 +79,6 @@ func (h *DriverHandler) GoOffline(w http.ResponseWriter, r *http.Request) {
 	driverID := r.PathValue("driver_id")
 	logger.Info("go_offline", "Driver attempting to go offline", "", driverID)
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if claims.UserID != driverID {
-		http.Error(w, "forbidden: token does not match driver", http.StatusForbidden)
-		return
-	}
-	if claims.Role != string(usermodel.RoleDriver) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
-
 	session, durationHours, err := h.service.GoOffline(ctx, uuid.UUID(driverID))
 	if err != nil {
 		logger.Error("go_offline", "Failed to set driver offline", "", driverID, err.Error())
@@ -132,17 +113,6 @@ func (h *DriverHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
 	driverID := r.PathValue("driver_id")
 	logger.Debug("update_location", "Driver updating location", "", driverID)
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if claims.UserID != driverID {
-		http.Error(w, "forbidden: token does not match driver", http.StatusForbidden)
-		return
-	}
-	if claims.Role != string(usermodel.RoleDriver) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
-
 	var req dto.LocationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Warn("update_location", "Invalid request body", "", driverID, err.Error())
@@ -182,17 +152,6 @@ func (h *DriverHandler) Start(w http.ResponseWriter, r *http.Request) {
 	driverID := r.PathValue("driver_id")
 	logger.Info("start_ride", "Driver starting ride", "", driverID)
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if claims.UserID != driverID {
-		http.Error(w, "forbidden: token does not match driver", http.StatusForbidden)
-		return
-	}
-	if claims.Role != string(usermodel.RoleDriver) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
-
 	var req dto.StartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Warn("start_ride", "Invalid request body", "", driverID, err.Error())
@@ -222,17 +181,6 @@ func (h *DriverHandler) Complete(w http.ResponseWriter, r *http.Request) {
 	driverID := r.PathValue("driver_id")
 	logger.Info("complete_ride", "Driver completing ride", "", driverID)
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if claims.UserID != driverID {
-		http.Error(w, "forbidden: token does not match driver", http.StatusForbidden)
-		return
-	}
-	if claims.Role != string(usermodel.RoleDriver) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
-
 	var req dto.CompleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Warn("complete_ride", "Invalid request body", "", driverID, err.Error())
@@ -13,16 +13,25 @@ import (
 	"ride-hail-system/pkg/uuid"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DriverRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewDriverRepository(db *pgx.Conn) *DriverRepository {
+func NewDriverRepository(db *pgxpool.Pool) *DriverRepository {
 	return &DriverRepository{db: db}
 }
 
+func (r *DriverRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return tx, nil
+}
+
 func (r *DriverRepository) GetInfo(ctx context.Context, id string) (model.DriverInfo, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -74,6 +83,26 @@ func (r *DriverRepository) GetDriverIDByRideID(ctx context.Context, rideID strin
 	return DriverID, nil
 }
 
+func (r *DriverRepository) GetPassengerIDByRideID(ctx context.Context, rideID string) (string, error) {
+	var passengerID string
+
+	query := `
+		SELECT passenger_id
+		FROM rides
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, rideID).Scan(&passengerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("ride with id %s not found", rideID)
+		}
+		return "", fmt.Errorf("failed to get passenger_id: %w", err)
+	}
+
+	return passengerID, nil
+}
+
 func (r *DriverRepository) GetPickupLocation(ctx context.Context, rideID string) (float64, float64, error) {
 	var lat, lng float64
 	query := `
@@ -229,16 +258,14 @@ func (r *DriverRepository) SetOffline(ctx context.Context, driverID uuid.UUID) (
 	return session, nil
 }
 
-func (r *DriverRepository) SaveLocation(ctx context.Context, location model.LocationHistory) (ridemodel.Coordinate, error) {
-	tx, err := r.db.Begin(ctx)
-	if err != nil {
-		return ridemodel.Coordinate{}, err
+func (r *DriverRepository) SaveLocation(ctx context.Context, tx pgx.Tx, location model.LocationHistory) (ridemodel.Coordinate, error) {
+	if tx == nil {
+		return ridemodel.Coordinate{}, fmt.Errorf("transaction is nil")
 	}
-	defer tx.Rollback(ctx)
 
 	var coord ridemodel.Coordinate
 
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
         SELECT id, entity_id, entity_type, address, latitude, longitude, is_current, created_at, updated_at
         FROM coordinates
         WHERE entity_id = $1 AND entity_type = 'driver' AND is_current = true
@@ -295,22 +322,12 @@ func (r *DriverRepository) SaveLocation(ctx context.Context, location model.Loca
 		return ridemodel.Coordinate{}, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return ridemodel.Coordinate{}, err
-	}
-
 	return coord, nil
 }
 
-func (r *DriverRepository) Start(ctx context.Context, driverID uuid.UUID, rideID uuid.UUID, loc model.Location) (usermodel.DriverStatus, time.Time, error) {
-	tx, err := r.db.Begin(ctx)
-	if err != nil {
-		return "", time.Time{}, err
-	}
-	defer tx.Rollback(ctx)
-
+func (r *DriverRepository) Start(ctx context.Context, tx pgx.Tx, driverID uuid.UUID, rideID uuid.UUID, loc model.Location) (usermodel.DriverStatus, time.Time, error) {
 	var startedAt time.Time
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 	UPDATE rides
 	SET status = 'IN_PROGRESS',
 	    arrived_at = now(),
@@ -367,23 +384,13 @@ func (r *DriverRepository) Start(ctx context.Context, driverID uuid.UUID, rideID
 		return "", time.Time{}, fmt.Errorf("failed to insert location history: %w", err)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return "", time.Time{}, fmt.Errorf("commit failed: %w", err)
-	}
-
 	return newStatus, startedAt, nil
 }
 
-func (r *DriverRepository) Complete(ctx context.Context, driverID uuid.UUID, driverEarning float64, location model.Location, distance, duration float64) (time.Time, error) {
+func (r *DriverRepository) Complete(ctx context.Context, tx pgx.Tx, driverID uuid.UUID, driverEarning float64, location model.Location, distance, duration float64) (time.Time, error) {
 	var completedAt time.Time
 
-	tx, err := r.db.Begin(ctx)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to start transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		UPDATE rides
 		SET 
 			status = 'COMPLETED',
@@ -442,10 +449,6 @@ func (r *DriverRepository) Complete(ctx context.Context, driverID uuid.UUID, dri
 		return time.Time{}, fmt.Errorf("failed to insert ride event: %w", err)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return time.Time{}, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return completedAt, nil
 }
 
@@ -474,3 +477,20 @@ func (r *DriverRepository) GetDriverStatus(ctx context.Context, driverID uuid.UU
 	}
 	return status, nil
 }
+
+// GetDriverEligibility возвращает то, что нужно для выхода на линию:
+// подтверждён ли водитель и статус его учётной записи.
+func (r *DriverRepository) GetDriverEligibility(ctx context.Context, driverID uuid.UUID) (bool, usermodel.UserStatus, error) {
+	var verified bool
+	var status usermodel.UserStatus
+	err := r.db.QueryRow(ctx, `
+		SELECT d.is_verified, u.status
+		FROM drivers d
+		JOIN users u ON u.id = d.id
+		WHERE d.id = $1
+	`, driverID).Scan(&verified, &status)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to get driver eligibility: %w", err)
+	}
+	return verified, status, nil
+}
@@ -1,212 +1,56 @@
 package rmq
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/common/rmq"
 )
 
-func (c *Client) ConsumeRideRequests(queueName string, handler func(msg rmq.RideRequestedMessage)) error {
-	ch := c.Channel
-	exchange := "ride_topic"
+func (c *Client) ConsumeRideRequests(ctx context.Context, queueName string, handler func(msg rmq.RideRequestedMessage) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypeRideRequested, PartitionBy: "ride_id"}
 
-	if err := ch.ExchangeDeclare(
-		exchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("rmq_exchange_declare_failed", "Failed to declare exchange", exchange, "", err.Error())
-		return fmt.Errorf("failed to declare exchange: %w", err)
-	}
-	logger.Info("rmq_exchange_declared", "Exchange declared successfully", exchange, "")
-
-	q, err := ch.QueueDeclare(
-		queueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
-	}
-
-	if err := ch.QueueBind(
-		q.Name,
-		"ride.request.*",
-		exchange,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("failed to bind queue: %w", err)
-	}
-
-	// Начинаем потребление
-	deliveries, err := ch.Consume(
-		q.Name,
-		"",
-		true,  // auto-ack
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,
-	)
+	err := rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.RideRequestedMessage
+		if err := env.Decode(&msg); err != nil {
+			return err
+		}
+		logger.Info("rmq_message_received", "Ride request received", queueName, msg.RideID)
+		return handler(msg)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
+		logger.Error("rmq_consume_failed", "Failed to start consuming ride requests", queueName, "", err.Error())
+		return err
 	}
-
-	go func() {
-		for d := range deliveries {
-			var msg rmq.RideRequestedMessage
-			if err := json.Unmarshal(d.Body, &msg); err != nil {
-				logger.Warn("rmq_unmarshal_failed", "Failed to unmarshal ride request", queueName, "", err.Error())
-				continue
-			}
-			logger.Info("rmq_message_received", "Ride request received", queueName, "")
-			handler(msg)
-		}
-	}()
-
 	return nil
 }
 
-func (c *Client) ConsumePassengerInfo(queueName string, handler func(msg rmq.PassiNFO)) error {
-	ch := c.Channel
-	exchange := "ride_topic"
-
-	if err := ch.ExchangeDeclare(
-		exchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("rmq_exchange_declare_failed", "Failed to declare exchange", exchange, "", err.Error())
-		return fmt.Errorf("failed to declare exchange: %w", err)
-	}
-	logger.Info("rmq_exchange_declared", "Exchange declared successfully", exchange, "")
-
-	q, err := ch.QueueDeclare(
-		queueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
-	}
-
-	if err := ch.QueueBind(
-		q.Name,
-		"ride.request.*",
-		exchange,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("failed to bind queue: %w", err)
-	}
+func (c *Client) ConsumePassengerInfo(ctx context.Context, queueName string, handler func(msg rmq.PassiNFO) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypePassengerInfo, PartitionBy: "ride_id"}
 
-	// Начинаем потребление
-	deliveries, err := ch.Consume(
-		q.Name,
-		"",
-		true,  // auto-ack
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,
-	)
+	err := rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.PassiNFO
+		if err := env.Decode(&msg); err != nil {
+			return err
+		}
+		logger.Info("rmq_message_received", "Passenger info received", queueName, msg.RideID)
+		return handler(msg)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
+		logger.Error("rmq_consume_failed", "Failed to start consuming passenger info", queueName, "", err.Error())
+		return err
 	}
-
-	go func() {
-		for d := range deliveries {
-			var msg rmq.PassiNFO
-			if err := json.Unmarshal(d.Body, &msg); err != nil {
-				logger.Warn("rmq_unmarshal_failed", "Failed to unmarshal ride request", queueName, "", err.Error())
-				continue
-			}
-			logger.Info("rmq_message_received", "Ride request received", queueName, "")
-			handler(msg)
-		}
-	}()
-
 	return nil
 }
 
-func (c *Client) ConsumeRideStatus(queueName string, handler func(msg rmq.RideStatusUpdateMessage)) error {
-	ch := c.Channel
-	exchange := "ride_topic"
+func (c *Client) ConsumeRideStatus(ctx context.Context, queueName string, handler func(msg rmq.RideStatusUpdateMessage) error) error {
+	spec := rmq.ConsumerSpec{Queue: queueName, Type: rmq.TypeRideStatus, PartitionBy: "ride_id"}
 
-	if err := ch.ExchangeDeclare(
-		exchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("failed to declare exchange: %w", err)
-	}
-
-	q, err := ch.QueueDeclare(
-		queueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
-	}
-
-	if err := ch.QueueBind(
-		q.Name,
-		"ride.status.*",
-		exchange,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("failed to bind queue: %w", err)
-	}
-
-	deliveries, err := ch.Consume(
-		q.Name,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
-	}
-
-	go func() {
-		for d := range deliveries {
-			var msg rmq.RideStatusUpdateMessage
-			if err := json.Unmarshal(d.Body, &msg); err != nil {
-				fmt.Printf("WARN: failed to unmarshal ride status: %v\n", err)
-				continue
-			}
-			handler(msg)
+	return rmq.RunConsumer(ctx, c.Bus, c.Options, spec, func(env rmq.Envelope) error {
+		var msg rmq.RideStatusUpdateMessage
+		if err := env.Decode(&msg); err != nil {
+			return err
 		}
-	}()
-
-	return nil
+		return handler(msg)
+	})
 }
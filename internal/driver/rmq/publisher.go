@@ -8,87 +8,52 @@ import (
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/common/rmq"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/jackc/pgx/v5"
 )
 
-func (c *Client) PublishDriverResponse(ctx context.Context, msg rmq.DriverResponseMessage) error {
+// PublishDriverResponse записывает ответ водителя в outbox в рамках tx.
+func (c *Client) PublishDriverResponse(ctx context.Context, tx pgx.Tx, msg rmq.DriverResponseMessage) error {
 	logger.Info("publish_driver_response", "Preparing to publish driver response", "", msg.RideID)
 
-	body, err := json.Marshal(msg)
-	if err != nil {
-		logger.Error("publish_driver_response", "Failed to marshal driver response message", "", msg.RideID, err.Error())
-		return err
-	}
-
 	routingKey := fmt.Sprintf("driver.response.%s", msg.RideID)
 
-	if err := c.Channel.ExchangeDeclare(
-		c.Exchange,
-		"topic",
-		true,  // durable
-		false, // auto-delete
-		false, // internal
-		false, // no-wait
-		nil,
-	); err != nil {
-		logger.Error("publish_driver_response", "Failed to declare exchange", "", msg.RideID, err.Error())
+	env, err := rmq.NewEnvelope(rmq.TypeDriverResponse, 1, msg.RideID, msg)
+	if err != nil {
+		logger.Error("publish_driver_response", "Failed to build envelope", "", msg.RideID, err.Error())
 		return err
 	}
 
-	if err := c.Channel.PublishWithContext(
-		ctx,
-		c.Exchange,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	); err != nil {
-		logger.Error("publish_driver_response", "Failed to publish driver response", "", msg.RideID, err.Error())
+	if err := c.Outbox.Enqueue(ctx, tx, c.Exchange, routingKey, env); err != nil {
+		logger.Error("publish_driver_response", "Failed to enqueue driver response", "", msg.RideID, err.Error())
 		return err
 	}
 
-	logger.Info("publish_driver_response", "Driver response published successfully", "", msg.RideID)
+	logger.Info("publish_driver_response", "Driver response enqueued to outbox", "", msg.RideID)
 	return nil
 }
 
 func (c *Client) PublishDriverStatus(ctx context.Context, msg rmq.RideStatusUpdateMessage) error {
 	logger.Info("publish_driver_status", "Preparing to publish driver status", "", msg.RideID)
 
-	body, err := json.Marshal(msg)
+	env, err := rmq.NewEnvelope(rmq.TypeDriverStatus, 1, msg.RideID, msg)
 	if err != nil {
-		logger.Error("publish_driver_status", "Failed to marshal driver status message", "", msg.RideID, err.Error())
+		logger.Error("publish_driver_status", "Failed to build envelope", "", msg.RideID, err.Error())
 		return err
 	}
 
-	routingKey := fmt.Sprintf("driver.status.%s", msg.RideID)
-
-	if err := c.Channel.ExchangeDeclare(
-		c.Exchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("publish_driver_status", "Failed to declare exchange", "", msg.RideID, err.Error())
+	body, err := json.Marshal(env)
+	if err != nil {
+		logger.Error("publish_driver_status", "Failed to marshal driver status message", "", msg.RideID, err.Error())
 		return err
 	}
 
-	if err := c.Channel.PublishWithContext(
-		ctx,
-		c.Exchange,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	); err != nil {
+	routingKey := fmt.Sprintf("driver.status.%s", msg.RideID)
+
+	if err := c.Bus.Publish(ctx, c.Exchange, routingKey, rmq.Publishing{
+		MessageID: env.MessageID,
+		Type:      env.Type,
+		Body:      body,
+	}); err != nil {
 		logger.Error("publish_driver_status", "Failed to publish driver status", "", msg.RideID, err.Error())
 		return err
 	}
@@ -97,44 +62,55 @@ func (c *Client) PublishDriverStatus(ctx context.Context, msg rmq.RideStatusUpda
 	return nil
 }
 
-func (c *Client) PublishLocationUpdate(ctx context.Context, msg rmq.LocationUpdateMessage) error {
+// PublishRideStatus записывает смену статуса поездки в outbox в рамках tx.
+func (c *Client) PublishRideStatus(ctx context.Context, tx pgx.Tx, msg rmq.RideStatusUpdateMessage) error {
+	return rmq.EnqueueRideStatus(ctx, c.Outbox, tx, msg)
+}
+
+// PublishLocationUpdate записывает обновление локации в outbox в рамках tx.
+func (c *Client) PublishLocationUpdate(ctx context.Context, tx pgx.Tx, msg rmq.LocationUpdateMessage) error {
 	logger.Info("publish_location_update", "Preparing to publish driver location update", "", msg.DriverID)
 
-	body, err := json.Marshal(msg)
+	env, err := rmq.NewEnvelope(rmq.TypeLocationUpdate, 1, msg.RideID, msg)
 	if err != nil {
-		logger.Error("publish_location_update", "Failed to marshal location update message", "", msg.DriverID, err.Error())
+		logger.Error("publish_location_update", "Failed to build envelope", "", msg.DriverID, err.Error())
 		return err
 	}
 
-	exchange := "location_fanout"
-	if err := c.Channel.ExchangeDeclare(
-		exchange,
-		"fanout",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("publish_location_update", "Failed to declare exchange", "", msg.DriverID, err.Error())
+	if err := c.Outbox.Enqueue(ctx, tx, rmq.ExchangeLocation, "", env); err != nil {
+		logger.Error("publish_location_update", "Failed to enqueue location update", "", msg.DriverID, err.Error())
 		return err
 	}
 
-	if err := c.Channel.PublishWithContext(
-		ctx,
-		exchange,
-		"",
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	); err != nil {
-		logger.Error("publish_location_update", "Failed to publish location update", "", msg.DriverID, err.Error())
-		return err
+	logger.Info("publish_location_update", "Driver location update enqueued to outbox", "", msg.DriverID)
+	return nil
+}
+
+// PublishDriverState публикует смену статуса водителя в driver_topic по ключу driver.state.{status}.
+func (c *Client) PublishDriverState(ctx context.Context, msg rmq.DriverStateMessage) error {
+	env, err := rmq.NewEnvelope(rmq.TypeDriverState, 1, msg.DriverID, msg)
+	if err != nil {
+		logger.Error("publish_driver_state", "Failed to build envelope", "", msg.DriverID, err.Error())
+		return fmt.Errorf("failed to build envelope: %w", err)
+	}
+
+	body, err := json.Marshal(env)
+	if err != nil {
+		logger.Error("publish_driver_state", "Failed to marshal driver state message", "", msg.DriverID, err.Error())
+		return fmt.Errorf("failed to marshal driver state message: %w", err)
+	}
+
+	routingKey := fmt.Sprintf("driver.state.%s", msg.Status)
+
+	if err := c.Bus.Publish(ctx, rmq.ExchangeDriver, routingKey, rmq.Publishing{
+		MessageID: env.MessageID,
+		Type:      env.Type,
+		Body:      body,
+	}); err != nil {
+		logger.Error("publish_driver_state", "Failed to publish driver state", "", msg.DriverID, err.Error())
+		return fmt.Errorf("failed to publish driver state: %w", err)
 	}
 
-	logger.Info("publish_location_update", "Driver location update published successfully", "", msg.DriverID)
+	logger.Info("publish_driver_state", "Driver state published: "+msg.Status, "", msg.DriverID)
 	return nil
 }
@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -14,43 +13,98 @@ import (
 	"ride-hail-system/internal/common/websocket"
 	"ride-hail-system/internal/driver/handler/dto"
 	"ride-hail-system/internal/driver/model"
-	"ride-hail-system/internal/driver/rmq"
 	model2 "ride-hail-system/internal/ride/model"
 	usermodel "ride-hail-system/internal/user/model"
 	"ride-hail-system/pkg/uuid"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type DriverRepository interface {
 	SetOnline(ctx context.Context, driverID uuid.UUID, lat, lon float64) (model.DriverSession, error)
 	SetOffline(ctx context.Context, driverID uuid.UUID) (model.DriverSession, error)
-	SaveLocation(ctx context.Context, location model.LocationHistory) (model2.Coordinate, error)
-	Start(ctx context.Context, driverID uuid.UUID, rideID uuid.UUID, loc model.Location) (usermodel.DriverStatus, time.Time, error)
-	Complete(ctx context.Context, driverID uuid.UUID, driverEarning float64, location model.Location, distance, duration float64) (time.Time, error)
+	SaveLocation(ctx context.Context, tx pgx.Tx, location model.LocationHistory) (model2.Coordinate, error)
+	Start(ctx context.Context, tx pgx.Tx, driverID uuid.UUID, rideID uuid.UUID, loc model.Location) (usermodel.DriverStatus, time.Time, error)
+	Complete(ctx context.Context, tx pgx.Tx, driverID uuid.UUID, driverEarning float64, location model.Location, distance, duration float64) (time.Time, error)
 	GetRideStatus(ctx context.Context, driverID, rideID uuid.UUID) (model2.RideStatus, error)
 	GetDriverStatus(ctx context.Context, driverID uuid.UUID) (usermodel.DriverStatus, error)
+	GetDriverEligibility(ctx context.Context, driverID uuid.UUID) (verified bool, status usermodel.UserStatus, err error)
 	GetInfo(ctx context.Context, id string) (model.DriverInfo, error)
 	GetPickupLocation(ctx context.Context, rideID string) (float64, float64, error)
 	GetDriverIDByRideID(ctx context.Context, rideID string) (string, error)
+	GetPassengerIDByRideID(ctx context.Context, rideID string) (string, error)
+	BeginTx(ctx context.Context) (pgx.Tx, error)
+}
+
+// MessageBus — операции шины, нужные сервису.
+type MessageBus interface {
+	PublishDriverResponse(ctx context.Context, tx pgx.Tx, msg commonmq.DriverResponseMessage) error
+	PublishLocationUpdate(ctx context.Context, tx pgx.Tx, msg commonmq.LocationUpdateMessage) error
+	ConsumeRideRequests(ctx context.Context, queueName string, handler func(msg commonmq.RideRequestedMessage) error) error
+	ConsumePassengerInfo(ctx context.Context, queueName string, handler func(msg commonmq.PassiNFO) error) error
+	PublishRideStatus(ctx context.Context, tx pgx.Tx, msg commonmq.RideStatusUpdateMessage) error
+	PublishDriverState(ctx context.Context, msg commonmq.DriverStateMessage) error
 }
 
+var (
+	ErrDriverNotVerified = errors.New("driver is not verified")
+	ErrDriverBlocked     = errors.New("driver account is not active")
+)
+
+// ErrBusy возвращается WebSocket-обработчикам, когда очередь входящих
+// сообщений сервиса переполнена; клиент получает ошибку и может повторить.
+var ErrBusy = errors.New("service is busy, try again later")
+
+const ingressBuffer = 256
+
 type DriverService struct {
 	repo      DriverRepository
-	rmqClient *rmq.Client
+	rmqClient MessageBus
 	wsHub     *websocket.Hub
+	responses chan model.DriverResponceWS
+	locations chan commonmq.LocationUpdateMessage
 }
 
-func NewDriverService(repo DriverRepository, rmqClient *rmq.Client, hub *websocket.Hub) *DriverService {
+func NewDriverService(repo DriverRepository, rmqClient MessageBus, hub *websocket.Hub) *DriverService {
 	return &DriverService{
 		repo:      repo,
 		rmqClient: rmqClient,
 		wsHub:     hub,
+		responses: make(chan model.DriverResponceWS, ingressBuffer),
+		locations: make(chan commonmq.LocationUpdateMessage, ingressBuffer),
+	}
+}
+
+// SubmitDriverResponse передаёт ответ водителя из WebSocket в цикл SendToMq.
+func (s *DriverService) SubmitDriverResponse(resp model.DriverResponceWS) error {
+	select {
+	case s.responses <- resp:
+		return nil
+	default:
+		return ErrBusy
+	}
+}
+
+// SubmitLocation передаёт координаты водителя из WebSocket в цикл UpdateLocationWS.
+func (s *DriverService) SubmitLocation(loc commonmq.LocationUpdateMessage) error {
+	select {
+	case s.locations <- loc:
+		return nil
+	default:
+		return ErrBusy
 	}
 }
 
 func (s *DriverService) ListenForRides(ctx context.Context, queueName string) {
-	err := s.rmqClient.ConsumeRideRequests(queueName, func(msg commonmq.RideRequestedMessage) {
+	err := s.rmqClient.ConsumeRideRequests(ctx, queueName, func(msg commonmq.RideRequestedMessage) error {
 		logger.Info("listen_for_rides", "Ride request received", "", msg.RideID)
-		s.wsHub.BroadcastRideOffer(msg)
+		n, err := s.wsHub.PublishMessage(websocket.TopicDrivers, websocket.MsgRideOffer, msg)
+		if err != nil {
+			logger.Error("listen_for_rides", "Failed to encode ride offer", "", msg.RideID, err.Error())
+			return err
+		}
+		logger.Info("listen_for_rides", fmt.Sprintf("Ride offer sent to %d drivers", n), "", msg.RideID)
+		return nil
 	})
 	if err != nil {
 		logger.Error("listen_for_rides", "Failed to start consuming ride requests", "", "", err.Error())
@@ -58,18 +112,21 @@ func (s *DriverService) ListenForRides(ctx context.Context, queueName string) {
 }
 
 func (s *DriverService) ListenForPassengers(ctx context.Context, queueName string) {
-	err := s.rmqClient.ConsumePassengerInfo(queueName, func(msg commonmq.PassiNFO) {
+	err := s.rmqClient.ConsumePassengerInfo(ctx, queueName, func(msg commonmq.PassiNFO) error {
 		logger.Info("listen_for_passengers", fmt.Sprintf("Passenger response received for ride %s", msg.RideID), "", msg.RideID)
 
-		data, _ := json.Marshal(msg)
 		driverID, err := s.repo.GetDriverIDByRideID(ctx, msg.RideID)
 		if err != nil {
 			logger.Error("listen_for_passengers", "Failed to get driver ID by ride ID", "", msg.RideID, err.Error())
-			return
+			return err
 		}
 
-		s.wsHub.SendToClient("driver_"+driverID, data)
+		if _, err := s.wsHub.PublishMessage(websocket.DriverTopic(driverID), websocket.MsgRideDetails, msg); err != nil {
+			logger.Error("listen_for_passengers", "Failed to encode ride details", "", msg.RideID, err.Error())
+			return err
+		}
 		logger.Info("listen_for_passengers", fmt.Sprintf("Sent passenger info to driver %s", driverID), "", msg.RideID)
+		return nil
 	})
 	if err != nil {
 		logger.Error("listen_for_passengers", fmt.Sprintf("Failed to consume queue %s", queueName), "", "", err.Error())
@@ -85,7 +142,7 @@ func (s *DriverService) SendToMq(ctx context.Context) {
 			logger.Warn("send_to_mq", "Stopped listening for driver responses", "", "", "")
 			return
 
-		case resp := <-s.wsHub.DriverResponses:
+		case resp := <-s.responses:
 			logger.Debug("send_to_mq", fmt.Sprintf("Received driver response from WS: %+v", resp), "", resp.RideID)
 
 			if strings.HasPrefix(resp.DriverID, "driver_") {
@@ -101,6 +158,12 @@ func (s *DriverService) SendToMq(ctx context.Context) {
 				logger.Error("send_to_mq", "Driver doesn't available", resp.DriverID, resp.RideID, "driver not available")
 				continue
 			}
+			// Допуск мог быть снят, пока водитель на линии: истёк документ.
+			verified, userStatus, err := s.repo.GetDriverEligibility(ctx, uuid.UUID(resp.DriverID))
+			if err != nil || !verified || userStatus != usermodel.UserActive {
+				logger.Warn("send_to_mq", "Driver is not eligible for rides", resp.DriverID, resp.RideID, "driver not verified or blocked")
+				continue
+			}
 			driverInfo, err := s.repo.GetInfo(ctx, resp.DriverID)
 			if err != nil {
 				logger.Error("send_to_mq", "Failed to get driver info", resp.DriverID, resp.RideID, "Failed to get driver info")
@@ -143,7 +206,9 @@ func (s *DriverService) SendToMq(ctx context.Context) {
 				RespondedAt:      time.Now(),
 			}
 
-			if err := s.rmqClient.PublishDriverResponse(ctx, msg); err != nil {
+			if err := s.publishInTx(ctx, func(tx pgx.Tx) error {
+				return s.rmqClient.PublishDriverResponse(ctx, tx, msg)
+			}); err != nil {
 				logger.Error("send_to_mq", "Failed to send driver response to MQ", resp.DriverID, resp.RideID, err.Error())
 			} else {
 				logger.Info("send_to_mq", "Sent driver response to MQ", resp.DriverID, resp.RideID)
@@ -159,10 +224,12 @@ func (s *DriverService) UpdateLocationWS(ctx context.Context) {
 			logger.Warn("update_location_ws", "Stopped listening for driver location updates", "", "", "")
 			return
 
-		case resp := <-s.wsHub.UpdateLocation:
+		case resp := <-s.locations:
 			logger.Debug("update_location_ws", fmt.Sprintf("Received driver location from WS: %+v", resp), resp.DriverID, "")
 
-			if err := s.rmqClient.PublishLocationUpdate(ctx, resp); err != nil {
+			if err := s.publishInTx(ctx, func(tx pgx.Tx) error {
+				return s.rmqClient.PublishLocationUpdate(ctx, tx, resp)
+			}); err != nil {
 				logger.Error("update_location_ws", "Failed to publish driver location update", resp.DriverID, "", err.Error())
 			} else {
 				logger.Info("update_location_ws", "Published driver location update", resp.DriverID, "")
@@ -171,6 +238,21 @@ func (s *DriverService) UpdateLocationWS(ctx context.Context) {
 	}
 }
 
+// publishInTx записывает сообщения в outbox в отдельной транзакции,
+// когда публикация не сопровождается изменением других данных.
+func (s *DriverService) publishInTx(ctx context.Context, publish func(tx pgx.Tx) error) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := publish(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 func calculateDistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371
 	dLat := (lat2 - lat1) * math.Pi / 180
@@ -202,6 +284,20 @@ func (s *DriverService) GoOnline(ctx context.Context, driverID uuid.UUID, lat, l
 		return model.DriverSession{}, errors.New("longitude out of range")
 	}
 
+	verified, userStatus, err := s.repo.GetDriverEligibility(ctx, driverID)
+	if err != nil {
+		logger.Error("GoOnline", "Failed to get driver eligibility", "", "", err.Error())
+		return model.DriverSession{}, err
+	}
+	if userStatus != usermodel.UserActive {
+		logger.Warn("GoOnline", "Driver account is not active", "", "", fmt.Sprintf("status: %s", userStatus))
+		return model.DriverSession{}, ErrDriverBlocked
+	}
+	if !verified {
+		logger.Warn("GoOnline", "Driver is not verified", "", "", string(driverID))
+		return model.DriverSession{}, ErrDriverNotVerified
+	}
+
 	driverStatus, err := s.repo.GetDriverStatus(ctx, driverID)
 	if err != nil {
 		logger.Error("GoOnline", "Failed to get driver status", "", "", err.Error())
@@ -220,6 +316,7 @@ func (s *DriverService) GoOnline(ctx context.Context, driverID uuid.UUID, lat, l
 	}
 
 	logger.Info("GoOnline", fmt.Sprintf("Driver %s is now ONLINE", driverID), "", "")
+	s.announceDriverState(ctx, string(driverID), usermodel.DriverStatusAvailable, "")
 	return session, nil
 }
 
@@ -244,6 +341,7 @@ func (s *DriverService) GoOffline(ctx context.Context, driverID uuid.UUID) (mode
 	}
 
 	durationHours := time.Since(session.StartedAt).Hours()
+	s.announceDriverState(ctx, string(driverID), usermodel.DriverStatusOffline, "")
 	logger.Info("GoOffline", fmt.Sprintf("Driver %s went offline after %.2f hours", driverID, durationHours), "", "")
 	return session, durationHours, nil
 }
@@ -282,7 +380,14 @@ func (s *DriverService) UpdateLocation(ctx context.Context, location model.Locat
 		return model2.Coordinate{}, errors.New("driver is OFFLINE")
 	}
 
-	coord, err := s.repo.SaveLocation(ctx, location)
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		logger.Error("UpdateLocation", "Failed to begin transaction", "", "", err.Error())
+		return model2.Coordinate{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	coord, err := s.repo.SaveLocation(ctx, tx, location)
 	if err != nil {
 		logger.Error("UpdateLocation", "Failed to save location", "", "", err.Error())
 		return model2.Coordinate{}, err
@@ -295,8 +400,14 @@ func (s *DriverService) UpdateLocation(ctx context.Context, location model.Locat
 		Heading:   location.HeadingDegrees,
 		Timestamp: coord.UpdatedAt.UTC(),
 	}
-	if err := s.rmqClient.PublishLocationUpdate(ctx, msg); err != nil {
-		logger.Warn("UpdateLocation", "Failed to publish driver location", "", "", err.Error())
+	if err := s.rmqClient.PublishLocationUpdate(ctx, tx, msg); err != nil {
+		logger.Error("UpdateLocation", "Failed to enqueue driver location", "", "", err.Error())
+		return model2.Coordinate{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("UpdateLocation", "Failed to commit transaction", "", "", err.Error())
+		return model2.Coordinate{}, err
 	}
 
 	logger.Info("UpdateLocation", fmt.Sprintf("Driver %s location updated successfully", location.DriverID), "", "")
@@ -337,12 +448,36 @@ func (s *DriverService) Start(ctx context.Context, driverID uuid.UUID, rideId uu
 		return dto.StartResponse{}, errors.New("driver is not available")
 	}
 
-	newDStatus, startedAt, err := s.repo.Start(ctx, driverID, rideId, location)
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		logger.Error("Start", "Failed to begin transaction", "", string(rideId), err.Error())
+		return dto.StartResponse{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	newDStatus, startedAt, err := s.repo.Start(ctx, tx, driverID, rideId, location)
 	if err != nil {
 		logger.Error("Start", "Failed to start ride", "", string(rideId), err.Error())
 		return dto.StartResponse{}, err
 	}
 
+	rideStatus := commonmq.RideStatusUpdateMessage{
+		RideID:    string(rideId),
+		Status:    string(model2.RideInProgress),
+		DriverID:  string(driverID),
+		Timestamp: startedAt,
+		Message:   "Ride started",
+	}
+	if err := s.rmqClient.PublishRideStatus(ctx, tx, rideStatus); err != nil {
+		logger.Error("Start", "Failed to enqueue ride status", "", string(rideId), err.Error())
+		return dto.StartResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("Start", "Failed to commit transaction", "", string(rideId), err.Error())
+		return dto.StartResponse{}, err
+	}
+
 	resp := dto.StartResponse{
 		RideID:    string(rideId),
 		Status:    newDStatus,
@@ -351,6 +486,8 @@ func (s *DriverService) Start(ctx context.Context, driverID uuid.UUID, rideId uu
 	}
 
 	logger.Info("Start", fmt.Sprintf("Ride %s started by driver %s", rideId, driverID), "", string(rideId))
+	s.announceDriverState(ctx, string(driverID), newDStatus, string(rideId))
+	s.notifyPassenger(ctx, rideStatus)
 	return resp, nil
 }
 
@@ -407,12 +544,36 @@ func (s *DriverService) Complete(ctx context.Context, driverID uuid.UUID, req dt
 		Latitude:  req.FinalLocation.Latitude,
 		Longitude: req.FinalLocation.Longitude,
 	}
-	completedAt, err := s.repo.Complete(ctx, driverID, driverEarnings, location, req.ActualDistanceKm, req.ActualDurationMins)
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		logger.Error("Complete", "Failed to begin transaction", "", string(req.RideID), err.Error())
+		return dto.CompleteResponse{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	completedAt, err := s.repo.Complete(ctx, tx, driverID, driverEarnings, location, req.ActualDistanceKm, req.ActualDurationMins)
 	if err != nil {
 		logger.Error("Complete", "Failed to complete ride", "", string(req.RideID), err.Error())
 		return dto.CompleteResponse{}, err
 	}
 
+	rideStatus := commonmq.RideStatusUpdateMessage{
+		RideID:    string(req.RideID),
+		Status:    string(model2.RideCompleted),
+		DriverID:  string(driverID),
+		Timestamp: completedAt,
+		Message:   "Ride completed",
+	}
+	if err := s.rmqClient.PublishRideStatus(ctx, tx, rideStatus); err != nil {
+		logger.Error("Complete", "Failed to enqueue ride status", "", string(req.RideID), err.Error())
+		return dto.CompleteResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("Complete", "Failed to commit transaction", "", string(req.RideID), err.Error())
+		return dto.CompleteResponse{}, err
+	}
+
 	resp := dto.CompleteResponse{
 		RideID:        string(req.RideID),
 		Status:        usermodel.DriverStatusAvailable,
@@ -422,9 +583,37 @@ func (s *DriverService) Complete(ctx context.Context, driverID uuid.UUID, req dt
 	}
 
 	logger.Info("Complete", fmt.Sprintf("Ride %s completed by driver %s, earnings %.2f", req.RideID, driverID, driverEarnings), "", string(req.RideID))
+	s.announceDriverState(ctx, string(driverID), usermodel.DriverStatusAvailable, string(req.RideID))
+	s.notifyPassenger(ctx, rideStatus)
 	return resp, nil
 }
 
+// notifyPassenger отправляет смену статуса поездки пассажиру.
+func (s *DriverService) notifyPassenger(ctx context.Context, msg commonmq.RideStatusUpdateMessage) {
+	passengerID, err := s.repo.GetPassengerIDByRideID(ctx, msg.RideID)
+	if err != nil {
+		logger.Warn("notify_passenger", "Failed to get passenger ID by ride ID", "", msg.RideID, err.Error())
+		return
+	}
+	msg.Type = websocket.MsgRideStatusUpdate
+	if _, err := s.wsHub.PublishMessage(websocket.PassengerTopic(passengerID), websocket.MsgRideStatusUpdate, msg); err != nil {
+		logger.Error("notify_passenger", "Failed to encode ride status update", "", msg.RideID, err.Error())
+	}
+}
+
+// announceDriverState публикует новый статус водителя для ленты администратора.
+func (s *DriverService) announceDriverState(ctx context.Context, driverID string, status usermodel.DriverStatus, rideID string) {
+	err := s.rmqClient.PublishDriverState(ctx, commonmq.DriverStateMessage{
+		DriverID:  driverID,
+		Status:    string(status),
+		RideID:    rideID,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Warn("announce_driver_state", "Failed to publish driver state", "", rideID, err.Error())
+	}
+}
+
 func (s *DriverService) GetDriverInfo(ctx context.Context, driverID string) (model.DriverInfo, error) {
 	logger.Info("GetDriverInfo", fmt.Sprintf("Fetching info for driver %s", driverID), "", "")
 	response, err := s.repo.GetInfo(ctx, driverID)
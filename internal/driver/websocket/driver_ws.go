@@ -2,12 +2,15 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/logger"
+	commonmq "ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/driver/model"
 	"ride-hail-system/internal/driver/service"
-	"ride-hail-system/internal/user/jwt"
 
 	commonws "ride-hail-system/internal/common/websocket"
 
@@ -18,7 +21,32 @@ var Upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-func DriverWSHandler(w http.ResponseWriter, r *http.Request, hub *commonws.Hub, jwtManager *jwt.Manager, svc *service.DriverService) {
+// RegisterHandlers подключает обработчики сообщений водителей к хабу.
+func RegisterHandlers(hub *commonws.Hub, svc *service.DriverService) {
+	hub.Handle(commonws.RoleDriver, commonws.MsgRideResponse, func(ctx context.Context, c *commonws.Client, msg commonws.Message) error {
+		var resp model.DriverResponceWS
+		if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+			return err
+		}
+		resp.DriverID = c.UserID
+		logger.Info("driver_response", "Received driver response", "", resp.DriverID)
+		return svc.SubmitDriverResponse(resp)
+	})
+
+	hub.Handle(commonws.RoleDriver, commonws.MsgLocationUpdate, func(ctx context.Context, c *commonws.Client, msg commonws.Message) error {
+		var loc commonmq.LocationUpdateMessage
+		if err := json.Unmarshal(msg.Payload, &loc); err != nil {
+			return err
+		}
+		loc.DriverID = c.UserID
+		logger.Info("driver_location", "Received location update", loc.RideID, loc.DriverID)
+		return svc.SubmitLocation(loc)
+	})
+	// Старые клиенты присылают координаты без поля type.
+	hub.Alias(commonws.RoleDriver, "", commonws.MsgLocationUpdate)
+}
+
+func DriverWSHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, hub *commonws.Hub, authn *auth.Authenticator) {
 	conn, err := Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Error("driver_ws_upgrade", "WebSocket upgrade failed", "", "", err.Error())
@@ -29,13 +57,7 @@ func DriverWSHandler(w http.ResponseWriter, r *http.Request, hub *commonws.Hub,
 		conn.Close()
 	}()
 
-	done := make(chan struct{})
-
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	conn.SetPongHandler(func(appData string) error {
-		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		return nil
-	})
 
 	var authMsg struct {
 		Type  string `json:"type"`
@@ -48,53 +70,27 @@ func DriverWSHandler(w http.ResponseWriter, r *http.Request, hub *commonws.Hub,
 		return
 	}
 
-	claims, err := jwtManager.ValidateToken(authMsg.Token)
+	claims, err := authn.Verify(authMsg.Token)
 	if err != nil {
 		logger.Warn("driver_ws_token", "invalid token for driver", "", "", err.Error())
 		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "invalid token"))
 		return
 	}
-
-	client := &commonws.Client{
-		ID:   "driver_" + claims.UserID,
-		Conn: conn,
-		Send: make(chan []byte, 256),
+	if err := authn.Permit(claims, auth.PermDriverOps); err != nil {
+		logger.Warn("driver_ws_token", "token is not allowed on driver socket", "", claims.UserID, err.Error())
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "forbidden"))
+		return
 	}
-	hub.Register <- client
-	logger.Info("driver_ws_connect", "driver connected", claims.UserID, "")
 
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-done:
-				return
-			case <-ticker.C:
-				err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(10*time.Second))
-				if err != nil {
-					logger.Warn("driver_ws_ping", "ping failed", claims.UserID, "", err.Error())
-					return
-				}
-			}
-		}
-	}()
-
-	go func() {
-		for msg := range client.Send {
-			if err := client.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-				logger.Error("driver_ws_send", "failed to send message to driver", claims.UserID, "", err.Error())
-				break
-			}
-		}
-	}()
+	client := hub.NewClient(commonws.RoleDriver, claims.UserID, conn)
+	client.RemoteAddr = r.RemoteAddr
+	client.UserAgent = r.UserAgent()
+	hub.Register(client)
+	logger.Info("driver_ws_connect", "driver connected", claims.UserID, "")
 
-	go hub.ListenDriverMessages(client)
-	go svc.SendToMq(context.Background())
-	go svc.UpdateLocationWS(context.Background())
+	go client.WritePump()
 
-	<-done
-	hub.Unregister <- client
-	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	client.ReadPump(ctx)
+	hub.Unregister(client)
 	logger.Info("driver_ws_disconnect", "driver disconnected", claims.UserID, "")
 }
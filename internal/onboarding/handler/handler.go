@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/onboarding/blobstore"
+	"ride-hail-system/internal/onboarding/model"
+	"ride-hail-system/internal/onboarding/service"
+)
+
+type OnboardingHandler struct {
+	service *service.OnboardingService
+}
+
+func NewOnboardingHandler(s *service.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{service: s}
+}
+
+// Upload принимает multipart-форму с полями kind, expires_at (YYYY-MM-DD) и file.
+func (h *OnboardingHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	const action = "UploadDocument"
+	driverID := r.PathValue("driver_id")
+
+	// Запас на поля формы сверх самого файла.
+	r.Body = http.MaxBytesReader(w, r.Body, h.service.MaxBytes()+64<<10)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, service.ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	expiresAt, err := time.Parse(time.DateOnly, r.FormValue("expires_at"))
+	if err != nil {
+		http.Error(w, "expires_at must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	doc, err := h.service.Upload(r.Context(), driverID, model.DocumentKind(r.FormValue("kind")), expiresAt, file)
+	if err != nil {
+		writeError(w, action, driverID, err)
+		return
+	}
+	writeJSON(w, action, http.StatusCreated, doc)
+}
+
+// GetStatus показывает водителю его документы и чего не хватает для допуска.
+func (h *OnboardingHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	const action = "GetOnboardingStatus"
+	driverID := r.PathValue("driver_id")
+
+	status, err := h.service.Status(r.Context(), driverID)
+	if err != nil {
+		writeError(w, action, driverID, err)
+		return
+	}
+	writeJSON(w, action, http.StatusOK, status)
+}
+
+func (h *OnboardingHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	const action = "GetPendingDocuments"
+
+	docs, err := h.service.Pending(r.Context())
+	if err != nil {
+		writeError(w, action, "", err)
+		return
+	}
+	writeJSON(w, action, http.StatusOK, docs)
+}
+
+func (h *OnboardingHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	const action = "GetDocumentFile"
+	documentID := r.PathValue("document_id")
+
+	doc, file, err := h.service.OpenFile(r.Context(), documentID)
+	if err != nil {
+		writeError(w, action, "", err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.SizeBytes, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := io.Copy(w, file); err != nil {
+		logger.Warn(action, "Failed to stream document", "", doc.DriverID, err.Error())
+	}
+}
+
+func (h *OnboardingHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	const action = "ApproveDocument"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	doc, err := h.service.Approve(r.Context(), r.PathValue("document_id"), claims.UserID)
+	if err != nil {
+		writeError(w, action, "", err)
+		return
+	}
+	writeJSON(w, action, http.StatusOK, doc)
+}
+
+type rejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *OnboardingHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	const action = "RejectDocument"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req rejectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	doc, err := h.service.Reject(r.Context(), r.PathValue("document_id"), claims.UserID, req.Reason)
+	if err != nil {
+		writeError(w, action, "", err)
+		return
+	}
+	writeJSON(w, action, http.StatusOK, doc)
+}
+
+func writeError(w http.ResponseWriter, action, driverID string, err error) {
+	switch {
+	case errors.Is(err, service.ErrDriverNotFound), errors.Is(err, service.ErrDocumentNotFound),
+		errors.Is(err, blobstore.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrAlreadyReviewed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrFileTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, service.ErrUnsupportedFile):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, service.ErrUnknownKind), errors.Is(err, service.ErrAlreadyExpired),
+		errors.Is(err, service.ErrEmptyFile), errors.Is(err, service.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Error(action, "Onboarding request failed", "", driverID, err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, action string, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error(action, "Failed to encode response", "", "", err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+type DocumentKind string
+
+const (
+	KindLicense             DocumentKind = "license"
+	KindInsurance           DocumentKind = "insurance"
+	KindVehicleRegistration DocumentKind = "vehicle_registration"
+)
+
+// RequiredKinds — документы, без которых водитель не может выйти на линию.
+var RequiredKinds = []DocumentKind{KindLicense, KindInsurance, KindVehicleRegistration}
+
+type DocumentStatus string
+
+const (
+	DocumentPending  DocumentStatus = "PENDING"
+	DocumentApproved DocumentStatus = "APPROVED"
+	DocumentRejected DocumentStatus = "REJECTED"
+	DocumentExpired  DocumentStatus = "EXPIRED"
+)
+
+// Document — загруженный водителем документ. Сам файл хранится в блоб-хранилище.
+type Document struct {
+	ID           string         `json:"document_id"`
+	DriverID     string         `json:"driver_id"`
+	Kind         DocumentKind   `json:"kind"`
+	Status       DocumentStatus `json:"status"`
+	BlobKey      string         `json:"-"`
+	ContentType  string         `json:"content_type"`
+	SizeBytes    int64          `json:"size_bytes"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+	UploadedAt   time.Time      `json:"uploaded_at"`
+	ReviewedAt   *time.Time     `json:"reviewed_at,omitempty"`
+	ReviewedBy   *string        `json:"reviewed_by,omitempty"`
+	RejectReason *string        `json:"reject_reason,omitempty"`
+}
+
+// Onboarding — состояние проверки водителя: какие документы ещё нужны.
+type Onboarding struct {
+	DriverID  string         `json:"driver_id"`
+	Verified  bool           `json:"is_verified"`
+	Missing   []DocumentKind `json:"missing"`
+	Documents []Document     `json:"documents"`
+}
+
+// Suspension — водитель, потерявший допуск из-за истёкшего документа.
+type Suspension struct {
+	DriverID    string
+	WentOffline bool
+}
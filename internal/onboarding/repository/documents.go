@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ride-hail-system/internal/onboarding/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrDriverNotFound   = errors.New("driver not found")
+)
+
+type DocumentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDocumentRepository(db *pgxpool.Pool) *DocumentRepository {
+	return &DocumentRepository{db: db}
+}
+
+const documentColumns = `id::text, driver_id::text, kind, status, blob_key, content_type, size_bytes,
+		expires_at, uploaded_at, reviewed_at, reviewed_by::text, reject_reason`
+
+func scanDocument(row pgx.Row) (model.Document, error) {
+	var d model.Document
+	err := row.Scan(&d.ID, &d.DriverID, &d.Kind, &d.Status, &d.BlobKey, &d.ContentType, &d.SizeBytes,
+		&d.ExpiresAt, &d.UploadedAt, &d.ReviewedAt, &d.ReviewedBy, &d.RejectReason)
+	return d, err
+}
+
+func (r *DocumentRepository) DriverExists(ctx context.Context, driverID string) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM drivers WHERE id = $1)`, driverID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check driver: %w", err)
+	}
+	if !exists {
+		return ErrDriverNotFound
+	}
+	return nil
+}
+
+func (r *DocumentRepository) InsertDocument(ctx context.Context, d model.Document) (model.Document, error) {
+	doc, err := scanDocument(r.db.QueryRow(ctx, `
+		INSERT INTO driver_documents (driver_id, kind, blob_key, content_type, size_bytes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+documentColumns,
+		d.DriverID, d.Kind, d.BlobKey, d.ContentType, d.SizeBytes, d.ExpiresAt))
+	if err != nil {
+		return model.Document{}, fmt.Errorf("failed to insert document: %w", err)
+	}
+	return doc, nil
+}
+
+func (r *DocumentRepository) GetDocument(ctx context.Context, id string) (model.Document, error) {
+	doc, err := scanDocument(r.db.QueryRow(ctx, `SELECT `+documentColumns+` FROM driver_documents WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Document{}, ErrDocumentNotFound
+		}
+		return model.Document{}, fmt.Errorf("failed to get document: %w", err)
+	}
+	return doc, nil
+}
+
+// ListDriverDocuments возвращает документы водителя, новые первыми.
+func (r *DocumentRepository) ListDriverDocuments(ctx context.Context, driverID string) ([]model.Document, error) {
+	return r.list(ctx, `SELECT `+documentColumns+` FROM driver_documents
+		WHERE driver_id = $1 ORDER BY uploaded_at DESC`, driverID)
+}
+
+// ListPending — очередь проверки: самые давние загрузки первыми.
+func (r *DocumentRepository) ListPending(ctx context.Context, limit int) ([]model.Document, error) {
+	return r.list(ctx, `SELECT `+documentColumns+` FROM driver_documents
+		WHERE status = 'PENDING' ORDER BY uploaded_at LIMIT $1`, limit)
+}
+
+func (r *DocumentRepository) list(ctx context.Context, query string, args ...any) ([]model.Document, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make([]model.Document, 0)
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// ReviewDocument переводит документ из PENDING в status.
+func (r *DocumentRepository) ReviewDocument(ctx context.Context, id string, status model.DocumentStatus, reviewerID string, reason *string) (model.Document, error) {
+	doc, err := scanDocument(r.db.QueryRow(ctx, `
+		UPDATE driver_documents
+		SET status = $2, reviewed_at = now(), reviewed_by = $3, reject_reason = $4
+		WHERE id = $1 AND status = 'PENDING'
+		RETURNING `+documentColumns,
+		id, status, reviewerID, reason))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Document{}, ErrDocumentNotFound
+		}
+		return model.Document{}, fmt.Errorf("failed to review document: %w", err)
+	}
+	return doc, nil
+}
+
+// RefreshVerification пересчитывает допуск водителя по его документам.
+func (r *DocumentRepository) RefreshVerification(ctx context.Context, driverID string, required []model.DocumentKind) (model.Suspension, bool, error) {
+	kinds := make([]string, len(required))
+	for i, k := range required {
+		kinds[i] = string(k)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return model.Suspension{}, false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var verified bool
+	err = tx.QueryRow(ctx, `
+		UPDATE drivers d
+		SET is_verified = (
+			SELECT count(DISTINCT kind) = cardinality($2::text[])
+			FROM driver_documents
+			WHERE driver_id = d.id AND status = 'APPROVED' AND expires_at > now() AND kind = ANY($2)
+		), updated_at = now()
+		WHERE d.id = $1
+		RETURNING is_verified
+	`, driverID, kinds).Scan(&verified)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Suspension{}, false, ErrDriverNotFound
+		}
+		return model.Suspension{}, false, fmt.Errorf("failed to update driver verification: %w", err)
+	}
+
+	suspension := model.Suspension{DriverID: driverID}
+	if !verified {
+		tag, err := tx.Exec(ctx, `
+			UPDATE drivers SET status = 'OFFLINE', updated_at = now()
+			WHERE id = $1 AND status = 'AVAILABLE'
+		`, driverID)
+		if err != nil {
+			return model.Suspension{}, false, fmt.Errorf("failed to take driver offline: %w", err)
+		}
+		if suspension.WentOffline = tag.RowsAffected() > 0; suspension.WentOffline {
+			if _, err := tx.Exec(ctx, `
+				UPDATE driver_sessions SET ended_at = now()
+				WHERE driver_id = $1 AND ended_at IS NULL
+			`, driverID); err != nil {
+				return model.Suspension{}, false, fmt.Errorf("failed to close driver session: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Suspension{}, false, fmt.Errorf("failed to commit verification: %w", err)
+	}
+	return suspension, verified, nil
+}
+
+// ExpireDocuments помечает истёкшими одобренные документы с прошедшим сроком
+// и возвращает затронутых водителей.
+func (r *DocumentRepository) ExpireDocuments(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE driver_documents SET status = 'EXPIRED'
+		WHERE status = 'APPROVED' AND expires_at <= now()
+		RETURNING driver_id::text
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire documents: %w", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]struct{})
+	var drivers []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expired document: %w", err)
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			drivers = append(drivers, id)
+		}
+	}
+	return drivers, rows.Err()
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/onboarding/model"
+	"ride-hail-system/internal/onboarding/repository"
+)
+
+type DocumentRepository interface {
+	DriverExists(ctx context.Context, driverID string) error
+	InsertDocument(ctx context.Context, d model.Document) (model.Document, error)
+	GetDocument(ctx context.Context, id string) (model.Document, error)
+	ListDriverDocuments(ctx context.Context, driverID string) ([]model.Document, error)
+	ListPending(ctx context.Context, limit int) ([]model.Document, error)
+	ReviewDocument(ctx context.Context, id string, status model.DocumentStatus, reviewerID string, reason *string) (model.Document, error)
+	RefreshVerification(ctx context.Context, driverID string, required []model.DocumentKind) (model.Suspension, bool, error)
+	ExpireDocuments(ctx context.Context) ([]string, error)
+}
+
+// BlobStore хранит файлы документов.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	ErrDriverNotFound   = repository.ErrDriverNotFound
+	ErrDocumentNotFound = repository.ErrDocumentNotFound
+	ErrUnknownKind      = errors.New("kind must be license, insurance or vehicle_registration")
+	ErrAlreadyExpired   = errors.New("expires_at must be in the future")
+	ErrEmptyFile        = errors.New("file is empty")
+	ErrFileTooLarge     = errors.New("file is too large")
+	ErrUnsupportedFile  = errors.New("file must be a PDF, JPEG or PNG")
+	ErrReasonRequired   = errors.New("reject reason is required")
+	ErrAlreadyReviewed  = errors.New("document has already been reviewed")
+)
+
+// allowedTypes — форматы, которые принимаются на проверку.
+var allowedTypes = map[string]string{
+	"application/pdf": "pdf",
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+}
+
+const pendingPageSize = 100
+
+type OnboardingService struct {
+	repo     DocumentRepository
+	blobs    BlobStore
+	maxBytes int64
+}
+
+func NewOnboardingService(repo DocumentRepository, blobs BlobStore, maxBytes int64) *OnboardingService {
+	return &OnboardingService{repo: repo, blobs: blobs, maxBytes: maxBytes}
+}
+
+func (s *OnboardingService) MaxBytes() int64 { return s.maxBytes }
+
+// Upload сохраняет документ водителя и ставит его в очередь проверки.
+func (s *OnboardingService) Upload(ctx context.Context, driverID string, kind model.DocumentKind, expiresAt time.Time, file io.Reader) (model.Document, error) {
+	if !knownKind(kind) {
+		return model.Document{}, ErrUnknownKind
+	}
+	if !expiresAt.After(time.Now()) {
+		return model.Document{}, ErrAlreadyExpired
+	}
+	if err := s.repo.DriverExists(ctx, driverID); err != nil {
+		return model.Document{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxBytes+1))
+	if err != nil {
+		return model.Document{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) == 0 {
+		return model.Document{}, ErrEmptyFile
+	}
+	if int64(len(data)) > s.maxBytes {
+		return model.Document{}, ErrFileTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return model.Document{}, ErrUnsupportedFile
+	}
+
+	key, err := blobKey(driverID, kind, ext)
+	if err != nil {
+		return model.Document{}, err
+	}
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return model.Document{}, err
+	}
+
+	doc, err := s.repo.InsertDocument(ctx, model.Document{
+		DriverID:    driverID,
+		Kind:        kind,
+		BlobKey:     key,
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		if delErr := s.blobs.Delete(ctx, key); delErr != nil {
+			logger.Warn("upload_document", "Failed to delete orphaned blob", "", driverID, delErr.Error())
+		}
+		return model.Document{}, err
+	}
+
+	logger.Info("upload_document", fmt.Sprintf("Driver uploaded %s, awaiting review", kind), "", driverID)
+	return doc, nil
+}
+
+// Status возвращает документы водителя и виды, которых не хватает для допуска.
+func (s *OnboardingService) Status(ctx context.Context, driverID string) (model.Onboarding, error) {
+	if err := s.repo.DriverExists(ctx, driverID); err != nil {
+		return model.Onboarding{}, err
+	}
+	docs, err := s.repo.ListDriverDocuments(ctx, driverID)
+	if err != nil {
+		return model.Onboarding{}, err
+	}
+
+	valid := make(map[model.DocumentKind]bool)
+	now := time.Now()
+	for _, d := range docs {
+		if d.Status == model.DocumentApproved && d.ExpiresAt.After(now) {
+			valid[d.Kind] = true
+		}
+	}
+	missing := make([]model.DocumentKind, 0)
+	for _, k := range model.RequiredKinds {
+		if !valid[k] {
+			missing = append(missing, k)
+		}
+	}
+
+	return model.Onboarding{DriverID: driverID, Verified: len(missing) == 0, Missing: missing, Documents: docs}, nil
+}
+
+// Pending — очередь документов на проверку.
+func (s *OnboardingService) Pending(ctx context.Context) ([]model.Document, error) {
+	return s.repo.ListPending(ctx, pendingPageSize)
+}
+
+// OpenFile отдаёт файл документа администратору для проверки.
+func (s *OnboardingService) OpenFile(ctx context.Context, documentID string) (model.Document, io.ReadCloser, error) {
+	doc, err := s.repo.GetDocument(ctx, documentID)
+	if err != nil {
+		return model.Document{}, nil, err
+	}
+	r, err := s.blobs.Open(ctx, doc.BlobKey)
+	if err != nil {
+		return model.Document{}, nil, err
+	}
+	return doc, r, nil
+}
+
+// Approve одобряет документ и пересчитывает допуск водителя.
+func (s *OnboardingService) Approve(ctx context.Context, documentID, reviewerID string) (model.Document, error) {
+	doc, err := s.review(ctx, documentID, model.DocumentApproved, reviewerID, nil)
+	if err != nil {
+		return model.Document{}, err
+	}
+	_, verified, err := s.repo.RefreshVerification(ctx, doc.DriverID, model.RequiredKinds)
+	if err != nil {
+		return model.Document{}, err
+	}
+	if verified {
+		logger.Info("approve_document", "Driver is verified", "", doc.DriverID)
+	}
+	return doc, nil
+}
+
+// Reject отклоняет документ; причина показывается водителю.
+func (s *OnboardingService) Reject(ctx context.Context, documentID, reviewerID, reason string) (model.Document, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return model.Document{}, ErrReasonRequired
+	}
+	return s.review(ctx, documentID, model.DocumentRejected, reviewerID, &reason)
+}
+
+func (s *OnboardingService) review(ctx context.Context, documentID string, status model.DocumentStatus, reviewerID string, reason *string) (model.Document, error) {
+	doc, err := s.repo.ReviewDocument(ctx, documentID, status, reviewerID, reason)
+	if errors.Is(err, repository.ErrDocumentNotFound) {
+		// Отличаем повторную проверку от несуществующего документа.
+		if _, getErr := s.repo.GetDocument(ctx, documentID); getErr == nil {
+			return model.Document{}, ErrAlreadyReviewed
+		}
+		return model.Document{}, ErrDocumentNotFound
+	}
+	if err != nil {
+		return model.Document{}, err
+	}
+	logger.Info("review_document", fmt.Sprintf("Document %s %s by %s", doc.Kind, status, reviewerID), "", doc.DriverID)
+	return doc, nil
+}
+
+// RunExpiry периодически помечает истёкшие документы и отстраняет водителей,
+// у которых не осталось действующего документа нужного вида.
+func (s *OnboardingService) RunExpiry(ctx context.Context, interval time.Duration) {
+	s.expire(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expire(ctx)
+		}
+	}
+}
+
+func (s *OnboardingService) expire(ctx context.Context) {
+	drivers, err := s.repo.ExpireDocuments(ctx)
+	if err != nil {
+		logger.Warn("document_expiry", "Failed to expire documents", "", "", err.Error())
+		return
+	}
+	for _, driverID := range drivers {
+		suspension, verified, err := s.repo.RefreshVerification(ctx, driverID, model.RequiredKinds)
+		if err != nil {
+			logger.Warn("document_expiry", "Failed to refresh driver verification", "", driverID, err.Error())
+			continue
+		}
+		if !verified {
+			logger.Warn("document_expiry", "Driver suspended: document expired", "", driverID,
+				fmt.Sprintf("went offline: %t", suspension.WentOffline))
+		}
+	}
+}
+
+func knownKind(kind model.DocumentKind) bool {
+	for _, k := range model.RequiredKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func blobKey(driverID string, kind model.DocumentKind, ext string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("drivers/%s/%s-%s.%s", driverID, kind, hex.EncodeToString(buf), ext), nil
+}
@@ -5,33 +5,26 @@ import (
 	"fmt"
 	"net/http"
 
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/ride/handler/dto"
 	"ride-hail-system/internal/ride/service"
-	"ride-hail-system/internal/user/jwt"
-
-	usermodel "ride-hail-system/internal/user/model"
 )
 
+// RideHandler — обработчики поездок.
 type RideHandler struct {
 	RideService *service.RideService
-	jwtManager  *jwt.Manager
 }
 
-func NewRideHandler(service *service.RideService, manager *jwt.Manager) *RideHandler {
-	return &RideHandler{RideService: service, jwtManager: manager}
+func NewRideHandler(service *service.RideService) *RideHandler {
+	return &RideHandler{RideService: service}
 }
 
 func (h *RideHandler) CreateRide(w http.ResponseWriter, r *http.Request) {
 	const action = "CreateRide"
 	requestID := r.Header.Get("X-Request-ID") // если есть requestID из заголовка
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if claims.Role != string(usermodel.RolePassenger) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
+	claims, _ := auth.ClaimsFrom(r.Context())
 
 	var req dto.RideRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -39,6 +32,8 @@ func (h *RideHandler) CreateRide(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid JSON", http.StatusBadRequest)
 		return
 	}
+	// Пассажир заказывает поездку только для себя.
+	req.PassengerID = claims.UserID
 
 	ride, pickup, destination, err := dto.MapRideRequestToEntities(req)
 	if err != nil {
@@ -77,12 +72,7 @@ func (h *RideHandler) CancelRide(w http.ResponseWriter, r *http.Request) {
 	const action = "CancelRide"
 	requestID := r.Header.Get("X-Request-ID")
 
-	claims, err := h.jwtManager.ExtractClaims(w, r)
-
-	if claims.Role != string(usermodel.RolePassenger) {
-		http.Error(w, "forbidden: not authorized", http.StatusUnauthorized)
-		return
-	}
+	claims, _ := auth.ClaimsFrom(r.Context())
 
 	rideID := r.PathValue("ride_id")
 	if rideID == "" {
@@ -91,6 +81,12 @@ func (h *RideHandler) CancelRide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	passengerID, err := h.RideService.RidePassenger(r.Context(), rideID)
+	if err != nil || passengerID != claims.UserID {
+		http.Error(w, "ride not found", http.StatusNotFound)
+		return
+	}
+
 	var req dto.CancelRideRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error(action, "invalid JSON in request body", requestID, rideID, err.Error())
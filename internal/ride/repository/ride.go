@@ -11,13 +11,14 @@ import (
 	"ride-hail-system/pkg/uuid"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type RideRepository struct {
-	DB *pgx.Conn
+	DB *pgxpool.Pool
 }
 
-func NewRideRepository(database *pgx.Conn) *RideRepository {
+func NewRideRepository(database *pgxpool.Pool) *RideRepository {
 	return &RideRepository{DB: database}
 }
 
@@ -71,13 +72,7 @@ func (r *RideRepository) GetPassengerIDByRideID(ctx context.Context, rideID stri
 	return passengerID, nil
 }
 
-func (r *RideRepository) UpdateRideStatusMatched(ctx context.Context, rideID string, driverID string) error {
-	tx, err := r.DB.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
+func (r *RideRepository) UpdateRideStatusMatched(ctx context.Context, tx pgx.Tx, rideID string, driverID string) error {
 	query := `
 		UPDATE rides
 		SET 
@@ -88,7 +83,7 @@ func (r *RideRepository) UpdateRideStatusMatched(ctx context.Context, rideID str
 		WHERE id = $3;
 	`
 
-	_, err = tx.Exec(ctx, query, driverID, time.Now().UTC(), rideID)
+	_, err := tx.Exec(ctx, query, driverID, time.Now().UTC(), rideID)
 	if err != nil {
 		return err
 	}
@@ -102,10 +97,6 @@ func (r *RideRepository) UpdateRideStatusMatched(ctx context.Context, rideID str
 	if err != nil {
 		return fmt.Errorf("failed to insert ride event: %w", err)
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
 	return nil
 }
 
@@ -203,13 +194,7 @@ type CancelRideResponse struct {
 	Message     string `json:"message"`
 }
 
-func (r *RideRepository) CancelRide(ctx context.Context, rideID, reason string) (*CancelRideResponse, error) {
-	tx, err := r.DB.Begin(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
+func (r *RideRepository) CancelRide(ctx context.Context, tx pgx.Tx, rideID, reason string) (*CancelRideResponse, error) {
 	query := `
 		UPDATE rides
 		SET status = 'CANCELLED', cancelled_at = NOW(), cancellation_reason = $1, updated_at = NOW()
@@ -218,7 +203,7 @@ func (r *RideRepository) CancelRide(ctx context.Context, rideID, reason string)
 	`
 
 	var cancelledAt time.Time
-	err = tx.QueryRow(ctx, query, reason, rideID).Scan(&cancelledAt)
+	err := tx.QueryRow(ctx, query, reason, rideID).Scan(&cancelledAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("ride not found or cannot be cancelled")
@@ -243,10 +228,6 @@ func (r *RideRepository) CancelRide(ctx context.Context, rideID, reason string)
 		return nil, fmt.Errorf("failed to insert cancellation event: %w", err)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return &CancelRideResponse{
 		RideID:      rideID,
 		Status:      "CANCELLED",
@@ -2,57 +2,39 @@ package rmq
 
 import (
 	"fmt"
+	"io"
 
 	"ride-hail-system/internal/common/logger"
-
-	amqp "github.com/rabbitmq/amqp091-go"
+	"ride-hail-system/internal/common/outbox"
+	"ride-hail-system/internal/common/rmq"
 )
 
 type Client struct {
-	Conn     *amqp.Connection
-	Channel  *amqp.Channel
+	Bus      rmq.Bus
 	Exchange string
+	Outbox   outbox.Enqueuer
+	Options  rmq.ConsumerOptions
 }
 
-func NewClient(rmqURL, exchange string) (*Client, error) {
-	conn, err := amqp.Dial(rmqURL)
-	if err != nil {
-		logger.Error("rmq_connect", "failed to connect to RabbitMQ", "", "", err.Error())
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
-	}
-	logger.Info("rmq_connect", "connected to RabbitMQ", "", "")
-
-	ch, err := conn.Channel()
-	if err != nil {
-		logger.Error("rmq_channel", "failed to open channel", "", "", err.Error())
-		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
-	}
-	logger.Info("rmq_channel", "channel opened successfully", "", "")
-
+// NewClient собирает клиент поверх любой реализации шины: AMQPBus в проде, MemoryBus в тестах.
+func NewClient(bus rmq.Bus, exchange string, enqueuer outbox.Enqueuer, opts rmq.ConsumerOptions) *Client {
 	return &Client{
-		Conn:     conn,
-		Channel:  ch,
+		Bus:      bus,
 		Exchange: exchange,
-	}, nil
+		Outbox:   enqueuer,
+		Options:  opts,
+	}
 }
 
 func (c *Client) Close() error {
-	if c.Channel != nil {
-		if err := c.Channel.Close(); err != nil {
-			logger.Warn("rmq_close_channel", "failed to close channel", "", "", err.Error())
-			return fmt.Errorf("failed to close channel: %w", err)
-		}
-		logger.Info("rmq_close_channel", "channel closed", "", "")
+	closer, ok := c.Bus.(io.Closer)
+	if !ok {
+		return nil
 	}
-
-	if c.Conn != nil {
-		if err := c.Conn.Close(); err != nil {
-			logger.Warn("rmq_close_connection", "failed to close connection", "", "", err.Error())
-			return fmt.Errorf("failed to close connection: %w", err)
-		}
-		logger.Info("rmq_close_connection", "connection closed", "", "")
+	if err := closer.Close(); err != nil {
+		logger.Warn("rmq_close_bus", "failed to close bus", "", "", err.Error())
+		return fmt.Errorf("failed to close bus: %w", err)
 	}
-
+	logger.Info("rmq_close_bus", "bus closed", "", "")
 	return nil
 }
@@ -2,139 +2,59 @@ package rmq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/common/rmq"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/jackc/pgx/v5"
 )
 
-func (c *Client) PublishRideRequested(ctx context.Context, msg rmq.RideRequestedMessage) error {
+// PublishRideRequested записывает событие ride.request в outbox в рамках tx.
+func (c *Client) PublishRideRequested(ctx context.Context, tx pgx.Tx, msg rmq.RideRequestedMessage) error {
 	if msg.CorrelationID == "" {
 		msg.CorrelationID = generateCorrelationID()
 	}
 
-	body, err := json.Marshal(msg)
-	if err != nil {
-		logger.Error("publish_ride_requested", "failed to marshal ride request message", msg.CorrelationID, msg.RideID, err.Error())
-		return fmt.Errorf("failed to marshal ride request message: %w", err)
-	}
-
 	routingKey := fmt.Sprintf("ride.request.%s", msg.RideType)
 
-	if err := c.Channel.ExchangeDeclare(
-		c.Exchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("publish_ride_requested", "failed to declare exchange", msg.CorrelationID, msg.RideID, err.Error())
-		return fmt.Errorf("failed to declare exchange: %w", err)
+	env, err := rmq.NewEnvelope(rmq.TypeRideRequested, 1, msg.CorrelationID, msg)
+	if err != nil {
+		logger.Error("publish_ride_requested", "failed to build envelope", msg.CorrelationID, msg.RideID, err.Error())
+		return fmt.Errorf("failed to build envelope: %w", err)
 	}
 
-	if err := c.Channel.PublishWithContext(
-		ctx,
-		c.Exchange,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	); err != nil {
-		logger.Error("publish_ride_requested", "failed to publish ride request", msg.CorrelationID, msg.RideID, err.Error())
-		return fmt.Errorf("failed to publish ride request: %w", err)
+	if err := c.Outbox.Enqueue(ctx, tx, c.Exchange, routingKey, env); err != nil {
+		logger.Error("publish_ride_requested", "failed to enqueue ride request", msg.CorrelationID, msg.RideID, err.Error())
+		return fmt.Errorf("failed to enqueue ride request: %w", err)
 	}
 
-	logger.Info("publish_ride_requested", "ride request successfully published", msg.CorrelationID, msg.RideID)
+	logger.Info("publish_ride_requested", "ride request enqueued to outbox", msg.CorrelationID, msg.RideID)
 	return nil
 }
 
-func (c *Client) PublishPassengerInfo(ctx context.Context, msg rmq.PassiNFO) error {
-	body, err := json.Marshal(msg)
+// PublishPassengerInfo записывает данные пассажира для водителя в outbox в рамках tx.
+func (c *Client) PublishPassengerInfo(ctx context.Context, tx pgx.Tx, msg rmq.PassiNFO) error {
+	env, err := rmq.NewEnvelope(rmq.TypePassengerInfo, 1, msg.RideID, msg)
 	if err != nil {
-		logger.Error("publish_passenger_info", "failed to marshal passenger info message", "", "", err.Error())
-		return fmt.Errorf("failed to marshal passenger info message: %w", err)
+		logger.Error("publish_passenger_info", "failed to build envelope", "", msg.RideID, err.Error())
+		return fmt.Errorf("failed to build envelope: %w", err)
 	}
 
-	routingKey := fmt.Sprintf("ride.request.%s", msg.Type)
-
-	if err := c.Channel.ExchangeDeclare(
-		c.Exchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("publish_passenger_info", "failed to declare exchange", "", "", err.Error())
-		return fmt.Errorf("failed to declare exchange: %w", err)
-	}
-
-	if err := c.Channel.PublishWithContext(
-		ctx,
-		c.Exchange,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	); err != nil {
-		logger.Error("publish_passenger_info", "failed to publish passenger info", "", "", err.Error())
-		return fmt.Errorf("failed to publish passenger info: %w", err)
+	routingKey := fmt.Sprintf("ride.passenger.%s", msg.RideID)
+	if err := c.Outbox.Enqueue(ctx, tx, c.Exchange, routingKey, env); err != nil {
+		logger.Error("publish_passenger_info", "failed to enqueue passenger info", "", msg.RideID, err.Error())
+		return fmt.Errorf("failed to enqueue passenger info: %w", err)
 	}
 
-	logger.Info("publish_passenger_info", "passenger info successfully published", "", "")
+	logger.Info("publish_passenger_info", "passenger info enqueued to outbox", "", msg.RideID)
 	return nil
 }
 
-func (c *Client) PublishLocationUpdate(ctx context.Context, msg rmq.LocationUpdateMessage) error {
-	body, err := json.Marshal(msg)
-	if err != nil {
-		logger.Error("publish_location_update", "failed to marshal location update message", "", msg.RideID, err.Error())
-		return fmt.Errorf("failed to marshal location update: %w", err)
-	}
-
-	if err := c.Channel.ExchangeDeclare(
-		c.Exchange,
-		"fanout",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		logger.Error("publish_location_update", "failed to declare exchange", "", msg.RideID, err.Error())
-		return fmt.Errorf("failed to declare exchange: %w", err)
-	}
-
-	if err := c.Channel.PublishWithContext(
-		ctx,
-		c.Exchange,
-		"", // fanout ignores routing key
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	); err != nil {
-		logger.Error("publish_location_update", "failed to publish location update", "", msg.RideID, err.Error())
-		return fmt.Errorf("failed to publish location update: %w", err)
-	}
-
-	logger.Debug("publish_location_update", "location update successfully published", "", msg.RideID)
-	return nil
+// PublishRideStatus записывает смену статуса поездки в outbox в рамках tx.
+func (c *Client) PublishRideStatus(ctx context.Context, tx pgx.Tx, msg rmq.RideStatusUpdateMessage) error {
+	return rmq.EnqueueRideStatus(ctx, c.Outbox, tx, msg)
 }
 
 func generateCorrelationID() string {
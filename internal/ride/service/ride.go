@@ -18,8 +18,6 @@ import (
 
 	usermodel "ride-hail-system/internal/user/model"
 
-	rmqClient "ride-hail-system/internal/ride/rmq"
-
 	"github.com/jackc/pgx/v5"
 )
 
@@ -27,26 +25,74 @@ type RideRepository interface {
 	InsertRide(ctx context.Context, tx pgx.Tx, ride model.Ride) (*model.Ride, error)
 	InsertRideEvent(ctx context.Context, tx pgx.Tx, event model.RideEvent) error
 	InsertCoordinate(ctx context.Context, tx pgx.Tx, coordinate model.Coordinate) (string, error)
-	CancelRide(ctx context.Context, rideID, reason string) (*repository.CancelRideResponse, error)
+	CancelRide(ctx context.Context, tx pgx.Tx, rideID, reason string) (*repository.CancelRideResponse, error)
 	GetPassengerIDByRideID(ctx context.Context, rideID string) (string, error)
 	BeginTx(ctx context.Context) (pgx.Tx, error)
-	UpdateRideStatusMatched(ctx context.Context, rideID string, driverID string) error
+	UpdateRideStatusMatched(ctx context.Context, tx pgx.Tx, rideID string, driverID string) error
 	UpdateLocation(ctx context.Context, rideID, passengerID string) error
 }
 
+// MessageBus — операции шины, нужные сервису.
+type MessageBus interface {
+	PublishRideRequested(ctx context.Context, tx pgx.Tx, msg common.RideRequestedMessage) error
+	PublishPassengerInfo(ctx context.Context, tx pgx.Tx, msg common.PassiNFO) error
+	ConsumeDriverResponses(ctx context.Context, queueName string, handler func(msg common.DriverResponseMessage) error) error
+	ConsumeLocationUpdates(ctx context.Context, queueName string, handler func(msg common.LocationUpdateMessage) error) error
+	PublishRideStatus(ctx context.Context, tx pgx.Tx, msg common.RideStatusUpdateMessage) error
+}
+
+// ContactRegistry хранит настоящий номер пассажира и выдаёт вместо него прокси-идентификатор.
+type ContactRegistry interface {
+	RegisterNumber(ctx context.Context, rideID, userID string, role usermodel.Role, number string) (string, error)
+}
+
+// ErrBusy возвращается WebSocket-обработчикам, когда очередь входящих
+// сообщений сервиса переполнена.
+var ErrBusy = errors.New("service is busy, try again later")
+
 type RideService struct {
-	repo  RideRepository
-	mq    *rmqClient.Client
-	wsHub *websocket.Hub
+	repo      RideRepository
+	mq        MessageBus
+	wsHub     *websocket.Hub
+	contacts  ContactRegistry
+	passInfos chan passengerInfo
 }
 
-func NewRideManager(repo RideRepository, mq *rmqClient.Client, wsHub *websocket.Hub) *RideService {
+// passengerInfo — данные пассажира из WebSocket.
+type passengerInfo struct {
+	passengerID string
+	phone       string
+	info        common.PassiNFO
+}
+
+func NewRideManager(repo RideRepository, mq MessageBus, wsHub *websocket.Hub, contacts ContactRegistry) *RideService {
 	logger.SetServiceName("ride-service")
-	return &RideService{repo: repo, mq: mq, wsHub: wsHub}
+	return &RideService{
+		repo:      repo,
+		mq:        mq,
+		wsHub:     wsHub,
+		contacts:  contacts,
+		passInfos: make(chan passengerInfo, 256),
+	}
+}
+
+// SubmitPassengerInfo передаёт данные пассажира из WebSocket в цикл SendPassInfo.
+func (s *RideService) SubmitPassengerInfo(passengerID, phone string, info common.PassiNFO) error {
+	select {
+	case s.passInfos <- passengerInfo{passengerID: passengerID, phone: phone, info: info}:
+		return nil
+	default:
+		return ErrBusy
+	}
+}
+
+// RidePassenger возвращает ID пассажира поездки; нужен для проверки доступа к её событиям.
+func (s *RideService) RidePassenger(ctx context.Context, rideID string) (string, error) {
+	return s.repo.GetPassengerIDByRideID(ctx, rideID)
 }
 
 func (s *RideService) ListenForDriver(ctx context.Context, queueName string) {
-	err := s.mq.ConsumeDriverResponses(queueName, func(msg common.DriverResponseMessage) {
+	err := s.mq.ConsumeDriverResponses(ctx, queueName, func(msg common.DriverResponseMessage) error {
 		logger.Info("driver_response_received",
 			fmt.Sprintf("получен ответ от водителя %s по заказу %s (accepted=%v)", msg.DriverID, msg.RideID, msg.Accepted),
 			"", msg.RideID)
@@ -57,23 +103,37 @@ func (s *RideService) ListenForDriver(ctx context.Context, queueName string) {
 			passengerID, err := s.repo.GetPassengerIDByRideID(ctx, msg.RideID)
 			if err != nil {
 				logger.Error("get_passenger_id_failed", "не удалось получить passenger_id", "", msg.RideID, err.Error())
-				return
+				return err
 			}
 
-			err = s.repo.UpdateRideStatusMatched(ctx, msg.RideID, msg.DriverID)
+			err = s.inTx(ctx, func(tx pgx.Tx) error {
+				if err := s.repo.UpdateRideStatusMatched(ctx, tx, msg.RideID, msg.DriverID); err != nil {
+					return err
+				}
+				return s.mq.PublishRideStatus(ctx, tx, common.RideStatusUpdateMessage{
+					RideID:    msg.RideID,
+					Status:    string(model.RideMatched),
+					DriverID:  msg.DriverID,
+					Timestamp: time.Now().UTC(),
+					Message:   "Driver matched",
+				})
+			})
 			if err != nil {
 				logger.Error("update_status_failed", "ошибка при обновлении статуса поездки", "", msg.RideID, err.Error())
+				return err
 			}
 
-			passId := "passenger_" + passengerID
 			logger.Info("send_to_passenger",
 				fmt.Sprintf("отправка пассажиру %s: %s", passengerID, string(data)),
 				"", msg.RideID)
-			s.wsHub.SendToClient(passId, data)
+			if _, err := s.wsHub.PublishMessage(websocket.PassengerTopic(passengerID), websocket.MsgRideMatched, msg); err != nil {
+				logger.Error("send_to_passenger", "не удалось сформировать сообщение пассажиру", "", msg.RideID, err.Error())
+			}
 		} else {
 			logger.Warn("driver_declined", "водитель отклонил поездку", "", msg.RideID,
 				fmt.Sprintf("driver_id=%s", msg.DriverID))
 		}
+		return nil
 	})
 	if err != nil {
 		logger.Error("consume_driver_responses_failed",
@@ -89,12 +149,24 @@ func (s *RideService) SendPassInfo(ctx context.Context) {
 			logger.Info("stop_listening", "остановлено получение ответов от пассажиров", "", "")
 			return
 
-		case resp := <-s.wsHub.PassengerResponses:
+		case in := <-s.passInfos:
+			resp := in.info
+			proxyID, err := s.contacts.RegisterNumber(ctx, resp.RideID, in.passengerID, usermodel.RolePassenger, in.phone)
+			if err != nil {
+				logger.Warn("contact_register_failed", "не удалось выдать прокси-номер пассажира, данные не отправлены", "", resp.RideID, err.Error())
+				continue
+			}
+			resp.PassengerContact = proxyID
+
 			logger.Debug("passenger_ws_response",
 				fmt.Sprintf("получен ответ пассажира из WS: %+v", resp),
 				"", resp.RideID)
 
-			err := s.mq.PublishPassengerInfo(ctx, resp)
+			// Данные приходят после совпадения, поэтому пишутся в outbox
+			// отдельной транзакцией.
+			err = s.inTx(ctx, func(tx pgx.Tx) error {
+				return s.mq.PublishPassengerInfo(ctx, tx, resp)
+			})
 			if err != nil {
 				logger.Error("mq_publish_failed", "ошибка отправки ответа пассажира в MQ", "", resp.RideID, err.Error())
 			} else {
@@ -107,7 +179,7 @@ func (s *RideService) SendPassInfo(ctx context.Context) {
 }
 
 func (s *RideService) LocationUpdate(ctx context.Context, queueName string) {
-	err := s.mq.ConsumeLocationUpdates(queueName, func(msg common.LocationUpdateMessage) {
+	err := s.mq.ConsumeLocationUpdates(ctx, queueName, func(msg common.LocationUpdateMessage) error {
 		logger.Info("location_update_received",
 			fmt.Sprintf("геолокация изменилась %s по заказу %s", msg.DriverID, msg.RideID),
 			"", msg.RideID)
@@ -117,26 +189,30 @@ func (s *RideService) LocationUpdate(ctx context.Context, queueName string) {
 		passengerID, err := s.repo.GetPassengerIDByRideID(ctx, msg.RideID)
 		if err != nil {
 			logger.Error("get_passenger_id_failed", "не удалось получить passenger_id", "", msg.RideID, err.Error())
-			return
+			return err
 		}
 
-		err = s.repo.UpdateRideStatusMatched(ctx, msg.RideID, msg.DriverID)
+		err = s.inTx(ctx, func(tx pgx.Tx) error {
+			return s.repo.UpdateRideStatusMatched(ctx, tx, msg.RideID, msg.DriverID)
+		})
 		if err != nil {
 			logger.Error("insert updated location", "cannot insert new location to db", "", msg.RideID, err.Error())
-			return
+			return err
 		}
 
 		err = s.repo.UpdateLocation(ctx, msg.RideID, passengerID)
 		if err != nil {
 			logger.Error("insert updated status", "cannot update ride event", "", msg.RideID, err.Error())
-			return
+			return err
 		}
-		passId := "passenger_" + passengerID
 		logger.Info("send_location_to_passenger",
 			fmt.Sprintf("отправка пассажиру %s: %s", passengerID, string(data)),
 			"", msg.RideID)
 
-		s.wsHub.SendToClient(passId, data)
+		if _, err := s.wsHub.PublishMessage(websocket.PassengerTopic(passengerID), websocket.MsgDriverLocationUpdate, msg); err != nil {
+			logger.Error("send_location_to_passenger", "не удалось сформировать сообщение пассажиру", "", msg.RideID, err.Error())
+		}
+		return nil
 	})
 	if err != nil {
 		logger.Error("consume_location_failed",
@@ -254,11 +330,6 @@ func (s *RideService) CreateRide(ctx context.Context, ride model.Ride, pickup, d
 		return nil, 0, 0, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		logger.Error("tx_commit_failed", "ошибка коммита транзакции", "", "", err.Error())
-		return nil, 0, 0, err
-	}
-
 	message := common.RideRequestedMessage{
 		RideID:     string(createdRide.ID),
 		RideNumber: rideNumber,
@@ -278,8 +349,14 @@ func (s *RideService) CreateRide(ctx context.Context, ride model.Ride, pickup, d
 		CorrelationID:  string(createdRide.ID),
 	}
 
-	if err := s.mq.PublishRideRequested(ctx, message); err != nil {
-		logger.Warn("publish_ride_request_failed", "не удалось опубликовать событие ride.request", "", string(createdRide.ID), err.Error())
+	if err = s.mq.PublishRideRequested(ctx, tx, message); err != nil {
+		logger.Error("publish_ride_request_failed", "не удалось записать событие ride.request в outbox", "", string(createdRide.ID), err.Error())
+		return nil, 0, 0, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("tx_commit_failed", "ошибка коммита транзакции", "", "", err.Error())
+		return nil, 0, 0, err
 	}
 
 	logger.Info("create_ride_success", "поездка успешно создана и опубликована", "", string(createdRide.ID))
@@ -298,7 +375,20 @@ func (s *RideService) CancelRide(ctx context.Context, rideID, reason string) (*r
 
 	logger.Info("CancelRide", fmt.Sprintf("Cancelling ride %s with reason: %s", rideID, reason), "", rideID)
 
-	resp, err := s.repo.CancelRide(ctx, rideID, reason)
+	var resp *repository.CancelRideResponse
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		resp, err = s.repo.CancelRide(ctx, tx, rideID, reason)
+		if err != nil {
+			return err
+		}
+		return s.mq.PublishRideStatus(ctx, tx, common.RideStatusUpdateMessage{
+			RideID:    rideID,
+			Status:    string(model.RideCancelled),
+			Timestamp: time.Now().UTC(),
+			Message:   reason,
+		})
+	})
 	if err != nil {
 		logger.Error("CancelRide", "failed to cancel ride", "", rideID, err.Error())
 		return nil, err
@@ -308,6 +398,23 @@ func (s *RideService) CancelRide(ctx context.Context, rideID, reason string) (*r
 	return resp, nil
 }
 
+// inTx выполняет fn в транзакции: смена статуса поездки и событие в outbox фиксируются вместе.
+func (s *RideService) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 func calculateRoute(pickupLat, pickupLng, destLat, destLng float64) (distanceKm float64, durationMin int, err error) {
 	const earthRadiusKm = 6371.0
 
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"ride-hail-system/internal/common/outbox"
+	common "ride-hail-system/internal/common/rmq"
+	"ride-hail-system/internal/common/websocket"
+	"ride-hail-system/internal/ride/model"
+	"ride-hail-system/internal/ride/repository"
+	ridermq "ride-hail-system/internal/ride/rmq"
+	usermodel "ride-hail-system/internal/user/model"
+	"ride-hail-system/pkg/uuid"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+type fakeRideRepo struct {
+	mu         sync.Mutex
+	txs        []*fakeTx
+	passengers map[string]string
+	matched    map[string]string
+	events     []model.RideEvent
+	coords     int
+	lookups    int
+	failLookup error
+}
+
+func newFakeRideRepo() *fakeRideRepo {
+	return &fakeRideRepo{passengers: map[string]string{}, matched: map[string]string{}}
+}
+
+func (r *fakeRideRepo) BeginTx(context.Context) (pgx.Tx, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tx := &fakeTx{}
+	r.txs = append(r.txs, tx)
+	return tx, nil
+}
+
+func (r *fakeRideRepo) InsertRide(_ context.Context, _ pgx.Tx, ride model.Ride) (*model.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ride.ID = uuid.UUID(fmt.Sprintf("ride-%d", len(r.passengers)+1))
+	r.passengers[string(ride.ID)] = string(ride.PassengerID)
+	return &ride, nil
+}
+
+func (r *fakeRideRepo) InsertRideEvent(_ context.Context, _ pgx.Tx, event model.RideEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeRideRepo) InsertCoordinate(context.Context, pgx.Tx, model.Coordinate) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.coords++
+	return fmt.Sprintf("coord-%d", r.coords), nil
+}
+
+func (r *fakeRideRepo) CancelRide(_ context.Context, _ pgx.Tx, rideID, reason string) (*repository.CancelRideResponse, error) {
+	return &repository.CancelRideResponse{RideID: rideID, Status: string(model.RideCancelled)}, nil
+}
+
+func (r *fakeRideRepo) GetPassengerIDByRideID(_ context.Context, rideID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	if r.failLookup != nil {
+		return "", r.failLookup
+	}
+	id, ok := r.passengers[rideID]
+	if !ok {
+		return "", errors.New("ride not found")
+	}
+	return id, nil
+}
+
+func (r *fakeRideRepo) UpdateRideStatusMatched(_ context.Context, _ pgx.Tx, rideID, driverID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matched[rideID] = driverID
+	return nil
+}
+
+func (r *fakeRideRepo) UpdateLocation(context.Context, string, string) error {
+	return nil
+}
+
+// committed возвращает число зафиксированных транзакций.
+func (r *fakeRideRepo) committed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, tx := range r.txs {
+		if tx.committed {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *fakeRideRepo) matchedDriver(rideID string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.matched[rideID]
+}
+
+// fakeContacts выдаёт прокси-номер для любой поездки, кроме denied.
+type fakeContacts struct {
+	denied string
+}
+
+func (c fakeContacts) RegisterNumber(_ context.Context, rideID, userID string, _ usermodel.Role, _ string) (string, error) {
+	if rideID == c.denied {
+		return "", errors.New("user is not a participant of the ride")
+	}
+	return "proxy-" + rideID, nil
+}
+
+// fakeConn собирает всё, что WritePump пишет в соединение.
+type fakeConn struct {
+	out chan []byte
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) { return 0, nil, errors.New("closed") }
+func (c *fakeConn) WriteMessage(_ int, data []byte) error {
+	c.out <- data
+	return nil
+}
+func (c *fakeConn) WriteControl(int, []byte, time.Time) error { return nil }
+func (c *fakeConn) SetReadDeadline(time.Time) error           { return nil }
+func (c *fakeConn) SetWriteDeadline(time.Time) error          { return nil }
+func (c *fakeConn) Close() error                              { return nil }
+
+type rideFixture struct {
+	bus  *common.MemoryBus
+	repo *fakeRideRepo
+	hub  *websocket.Hub
+	svc  *RideService
+}
+
+func newRideFixture(t *testing.T, contacts ContactRegistry) *rideFixture {
+	t.Helper()
+
+	bus := common.NewMemoryBus()
+	top := common.DefaultTopology()
+	if err := top.Apply(bus); err != nil {
+		t.Fatalf("Apply topology: %v", err)
+	}
+	t.Cleanup(func() { _ = bus.Close() })
+
+	client := ridermq.NewClient(bus, common.ExchangeRide, outbox.NewDirect(bus), common.ConsumerOptions{Topology: top, MaxAttempts: 2, RetryDelay: time.Millisecond})
+	repo := newFakeRideRepo()
+	hub := websocket.NewHub()
+	return &rideFixture{bus: bus, repo: repo, hub: hub, svc: NewRideManager(repo, client, hub, contacts)}
+}
+
+// connect подключает пассажира к хабу и возвращает канал отправленных ему кадров.
+func (f *rideFixture) connect(t *testing.T, passengerID string) <-chan []byte {
+	t.Helper()
+	conn := &fakeConn{out: make(chan []byte, 16)}
+	c := f.hub.NewClient(websocket.RolePassenger, passengerID, conn)
+	f.hub.Register(c)
+	go c.WritePump()
+	t.Cleanup(func() { f.hub.Unregister(c) })
+	return conn.out
+}
+
+func (f *rideFixture) publish(t *testing.T, exchange, routingKey, msgType string, payload any) {
+	t.Helper()
+	env, err := common.NewEnvelope(msgType, 1, "", payload)
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	body, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	if err := f.bus.Publish(context.Background(), exchange, routingKey, common.Publishing{MessageID: env.MessageID, Type: env.Type, Body: body}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+func (f *rideFixture) next(t *testing.T, queue string) common.Envelope {
+	t.Helper()
+	ch, err := f.bus.Consume(queue, 1)
+	if err != nil {
+		t.Fatalf("Consume(%s): %v", queue, err)
+	}
+	select {
+	case d := <-ch:
+		var env common.Envelope
+		if err := json.Unmarshal(d.Body, &env); err != nil {
+			t.Fatalf("unmarshal envelope: %v", err)
+		}
+		return env
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no message in %s", queue)
+		return common.Envelope{}
+	}
+}
+
+func expectFrame(t *testing.T, frames <-chan []byte, msgType string) websocket.Message {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case data := <-frames:
+			var msg websocket.Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("unmarshal frame: %v", err)
+			}
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-deadline:
+			t.Fatalf("passenger did not receive %s", msgType)
+			return websocket.Message{}
+		}
+	}
+}
+
+func TestCreateRidePublishesRequest(t *testing.T) {
+	f := newRideFixture(t, fakeContacts{})
+
+	vehicle := usermodel.VehicleEconomy
+	ride, _, _, err := f.svc.CreateRide(context.Background(),
+		model.Ride{PassengerID: "p-1", VehicleType: &vehicle},
+		model.Coordinate{EntityID: "p-1", Address: "Abay 1", Latitude: 43.238, Longitude: 76.889},
+		model.Coordinate{EntityID: "p-1", Address: "Dostyk 5", Latitude: 43.222, Longitude: 76.851},
+	)
+	if err != nil {
+		t.Fatalf("CreateRide: %v", err)
+	}
+	if len(f.repo.txs) != 1 || !f.repo.txs[0].committed {
+		t.Fatal("ride transaction was not committed")
+	}
+
+	env := f.next(t, common.QueueRideRequests)
+	var msg common.RideRequestedMessage
+	if err := env.DecodeAs(common.TypeRideRequested, &msg); err != nil {
+		t.Fatalf("decode ride request: %v", err)
+	}
+	if msg.RideID != string(ride.ID) || msg.RideType != usermodel.VehicleEconomy {
+		t.Fatalf("ride request = %+v, want ride %s ECONOMY", msg, ride.ID)
+	}
+	if msg.PickupLocation.Address != "Abay 1" || msg.MaxDistanceKm <= 0 {
+		t.Fatalf("unexpected ride request payload %+v", msg)
+	}
+}
+
+func TestListenForDriverMatchesRide(t *testing.T) {
+	f := newRideFixture(t, fakeContacts{})
+	f.repo.passengers["ride-1"] = "p-1"
+	frames := f.connect(t, "p-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.svc.ListenForDriver(ctx, common.QueueDriverResponses)
+
+	f.publish(t, common.ExchangeDriver, "driver.response.ride-1", common.TypeDriverResponse, common.DriverResponseMessage{
+		RideID:   "ride-1",
+		DriverID: "d-1",
+		Accepted: true,
+	})
+
+	matched := expectFrame(t, frames, websocket.MsgRideMatched)
+	var payload common.DriverResponseMessage
+	if err := json.Unmarshal(matched.Payload, &payload); err != nil || payload.DriverID != "d-1" {
+		t.Fatalf("ride_matched payload = %s (%v), want driver d-1", matched.Payload, err)
+	}
+	if got := f.repo.matchedDriver("ride-1"); got != "d-1" {
+		t.Fatalf("ride matched to %q, want d-1", got)
+	}
+	if got := f.repo.committed(); got != 1 {
+		t.Fatalf("committed transactions = %d, want 1", got)
+	}
+
+	env := f.next(t, common.QueueChatRideStatus)
+	var status common.RideStatusUpdateMessage
+	if err := env.DecodeAs(common.TypeRideStatus, &status); err != nil {
+		t.Fatalf("decode ride status: %v", err)
+	}
+	if status.RideID != "ride-1" || status.Status != string(model.RideMatched) || status.DriverID != "d-1" {
+		t.Fatalf("ride status = %+v, want MATCHED by d-1", status)
+	}
+}
+
+func TestListenForDriverIgnoresDecline(t *testing.T) {
+	f := newRideFixture(t, fakeContacts{})
+	f.repo.passengers["ride-1"] = "p-1"
+	frames := f.connect(t, "p-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.svc.ListenForDriver(ctx, common.QueueDriverResponses)
+
+	// Ответы по одной поездке обрабатываются по порядку одним воркером.
+	for _, resp := range []common.DriverResponseMessage{
+		{RideID: "ride-1", DriverID: "d-1", Accepted: false},
+		{RideID: "ride-1", DriverID: "d-2", Accepted: true},
+	} {
+		f.publish(t, common.ExchangeDriver, "driver.response.ride-1", common.TypeDriverResponse, resp)
+	}
+
+	expectFrame(t, frames, websocket.MsgRideMatched)
+	if got := f.repo.matchedDriver("ride-1"); got != "d-2" {
+		t.Fatalf("ride matched to %q, want d-2", got)
+	}
+	if n, _ := f.bus.Depth(common.QueueChatRideStatus); n != 1 {
+		t.Fatalf("ride status messages = %d, want 1", n)
+	}
+}
+
+// Ошибка базы временная: ответ повторяется MaxAttempts раз и только потом уходит в DLX.
+func TestListenForDriverRetriesOnDatabaseError(t *testing.T) {
+	f := newRideFixture(t, fakeContacts{})
+	f.repo.failLookup = errors.New("conn closed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.svc.ListenForDriver(ctx, common.QueueDriverResponses)
+
+	f.publish(t, common.ExchangeDriver, "driver.response.ride-1", common.TypeDriverResponse, common.DriverResponseMessage{
+		RideID:   "ride-1",
+		DriverID: "d-1",
+		Accepted: true,
+	})
+
+	env := f.next(t, common.QueueDeadLetters)
+	if env.Type != common.TypeDriverResponse {
+		t.Fatalf("dead letter type = %q, want %q", env.Type, common.TypeDriverResponse)
+	}
+	f.repo.mu.Lock()
+	lookups := f.repo.lookups
+	f.repo.mu.Unlock()
+	if lookups != 2 {
+		t.Fatalf("passenger lookups = %d, want 2", lookups)
+	}
+	if got := f.repo.matchedDriver("ride-1"); got != "" {
+		t.Fatalf("ride matched to %q despite lookup failure", got)
+	}
+	if n, _ := f.bus.Depth(common.QueueChatRideStatus); n != 0 {
+		t.Fatalf("ride status published despite lookup failure: %d messages", n)
+	}
+}
+
+func TestSendPassInfoDropsUnregisteredContact(t *testing.T) {
+	f := newRideFixture(t, fakeContacts{denied: "ride-1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.svc.SendPassInfo(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, rideID := range []string{"ride-1", "ride-2"} {
+		if err := f.svc.SubmitPassengerInfo("p-1", "+77011234567", common.PassiNFO{RideID: rideID, PassengerName: "Aigerim"}); err != nil {
+			t.Fatalf("SubmitPassengerInfo(%s): %v", rideID, err)
+		}
+	}
+
+	// Цикл обрабатывает данные по порядку: если ride-2 уже опубликован,
+	// решение по ride-1 принято.
+	var msg common.PassiNFO
+	if err := f.next(t, common.QueueDriverMatching).DecodeAs(common.TypePassengerInfo, &msg); err != nil {
+		t.Fatalf("decode passenger info: %v", err)
+	}
+	if msg.RideID != "ride-2" || msg.PassengerContact != "proxy-ride-2" {
+		t.Fatalf("passenger info = %+v, want ride-2 with proxy-ride-2", msg)
+	}
+	if n, _ := f.bus.Depth(common.QueueDriverMatching); n != 0 {
+		t.Fatalf("%d more passenger info messages published", n)
+	}
+
+	// Данные пишутся в outbox в транзакции; для ride-1 она не открывалась.
+	cancel()
+	<-done
+	if got := f.repo.committed(); got != 1 {
+		t.Fatalf("committed transactions = %d, want 1", got)
+	}
+}
+
+func TestCancelRideEnqueuesStatus(t *testing.T) {
+	f := newRideFixture(t, fakeContacts{})
+
+	if _, err := f.svc.CancelRide(context.Background(), "ride-1", "changed plans"); err != nil {
+		t.Fatalf("CancelRide: %v", err)
+	}
+	if got := f.repo.committed(); got != 1 {
+		t.Fatalf("committed transactions = %d, want 1", got)
+	}
+
+	var status common.RideStatusUpdateMessage
+	if err := f.next(t, common.QueueChatRideStatus).DecodeAs(common.TypeRideStatus, &status); err != nil {
+		t.Fatalf("decode ride status: %v", err)
+	}
+	if status.RideID != "ride-1" || status.Status != string(model.RideCancelled) || status.Message != "changed plans" {
+		t.Fatalf("ride status = %+v, want CANCELLED with reason", status)
+	}
+}
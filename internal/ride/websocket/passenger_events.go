@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	commonws "ride-hail-system/internal/common/websocket"
+	"ride-hail-system/internal/ride/service"
+	"ride-hail-system/internal/user/jwt"
+)
+
+const (
+	defaultPollTimeout = 25 * time.Second
+	maxPollTimeout     = 55 * time.Second
+)
+
+// PollResponse — ответ long-poll. NextSince передаётся в следующий запрос.
+type PollResponse struct {
+	Messages  []json.RawMessage `json:"messages"`
+	NextSince uint64            `json:"next_since"`
+	Gap       bool              `json:"gap"`
+	Reset     bool              `json:"reset"`
+}
+
+// authorizeRide проверяет, что поездка принадлежит пассажиру из токена.
+func authorizeRide(w http.ResponseWriter, r *http.Request, svc *service.RideService) (jwt.Claims, string, bool) {
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	rideID := r.PathValue("ride_id")
+	passengerID, err := svc.RidePassenger(r.Context(), rideID)
+	if err != nil || passengerID != claims.UserID {
+		http.Error(w, "ride not found", http.StatusNotFound)
+		return jwt.Claims{}, "", false
+	}
+	return claims, rideID, true
+}
+
+// parseSince читает последний полученный seq из Last-Event-ID или ?since=.
+func parseSince(r *http.Request) (uint64, bool) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("since")
+	}
+	if raw == "" {
+		return 0, false
+	}
+	since, err := strconv.ParseUint(raw, 10, 64)
+	return since, err == nil
+}
+
+// PassengerEventsHandler отдаёт события поездки потоком SSE.
+func PassengerEventsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, hub *commonws.Hub, svc *service.RideService) {
+	const action = "PassengerEventsHandler"
+
+	claims, rideID, ok := authorizeRide(w, r, svc)
+	if !ok {
+		return
+	}
+
+	conn := commonws.NewSSEConn(w, r, commonws.RideFilter(rideID))
+	client := hub.NewClient(commonws.RolePassenger, claims.UserID, conn)
+	client.RemoteAddr = r.RemoteAddr
+	client.UserAgent = r.UserAgent()
+	client.NoAck = true
+	hub.Register(client)
+	logger.Info(action, "Passenger subscribed to ride events over SSE: "+claims.UserID, "", rideID)
+
+	if since, ok := parseSince(r); ok {
+		if _, err := hub.Resume(client, since); err != nil {
+			logger.Warn(action, "Failed to replay ride events", "", rideID, err.Error())
+		}
+	}
+
+	go client.WritePump()
+
+	client.ReadPump(ctx)
+	hub.Unregister(client)
+	logger.Info(action, "Passenger SSE stream closed: "+claims.UserID, "", rideID)
+}
+
+// PassengerPollHandler — long-poll вариант: ждёт события поездки после
+// ?since= не дольше ?timeout= секунд и возвращает их одним ответом.
+func PassengerPollHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, hub *commonws.Hub, svc *service.RideService) {
+	const action = "PassengerPollHandler"
+
+	claims, rideID, ok := authorizeRide(w, r, svc)
+	if !ok {
+		return
+	}
+
+	timeout := defaultPollTimeout
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		secs, err := strconv.Atoi(raw)
+		if err != nil || secs < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = min(time.Duration(secs)*time.Second, maxPollTimeout)
+	}
+	since, _ := parseSince(r)
+
+	// Ответ может ждать дольше WriteTimeout сервера.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+
+	// Опрос — не сессия: без записи в реестр сессий и без учёта в подтверждениях.
+	conn := commonws.NewPollConn(commonws.RideFilter(rideID))
+	client := hub.NewClient(commonws.RolePassenger, claims.UserID, conn)
+	hub.Attach(client)
+
+	written := make(chan struct{})
+	go func() {
+		client.WritePump()
+		close(written)
+	}()
+
+	res, err := hub.Resume(client, since)
+	if err != nil {
+		logger.Warn(action, "Failed to replay ride events", "", rideID, err.Error())
+	}
+
+	waitCtx, cancel := context.WithCancel(r.Context())
+	stop := context.AfterFunc(ctx, cancel)
+	conn.Wait(waitCtx, timeout)
+	stop()
+	cancel()
+
+	hub.Detach(client)
+	<-written
+
+	messages, lastSeq := conn.Messages()
+	resp := PollResponse{Messages: messages, NextSince: since, Gap: res.Gap, Reset: res.Reset}
+	if lastSeq > resp.NextSince || res.Reset {
+		resp.NextSince = lastSeq
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Error(action, "Failed to encode response", "", rideID, err.Error())
+	}
+}
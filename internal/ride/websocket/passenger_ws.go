@@ -2,13 +2,15 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/logger"
+	commonmq "ride-hail-system/internal/common/rmq"
 	commonws "ride-hail-system/internal/common/websocket"
 	"ride-hail-system/internal/ride/service"
-	"ride-hail-system/internal/user/jwt"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,7 +19,25 @@ var Upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-func PassengerWSHandler(w http.ResponseWriter, r *http.Request, hub *commonws.Hub, jwtManager *jwt.Manager, svc *service.RideService) {
+// RegisterHandlers подключает обработчики сообщений пассажиров к хабу.
+func RegisterHandlers(hub *commonws.Hub, svc *service.RideService) {
+	hub.Handle(commonws.RolePassenger, commonws.MsgPassengerDetails, func(ctx context.Context, c *commonws.Client, msg commonws.Message) error {
+		var details struct {
+			commonmq.PassiNFO
+			PassengerPhone string `json:"passenger_phone"`
+		}
+		if err := json.Unmarshal(msg.Payload, &details); err != nil {
+			return err
+		}
+		logger.Info("passenger_response", "Received passenger response", "", c.ID)
+		return svc.SubmitPassengerInfo(c.UserID, details.PassengerPhone, details.PassiNFO)
+	})
+	// Старые клиенты присылают ride_details или кадр без type.
+	hub.Alias(commonws.RolePassenger, "ride_details", commonws.MsgPassengerDetails)
+	hub.Alias(commonws.RolePassenger, "", commonws.MsgPassengerDetails)
+}
+
+func PassengerWSHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, hub *commonws.Hub, authn *auth.Authenticator) {
 	action := "PassengerWSHandler"
 	requestID := ""
 	rideID := ""
@@ -30,13 +50,7 @@ func PassengerWSHandler(w http.ResponseWriter, r *http.Request, hub *commonws.Hu
 	}
 	defer conn.Close()
 
-	done := make(chan struct{})
-
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	conn.SetPongHandler(func(appData string) error {
-		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		return nil
-	})
 
 	logger.Info(action, "Waiting for passenger auth message", requestID, rideID)
 
@@ -50,56 +64,29 @@ func PassengerWSHandler(w http.ResponseWriter, r *http.Request, hub *commonws.Hu
 		return
 	}
 
-	claims, err := jwtManager.ValidateToken(authMsg.Token)
+	claims, err := authn.Verify(authMsg.Token)
 	if err != nil {
 		logger.Warn(action, "Invalid passenger token", requestID, rideID, err.Error())
 		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "invalid token"))
 		return
 	}
-
-	client := &commonws.Client{
-		ID:   "passenger_" + claims.UserID,
-		Conn: conn,
-		Send: make(chan []byte, 256),
+	if authn.Permit(claims, auth.PermRideEvents) != nil {
+		logger.Warn(action, "Token role is not allowed on passenger socket", requestID, rideID, claims.Role)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "forbidden"))
+		return
 	}
-	hub.Register <- client
 
-	logger.Info(action, "Passenger connected: "+claims.UserID, requestID, rideID)
+	client := hub.NewClient(commonws.RolePassenger, claims.UserID, conn)
+	client.RemoteAddr = r.RemoteAddr
+	client.UserAgent = r.UserAgent()
+	hub.Register(client)
 
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-done:
-				return
-			case <-ticker.C:
-				err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(10*time.Second))
-				if err != nil {
-					logger.Warn(action, "Ping failed for passenger "+claims.UserID, requestID, rideID, err.Error())
-					return
-				}
-				logger.Debug(action, "Ping sent to passenger "+claims.UserID, requestID, rideID)
-			}
-		}
-	}()
-
-	go func() {
-		for msg := range client.Send {
-			if err := client.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-				logger.Error(action, "Failed to send message to passenger "+client.ID, requestID, rideID, err.Error())
-				break
-			}
-			logger.Debug(action, "Message sent to passenger "+client.ID, requestID, rideID)
-		}
-	}()
+	logger.Info(action, "Passenger connected: "+claims.UserID, requestID, rideID)
 
-	go hub.ListenPassengerMessages(client)
-	go svc.SendPassInfo(context.Background())
+	go client.WritePump()
 
-	<-done
-	hub.Unregister <- client
-	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	client.ReadPump(ctx)
+	hub.Unregister(client)
 
 	logger.Info(action, "Passenger connection closed: "+claims.UserID, requestID, rideID)
 }
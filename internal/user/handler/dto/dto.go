@@ -29,9 +29,19 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginResponse содержит либо пару токенов, либо, при включённой 2FA,
+// токен второго шага для /login/2fa.
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken       string `json:"access_token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// TwoFactorLoginRequest — второй шаг входа: код из приложения или код восстановления.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
 }
 
 type RefreshTokenRequest struct {
@@ -43,6 +53,60 @@ type RefreshTokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// UpdateProfileRequest — частичное обновление профиля: отсутствующие поля не
+// меняются, пустая строка очищает поле.
+type UpdateProfileRequest struct {
+	Name         *string         `json:"name,omitempty"`
+	Phone        *string         `json:"phone,omitempty"`
+	Locale       *string         `json:"locale,omitempty"`
+	AvatarURL    *string         `json:"avatar_url,omitempty"`
+	VehicleAttrs json.RawMessage `json:"vehicle_attrs,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type TwoFactorEnrollRequest struct {
+	Password string `json:"password"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 func (r *RegisterRequest) Validate() error {
 	if strings.TrimSpace(r.Email) == "" {
 		return errors.New("email is required")
@@ -73,3 +137,34 @@ func isValidEmail(email string) bool {
 	re := regexp.MustCompile(`^[\w._%+\-]+@[\w.\-]+\.[A-Za-z]{2,}$`)
 	return re.MatchString(email)
 }
+
+func (r *ChangePasswordRequest) Validate() error {
+	if r.OldPassword == "" {
+		return errors.New("old_password is required")
+	}
+	if len(r.NewPassword) < 8 {
+		return errors.New("password must be at least 8 characters long")
+	}
+	return nil
+}
+
+func (r *ResetPasswordRequest) Validate() error {
+	if strings.TrimSpace(r.Token) == "" {
+		return errors.New("token is required")
+	}
+	if len(r.NewPassword) < 8 {
+		return errors.New("password must be at least 8 characters long")
+	}
+	return nil
+}
+
+func (r *ChangeEmailRequest) Validate() error {
+	r.NewEmail = strings.TrimSpace(r.NewEmail)
+	if !isValidEmail(r.NewEmail) {
+		return errors.New("invalid email format")
+	}
+	if r.Password == "" {
+		return errors.New("password is required")
+	}
+	return nil
+}
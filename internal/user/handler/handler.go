@@ -3,19 +3,27 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/user/handler/dto"
+	"ride-hail-system/internal/user/jwt"
+	"ride-hail-system/internal/user/model"
 	"ride-hail-system/internal/user/service"
 )
 
 type AuthHandler struct {
 	authService *service.AuthService
+	profiles    *service.ProfileService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.AuthService, profiles *service.ProfileService) *AuthHandler {
+	return &AuthHandler{authService: authService, profiles: profiles}
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +54,12 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info(action, "user successfully registered", requestID, string(createdUser.ID))
 
+	// Письмо можно запросить повторно через /email/verify/resend, поэтому
+	// ошибка отправки не отменяет регистрацию.
+	if err := h.profiles.SendVerification(context.Background(), string(createdUser.ID)); err != nil {
+		logger.Warn(action, "failed to send verification email", requestID, string(createdUser.ID), err.Error())
+	}
+
 	resp := dto.RegisterResponse{
 		UserID: string(createdUser.ID),
 	}
@@ -74,25 +88,66 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info(action, "login request received", requestID, "")
 
-	access, refresh, err := h.authService.Login(context.Background(), req.Email, req.Password)
+	resp, err := h.authService.Login(context.Background(), req.Email, req.Password, clientInfo(r))
 	if err != nil {
 		logger.Error(action, "login failed", requestID, "", err.Error())
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeLoginError(w, err)
 		return
 	}
 
-	logger.Info(action, "user successfully logged in", requestID, "")
+	if resp.TwoFactorRequired {
+		logger.Info(action, "two-factor code required", requestID, "")
+	} else {
+		logger.Info(action, "user successfully logged in", requestID, "")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
 
-	resp := dto.LoginResponse{
-		AccessToken:  access,
-		RefreshToken: refresh,
+// LoginTwoFactor — второй шаг входа с токеном из ответа /login.
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	action := "login_two_factor"
+	requestID := r.Header.Get("X-Request-ID")
+
+	var req dto.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "challenge_token and code are required", http.StatusBadRequest)
+		return
 	}
 
+	resp, err := h.authService.LoginTwoFactor(r.Context(), req, clientInfo(r))
+	if err != nil {
+		logger.Error(action, "two-factor login failed", requestID, "", err.Error())
+		writeLoginError(w, err)
+		return
+	}
+
+	logger.Info(action, "user successfully logged in", requestID, "")
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
+func writeLoginError(w http.ResponseWriter, err error) {
+	var locked *service.LockoutError
+	switch {
+	case errors.As(err, &locked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrUserBanned), errors.Is(err, service.ErrUserInactive):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidTwoFactorCode),
+		errors.Is(err, service.ErrInvalidChallenge):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		http.Error(w, "failed to login", http.StatusInternalServerError)
+	}
+}
+
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	action := "refresh_token"
 	requestID := r.Header.Get("X-Request-ID")
@@ -112,9 +167,17 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info(action, "refresh token request received", requestID, "")
 
-	resp, err := h.authService.RefreshToken(context.Background(), req)
+	resp, err := h.authService.RefreshToken(context.Background(), req, clientInfo(r))
 	if err != nil {
 		logger.Error(action, "token refresh failed", requestID, "", err.Error())
+		if errors.Is(err, service.ErrUserBanned) || errors.Is(err, service.ErrUserInactive) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if !errors.Is(err, service.ErrInvalidRefreshToken) && !errors.Is(err, service.ErrRefreshTokenReused) {
+			http.Error(w, "failed to refresh token", http.StatusInternalServerError)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
@@ -125,3 +188,67 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	action := "logout_user"
+	requestID := r.Header.Get("X-Request-ID")
+
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		logger.Warn(action, "refresh_token is required", requestID, claims.UserID, "")
+		http.Error(w, "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), claims.UserID, req.RefreshToken, clientInfo(r)); err != nil {
+		logger.Error(action, "logout failed", requestID, claims.UserID, err.Error())
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	action := "logout_all"
+	requestID := r.Header.Get("X-Request-ID")
+
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	if err := h.authService.LogoutAll(r.Context(), claims.UserID, clientInfo(r)); err != nil {
+		logger.Error(action, "logout from all sessions failed", requestID, claims.UserID, err.Error())
+		http.Error(w, "failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientInfo берёт адрес из соединения: заголовкам прокси клиент может подставить что угодно.
+func clientInfo(r *http.Request) model.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return model.ClientInfo{IP: ip, UserAgent: r.UserAgent()}
+}
+
+type KeysHandler struct {
+	keys *jwt.Manager
+}
+
+func NewKeysHandler(keys *jwt.Manager) *KeysHandler {
+	return &KeysHandler{keys: keys}
+}
+
+func (h *KeysHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/user/handler/dto"
+	"ride-hail-system/internal/user/model"
+	"ride-hail-system/internal/user/service"
+)
+
+type ProfileHandler struct {
+	profiles *service.ProfileService
+}
+
+func NewProfileHandler(profiles *service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{profiles: profiles}
+}
+
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	const action = "get_profile"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	profile, err := h.profiles.Get(r.Context(), claims.UserID)
+	if err != nil {
+		writeProfileError(w, r, action, claims.UserID, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, profile)
+}
+
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	const action = "update_profile"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.profiles.Update(r.Context(), claims.UserID, model.Role(claims.Role), req)
+	if err != nil {
+		writeProfileError(w, r, action, claims.UserID, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, profile)
+}
+
+func (h *ProfileHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	const action = "change_password"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profiles.ChangePassword(r.Context(), claims.UserID, claims.SessionID, req); err != nil {
+		writeProfileError(w, r, action, claims.UserID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProfileHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	const action = "change_email"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profiles.RequestEmailChange(r.Context(), claims.UserID, req); err != nil {
+		writeProfileError(w, r, action, claims.UserID, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmEmailChange не требует токена доступа: код из письма сам
+// подтверждает владение новым адресом.
+func (h *ProfileHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	const action = "confirm_email_change"
+
+	var req dto.ConfirmEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profiles.ConfirmEmailChange(r.Context(), req.Token); err != nil {
+		writeProfileError(w, r, action, "", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail, как и ConfirmEmailChange, открыт без токена доступа.
+func (h *ProfileHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	const action = "verify_email"
+
+	var req dto.ConfirmEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profiles.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeProfileError(w, r, action, "", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProfileHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	const action = "send_verification"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	if err := h.profiles.SendVerification(r.Context(), claims.UserID); err != nil {
+		writeProfileError(w, r, action, claims.UserID, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPassword всегда отвечает 202, даже если адрес не зарегистрирован.
+func (h *ProfileHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	const action = "forgot_password"
+
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profiles.ForgotPassword(r.Context(), req.Email); err != nil {
+		logger.Error(action, "failed to process password reset request", r.Header.Get("X-Request-ID"), "", err.Error())
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ProfileHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const action = "reset_password"
+
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profiles.ResetPassword(r.Context(), req); err != nil {
+		writeProfileError(w, r, action, "", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeProfileError(w http.ResponseWriter, r *http.Request, action, userID string, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrWrongPassword):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrVehicleNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrEmailTaken), errors.Is(err, service.ErrAlreadyVerified):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrEmailTokenInvalid):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, service.ErrEmailTokensDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, service.ErrInvalidProfile), errors.Is(err, service.ErrSameEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Error(action, "profile request failed", r.Header.Get("X-Request-ID"), userID, err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func writeProfileJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
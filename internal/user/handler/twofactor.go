@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ride-hail-system/internal/common/auth"
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/user/handler/dto"
+	"ride-hail-system/internal/user/model"
+	"ride-hail-system/internal/user/service"
+)
+
+// TwoFactorHandler — настройка 2FA владельцем аккаунта.
+type TwoFactorHandler struct {
+	twoFactor *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactor *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor}
+}
+
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	const action = "two_factor_status"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	status, err := h.twoFactor.Status(r.Context(), claims.UserID, model.Role(claims.Role))
+	if err != nil {
+		writeTwoFactorError(w, r, action, claims.UserID, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, status)
+}
+
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	const action = "two_factor_enroll"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.TwoFactorEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "password is required", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(r.Context(), claims.UserID, req.Password)
+	if err != nil {
+		writeTwoFactorError(w, r, action, claims.UserID, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, enrollment)
+}
+
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	const action = "two_factor_confirm"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactor.Confirm(r.Context(), claims.UserID, req.Code, clientInfo(r))
+	if err != nil {
+		writeTwoFactorError(w, r, action, claims.UserID, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	const action = "two_factor_disable"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.TwoFactorDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		http.Error(w, "password and code are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.twoFactor.Disable(r.Context(), claims.UserID, model.Role(claims.Role), req.Password, req.Code, clientInfo(r)); err != nil {
+		writeTwoFactorError(w, r, action, claims.UserID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	const action = "two_factor_recovery"
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	var req dto.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, r, action, claims.UserID, err)
+		return
+	}
+	writeProfileJSON(w, http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func writeTwoFactorError(w http.ResponseWriter, r *http.Request, action, userID string, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrWrongPassword), errors.Is(err, service.ErrTwoFactorMandatory):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTwoFactorEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		logger.Error(action, "two-factor request failed", r.Header.Get("X-Request-ID"), userID, err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
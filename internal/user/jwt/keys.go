@@ -0,0 +1,72 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+const AlgEdDSA = "EdDSA"
+
+// SigningKey — пара ключей Ed25519.
+type SigningKey struct {
+	KID        string
+	Algorithm  string
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	CreatedAt  time.Time
+}
+
+// KeyStore хранит ключи, общие для всех реплик сервиса пользователей.
+type KeyStore interface {
+	LoadKeys(ctx context.Context) ([]SigningKey, error)
+	// InsertKeyIfStale сохраняет ключ, только если нет ключа новее notBefore.
+	InsertKeyIfStale(ctx context.Context, key SigningKey, notBefore time.Time) error
+	DeleteKeys(ctx context.Context, kids []string) error
+}
+
+func GenerateSigningKey() (SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return SigningKey{}, err
+	}
+	return SigningKey{
+		KID:        hex.EncodeToString(id),
+		Algorithm:  AlgEdDSA,
+		PrivateKey: priv,
+		PublicKey:  pub,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// JWK — открытый ключ в формате RFC 8037 (OKP, Ed25519).
+type JWK struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	KID     string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k SigningKey) JWK() JWK {
+	return JWK{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       base64.RawURLEncoding.EncodeToString(k.PublicKey),
+		KID:     k.KID,
+		Use:     "sig",
+		Alg:     k.Algorithm,
+	}
+}
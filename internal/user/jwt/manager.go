@@ -1,129 +1,240 @@
 package jwt
 
 import (
+	"context"
+	"crypto/ed25519"
 	"errors"
-	"log"
-	"net/http"
-	"strings"
+	"fmt"
+	"sync"
 	"time"
 
+	"ride-hail-system/internal/common/logger"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Options задаёт сроки токенов и ротацию ключей подписи.
+type Options struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// RotateEvery — как часто выпускается новый ключ подписи.
+	RotateEvery time.Duration
+	// ReloadEvery — как часто реплика перечитывает ключи из хранилища.
+	ReloadEvery time.Duration
+}
+
+// Manager подписывает access-токены EdDSA текущим ключом и проверяет их по
+// всем опубликованным ключам. Ключи общие для реплик и лежат в KeyStore.
 type Manager struct {
-	secretKey  string
-	accessTTL  time.Duration
-	refreshTTL time.Duration
+	store KeyStore
+	opts  Options
+
+	mu      sync.RWMutex
+	signing *SigningKey
+	keys    []SigningKey
+	byKID   map[string]ed25519.PublicKey
 }
 
-func NewManager(secret string, accessTTL, refreshTTL time.Duration) *Manager {
+func NewManager(store KeyStore, opts Options) *Manager {
 	return &Manager{
-		secretKey:  secret,
-		accessTTL:  accessTTL,
-		refreshTTL: refreshTTL,
+		store: store,
+		opts:  opts,
+		byKID: make(map[string]ed25519.PublicKey),
 	}
 }
 
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
-	Type   string `json:"type"` // "access" или "refresh"
+	Type   string `json:"type"` // "access"
+	// SessionID — семейство refresh-токенов, выданное при входе.
+	SessionID string `json:"sid,omitempty"`
+	// EmailUnverified — адрес ещё не подтверждён.
+	EmailUnverified bool `json:"euv,omitempty"`
+	// TwoFactorPending — роль требует 2FA, а она не настроена: токен годится
+	// только для маршрутов без отдельных прав, в том числе для настройки 2FA.
+	TwoFactorPending bool `json:"tfp,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (m *Manager) GenerateAccessToken(userID, role string) (string, error) {
+func (m *Manager) GenerateAccessToken(userID, role, sessionID string, emailVerified, twoFactorPending bool) (string, error) {
+	m.mu.RLock()
+	key := m.signing
+	m.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no signing key loaded")
+	}
+
 	claims := &Claims{
-		UserID: userID,
-		Role:   role,
-		Type:   "access",
+		UserID:           userID,
+		Role:             role,
+		Type:             "access",
+		SessionID:        sessionID,
+		EmailUnverified:  !emailVerified,
+		TwoFactorPending: twoFactorPending,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTTL)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.opts.AccessTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(m.secretKey))
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.PrivateKey)
 }
 
-func (m *Manager) GenerateRefreshToken(userID, role string) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		Role:   role,
-		Type:   "refresh",
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.refreshTTL)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
+func (m *Manager) AccessTTL() time.Duration  { return m.opts.AccessTTL }
+func (m *Manager) RefreshTTL() time.Duration { return m.opts.RefreshTTL }
 
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(m.secretKey))
+func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
+	return parseClaims(tokenString, func(kid string) (ed25519.PublicKey, bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		key, ok := m.byKID[kid]
+		return key, ok
+	})
 }
 
-func (m *Manager) GenerateTokens(userID, role string) (accessToken, refreshToken string, err error) {
-	accessToken, err = m.GenerateAccessToken(userID, role)
+// ValidateAccessToken проверяет токен и то, что это access-, а не refresh-токен.
+func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	claims, err := m.ValidateToken(tokenString)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+	if claims.Type != "access" {
+		return nil, errors.New("not an access token")
 	}
+	return claims, nil
+}
 
-	refreshToken, err = m.GenerateRefreshToken(userID, role)
-	if err != nil {
-		return "", "", err
+// JWKS возвращает открытые ключи, которыми могут быть подписаны ещё живые токены.
+func (m *Manager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(m.keys))}
+	for _, k := range m.keys {
+		set.Keys = append(set.Keys, k.JWK())
 	}
+	return set
+}
 
-	return accessToken, refreshToken, nil
+// Init загружает ключи и создаёт первый, если хранилище пусто.
+func (m *Manager) Init(ctx context.Context) error {
+	return m.rotate(ctx)
 }
 
-func (m *Manager) ParseToken(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(m.secretKey), nil
-	})
+// Run периодически перечитывает ключи, выпускает новый по расписанию и
+// удаляет те, которыми уже не может быть подписан ни один живой токен.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.ReloadEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.rotate(ctx); err != nil {
+				logger.Warn("jwt_key_rotation", "Failed to rotate signing keys", "", "", err.Error())
+			}
+		}
+	}
+}
+
+func (m *Manager) rotate(ctx context.Context) error {
+	keys, err := m.store.LoadKeys(ctx)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	now := time.Now()
+	if len(keys) == 0 || keys[0].CreatedAt.Before(now.Add(-m.opts.RotateEvery)) {
+		key, err := GenerateSigningKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		if err := m.store.InsertKeyIfStale(ctx, key, now.Add(-m.opts.RotateEvery)); err != nil {
+			return fmt.Errorf("failed to store signing key: %w", err)
+		}
+		if keys, err = m.store.LoadKeys(ctx); err != nil {
+			return fmt.Errorf("failed to load signing keys: %w", err)
+		}
+		logger.Info("jwt_key_rotation", "Signing key rotated", "", "")
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	keys, expired := splitExpired(keys, now, m.opts.AccessTTL)
+	if len(expired) > 0 {
+		if err := m.store.DeleteKeys(ctx, expired); err != nil {
+			logger.Warn("jwt_key_rotation", "Failed to delete expired signing keys", "", "", err.Error())
+		}
 	}
 
-	return nil, jwt.ErrSignatureInvalid
+	m.install(keys, now)
+	return nil
 }
 
-func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(m.secretKey), nil
-	})
-	if err != nil {
-		return nil, err
+// install выбирает ключ подписи: самый новый из опубликованных достаточно давно.
+func (m *Manager) install(keys []SigningKey, now time.Time) {
+	byKID := make(map[string]ed25519.PublicKey, len(keys))
+	for _, k := range keys {
+		byKID[k.KID] = k.PublicKey
 	}
 
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, errors.New("invalid token")
+	var signing *SigningKey
+	activeBefore := now.Add(-2 * m.opts.ReloadEvery)
+	for i := range keys {
+		if keys[i].CreatedAt.Before(activeBefore) {
+			signing = &keys[i]
+			break
+		}
+	}
+	if signing == nil && len(keys) > 0 {
+		signing = &keys[len(keys)-1]
 	}
 
-	return claims, nil
+	m.mu.Lock()
+	m.keys = keys
+	m.byKID = byKID
+	m.signing = signing
+	m.mu.Unlock()
 }
 
-func (m *Manager) ExtractClaims(w http.ResponseWriter, r *http.Request) (Claims, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "missing Authorization header", http.StatusUnauthorized)
-		return Claims{}, errors.New("missing Authorization header")
+// splitExpired отделяет ключи, сменённые более AccessTTL назад с запасом:
+// подписанные ими токены уже истекли. keys отсортированы от новых к старым.
+func splitExpired(keys []SigningKey, now time.Time, accessTTL time.Duration) ([]SigningKey, []string) {
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1].CreatedAt.Add(2 * accessTTL).Before(now) {
+			expired := make([]string, 0, len(keys)-i)
+			for _, k := range keys[i:] {
+				expired = append(expired, k.KID)
+			}
+			return keys[:i], expired
+		}
 	}
+	return keys, nil
+}
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		http.Error(w, "invalid Authorization header format", http.StatusUnauthorized)
-		return Claims{}, errors.New("invalid Authorization header")
+// parseClaims проверяет подпись EdDSA ключом из заголовка kid.
+func parseClaims(tokenString string, lookup func(kid string) (ed25519.PublicKey, bool)) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := lookup(kid)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{AlgEdDSA}))
+	if err != nil {
+		return nil, err
 	}
 
-	accessToken := parts[1]
-
-	claims, err := m.ValidateToken(accessToken)
-	if err != nil {
-		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
-		return Claims{}, errors.New("invalid or expired token")
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
 	}
-	log.Println(*claims)
-	return *claims, nil
+
+	return claims, nil
 }
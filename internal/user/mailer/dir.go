@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ride-hail-system/internal/user/model"
+)
+
+// Dir складывает письма в каталог файлами .eml, которые открываются любым почтовым клиентом.
+type Dir struct {
+	dir  string
+	from string
+}
+
+func NewDir(dir, from string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &Dir{dir: dir, from: from}, nil
+}
+
+func (d *Dir) Send(_ context.Context, mail model.Mail) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", d.from)
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(mail.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(mail.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(mail.Body)
+	b.WriteString("\r\n")
+
+	if err := os.WriteFile(filepath.Join(d.dir, name), []byte(b.String()), 0o640); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
+
+// headerValue не даёт подставить в заголовок лишние строки.
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(v)
+}
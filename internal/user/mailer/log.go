@@ -0,0 +1,22 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/user/model"
+)
+
+// Log — почта для локальной разработки: письма не отправляются, а пишутся в
+// лог целиком, вместе с кодами подтверждения.
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (Log) Send(_ context.Context, mail model.Mail) error {
+	logger.Info("mail_log", fmt.Sprintf("To: %s | Subject: %s | %s", mail.To, mail.Subject, mail.Body), "", "")
+	return nil
+}
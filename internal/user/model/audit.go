@@ -0,0 +1,35 @@
+package model
+
+// AuthEventType — вид записи журнала аутентификации.
+type AuthEventType string
+
+const (
+	EventLoginSucceeded AuthEventType = "login_succeeded"
+	EventLoginFailed    AuthEventType = "login_failed"
+	EventLoginLocked    AuthEventType = "login_locked"
+	// EventLoginChallenged — пароль верен, ждём код второго фактора.
+	EventLoginChallenged AuthEventType = "login_challenged"
+	EventTokenRefreshed  AuthEventType = "token_refreshed"
+	EventRefreshFailed   AuthEventType = "refresh_failed"
+	EventRefreshReused   AuthEventType = "refresh_reused"
+	EventLogout          AuthEventType = "logout"
+	EventLogoutAll       AuthEventType = "logout_all"
+	EventTwoFactorOn     AuthEventType = "two_factor_enabled"
+	EventTwoFactorOff    AuthEventType = "two_factor_disabled"
+)
+
+// ClientInfo — откуда пришёл запрос: адрес и User-Agent клиента.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// AuthEvent — запись журнала аутентификации.
+type AuthEvent struct {
+	Event     AuthEventType
+	UserID    string
+	Email     string
+	SessionID string
+	Client    ClientInfo
+	Reason    string
+}
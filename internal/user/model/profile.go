@@ -0,0 +1,53 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Profile — данные пользователя, которые он видит и меняет сам.
+type Profile struct {
+	UserID string     `json:"user_id"`
+	Email  string     `json:"email"`
+	Role   Role       `json:"role"`
+	Status UserStatus `json:"status"`
+	// EmailVerified — адрес подтверждён; до этого часть возможностей закрыта.
+	EmailVerified bool           `json:"email_verified"`
+	Name          string         `json:"name,omitempty"`
+	Phone         string         `json:"phone,omitempty"`
+	Locale        string         `json:"locale,omitempty"`
+	AvatarURL     string         `json:"avatar_url,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Driver        *DriverProfile `json:"driver,omitempty"`
+}
+
+type DriverProfile struct {
+	LicenseNumber string          `json:"license_number"`
+	VehicleType   VehicleType     `json:"vehicle_type"`
+	VehicleAttrs  json.RawMessage `json:"vehicle_attrs,omitempty"`
+	IsVerified    bool            `json:"is_verified"`
+	Rating        float64         `json:"rating"`
+}
+
+// Ключи профиля в users.attrs.
+const (
+	AttrName      = "name"
+	AttrPhone     = "phone"
+	AttrLocale    = "locale"
+	AttrAvatarURL = "avatar_url"
+)
+
+type EmailTokenPurpose string
+
+const (
+	EmailChange       EmailTokenPurpose = "email_change"
+	EmailVerification EmailTokenPurpose = "email_verification"
+	PasswordReset     EmailTokenPurpose = "password_reset"
+)
+
+// Mail — письмо пользователю.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
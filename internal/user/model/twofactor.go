@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// TOTP — секрет второго фактора пользователя.
+type TOTP struct {
+	UserID    string
+	Secret    string
+	EnabledAt *time.Time
+	// LastStep — последний принятый шаг времени; коды этого и более ранних
+	// шагов повторно не принимаются.
+	LastStep *int64
+}
+
+// LoginChallenge — второй шаг входа, выданный после верного пароля.
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	Email     string
+	ExpiresAt time.Time
+	Attempts  int
+	UsedAt    *time.Time
+}
+
+// TwoFactorEnrollment — данные для приложения-аутентификатора.
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus — состояние 2FA пользователя.
+type TwoFactorStatus struct {
+	Enabled  bool `json:"enabled"`
+	Required bool `json:"required"`
+	// RecoveryCodesLeft — сколько неиспользованных кодов восстановления осталось.
+	RecoveryCodesLeft int `json:"recovery_codes_left"`
+}
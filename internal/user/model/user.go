@@ -39,4 +39,23 @@ type User struct {
 	Status       UserStatus      `json:"status" db:"status"`
 	PasswordHash string          `json:"password_hash" db:"password_hash"`
 	Attrs        json.RawMessage `json:"attrs" db:"attrs"`
+	// EmailVerifiedAt пуст, пока пользователь не подтвердил адрес.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+}
+
+// RefreshToken — запись о refresh-токене. Сам токен не хранится, только его хэш.
+type RefreshToken struct {
+	ID         string
+	UserID     string
+	Role       Role
+	UserStatus UserStatus
+	// EmailVerified нужен, чтобы выпустить access-токен с актуальным признаком.
+	EmailVerified bool
+	// TwoFactorEnabled — у владельца включена 2FA; без неё роль, для которой
+	// 2FA обязательна, получает токен только для настройки.
+	TwoFactorEnabled bool
+	FamilyID         string
+	ExpiresAt        time.Time
+	UsedAt           *time.Time
+	RevokedAt        *time.Time
 }
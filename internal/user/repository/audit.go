@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/user/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditRepository пишет журнал аутентификации и счётчики неудачных входов.
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) InsertAuthEvent(ctx context.Context, e model.AuthEvent) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO auth_events (event, user_id, email, session_id, ip, user_agent, reason)
+		VALUES ($1, nullif($2, '')::uuid, nullif($3, ''), nullif($4, '')::uuid, nullif($5, ''), nullif($6, ''), nullif($7, ''))
+	`, e.Event, e.UserID, e.Email, e.SessionID, e.Client.IP, e.Client.UserAgent, e.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to insert auth event: %w", err)
+	}
+	return nil
+}
+
+// LockedUntil возвращает самую позднюю блокировку среди ключей или нулевое
+// время, если ни один ключ не заблокирован.
+func (r *AuditRepository) LockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	var until *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT max(locked_until) FROM login_throttle
+		WHERE key = ANY($1) AND locked_until > now()
+	`, keys).Scan(&until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to check login lockout: %w", err)
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+// RegisterFailure увеличивает счётчик неудач по ключу в пределах window.
+func (r *AuditRepository) RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO login_throttle (key, failures, last_failure_at)
+		VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttle.last_failure_at < now() - make_interval(secs => $2) THEN 1
+				ELSE login_throttle.failures + 1
+			END,
+			last_failure_at = now()
+		RETURNING failures
+	`, key, window.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register login failure: %w", err)
+	}
+	return failures, nil
+}
+
+func (r *AuditRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	if _, err := r.db.Exec(ctx, `UPDATE login_throttle SET locked_until = $2 WHERE key = $1`, key, until); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) ClearFailures(ctx context.Context, key string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM login_throttle WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to clear login failures: %w", err)
+	}
+	return nil
+}
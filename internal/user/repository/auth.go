@@ -8,13 +8,14 @@ import (
 	"ride-hail-system/internal/user/model"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewUserRepository(db *pgx.Conn) *UserRepository {
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
@@ -28,7 +29,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, tx pgx.Tx, user model.U
 	query := `
 		INSERT INTO users (email, role, status, password_hash, attrs)
 		VALUES ($1, $2, COALESCE($3, 'ACTIVE'), $4, $5)
-		RETURNING id, created_at, updated_at, email, role, status, password_hash, attrs
+		RETURNING id, created_at, updated_at, email, role, status, password_hash, attrs, email_verified_at
 	`
 
 	err := tx.QueryRow(
@@ -48,6 +49,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, tx pgx.Tx, user model.U
 		&created.Status,
 		&created.PasswordHash,
 		&created.Attrs,
+		&created.EmailVerifiedAt,
 	)
 	if err != nil {
 		return model.User{}, fmt.Errorf("failed to insert user: %w", err)
@@ -129,7 +131,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (model.Us
 			role,
 			status,
 			password_hash,
-			attrs
+			attrs,
+			email_verified_at
 		FROM users
 		WHERE email = $1
 	`
@@ -143,6 +146,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (model.Us
 		&user.Status,
 		&user.PasswordHash,
 		&user.Attrs,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -153,3 +157,17 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (model.Us
 
 	return user, nil
 }
+
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID, hash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $2, updated_at = now()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, hash); err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	return nil
+}
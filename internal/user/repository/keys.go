@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/user/jwt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// KeyRepository хранит закрытые ключи зашифрованными AES-256-GCM: nonce и
+// шифротекст, kid служит дополнительными данными.
+type KeyRepository struct {
+	db   *pgxpool.Pool
+	aead cipher.AEAD
+}
+
+// NewKeyRepository принимает ключ шифрования в base64, 32 байта.
+func NewKeyRepository(db *pgxpool.Pool, encryptionKey string) (*KeyRepository, error) {
+	if encryptionKey == "" {
+		return nil, errors.New("signing key encryption key is not set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("signing key encryption key is not valid base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("signing key encryption key must be 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyRepository{db: db, aead: aead}, nil
+}
+
+// LoadKeys возвращает ключи подписи от новых к старым. Ключи, сохранённые до
+// шифрования, шифруются на месте.
+func (r *KeyRepository) LoadKeys(ctx context.Context) ([]jwt.SigningKey, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT kid, algorithm, private_key, public_key, created_at
+		FROM signing_keys
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []jwt.SigningKey
+	var plain []jwt.SigningKey
+	for rows.Next() {
+		var k jwt.SigningKey
+		var stored, pub []byte
+		if err := rows.Scan(&k.KID, &k.Algorithm, &stored, &pub, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("signing key %s is malformed", k.KID)
+		}
+
+		if len(stored) == ed25519.PrivateKeySize {
+			k.PrivateKey = ed25519.PrivateKey(stored)
+			plain = append(plain, k)
+		} else if k.PrivateKey, err = r.open(k.KID, stored); err != nil {
+			return nil, err
+		}
+		k.PublicKey = ed25519.PublicKey(pub)
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, k := range plain {
+		if err := r.encryptPlain(ctx, k); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (r *KeyRepository) InsertKeyIfStale(ctx context.Context, key jwt.SigningKey, notBefore time.Time) error {
+	sealed, err := r.seal(key.KID, key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO signing_keys (kid, algorithm, private_key, public_key, created_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM signing_keys WHERE created_at >= $6)
+	`, key.KID, key.Algorithm, sealed, []byte(key.PublicKey), key.CreatedAt, notBefore)
+	if err != nil {
+		return fmt.Errorf("failed to insert signing key: %w", err)
+	}
+	return nil
+}
+
+func (r *KeyRepository) DeleteKeys(ctx context.Context, kids []string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM signing_keys WHERE kid = ANY($1)`, kids); err != nil {
+		return fmt.Errorf("failed to delete signing keys: %w", err)
+	}
+	return nil
+}
+
+// encryptPlain заменяет открытый ключ зашифрованным; другая реплика могла
+// сделать это раньше, тогда строка не изменится.
+func (r *KeyRepository) encryptPlain(ctx context.Context, key jwt.SigningKey) error {
+	sealed, err := r.seal(key.KID, key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		UPDATE signing_keys SET private_key = $2
+		WHERE kid = $1 AND private_key = $3
+	`, key.KID, sealed, []byte(key.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", key.KID, err)
+	}
+	return nil
+}
+
+func (r *KeyRepository) seal(kid string, priv ed25519.PrivateKey) ([]byte, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return r.aead.Seal(nonce, nonce, priv, []byte(kid)), nil
+}
+
+func (r *KeyRepository) open(kid string, sealed []byte) (ed25519.PrivateKey, error) {
+	n := r.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("signing key %s is malformed", kid)
+	}
+	priv, err := r.aead.Open(nil, sealed[:n], sealed[n:], []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key %s: %w", kid, err)
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("signing key %s is malformed", kid)
+	}
+	return ed25519.PrivateKey(priv), nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"ride-hail-system/internal/user/jwt"
+)
+
+func TestSigningKeyEncryption(t *testing.T) {
+	repo, err := NewKeyRepository(nil, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatalf("NewKeyRepository: %v", err)
+	}
+	key, err := jwt.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+
+	sealed, err := repo.seal(key.KID, key.PrivateKey)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if bytes.Contains(sealed, key.PrivateKey.Seed()) {
+		t.Fatal("sealed key contains the private seed")
+	}
+
+	priv, err := repo.open(key.KID, sealed)
+	if err != nil || !priv.Equal(key.PrivateKey) {
+		t.Fatalf("open = %v, want original key", err)
+	}
+	// Шифротекст привязан к kid: подмена строки не пройдёт.
+	if _, err := repo.open("other", sealed); err == nil {
+		t.Fatal("open accepted key under another kid")
+	}
+}
+
+func TestNewKeyRepositoryRejectsBadKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"empty", "", "not set"},
+		{"not base64", "???", "base64"},
+		{"short", base64.StdEncoding.EncodeToString(make([]byte, 16)), "32 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyRepository(nil, tt.key); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewKeyRepository(%q) = %v, want error containing %q", tt.key, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/user/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailTaken         = errors.New("email is already in use")
+	ErrEmailTokenNotFound = errors.New("token is invalid, expired or already used")
+)
+
+func (r *UserRepository) GetByID(ctx context.Context, userID string) (model.User, error) {
+	var user model.User
+	err := r.db.QueryRow(ctx, `
+		SELECT id, created_at, updated_at, email, role, status, password_hash, attrs, email_verified_at
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Email, &user.Role, &user.Status, &user.PasswordHash, &user.Attrs, &user.EmailVerifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, fmt.Errorf("failed to fetch user by id: %w", err)
+	}
+	return user, nil
+}
+
+// GetProfile собирает профиль из users.attrs и, для водителя, из drivers.
+func (r *UserRepository) GetProfile(ctx context.Context, userID string) (model.Profile, error) {
+	var p model.Profile
+	var attrs []byte
+	var license, vehicleType *string
+	var vehicleAttrs []byte
+	var verified *bool
+	var rating *float64
+
+	err := r.db.QueryRow(ctx, `
+		SELECT u.id::text, u.email, u.role, u.status, u.email_verified_at IS NOT NULL, u.created_at, coalesce(u.attrs, '{}'::jsonb),
+		       d.license_number, d.vehicle_type, d.vehicle_attrs, d.is_verified, d.rating
+		FROM users u
+		LEFT JOIN drivers d ON d.id = u.id
+		WHERE u.id = $1
+	`, userID).Scan(&p.UserID, &p.Email, &p.Role, &p.Status, &p.EmailVerified, &p.CreatedAt, &attrs,
+		&license, &vehicleType, &vehicleAttrs, &verified, &rating)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Profile{}, ErrUserNotFound
+		}
+		return model.Profile{}, fmt.Errorf("failed to fetch profile: %w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(attrs, &fields); err != nil {
+		return model.Profile{}, fmt.Errorf("failed to decode user attrs: %w", err)
+	}
+	p.Name, _ = fields[model.AttrName].(string)
+	p.Phone, _ = fields[model.AttrPhone].(string)
+	p.Locale, _ = fields[model.AttrLocale].(string)
+	p.AvatarURL, _ = fields[model.AttrAvatarURL].(string)
+
+	if license != nil {
+		p.Driver = &model.DriverProfile{
+			LicenseNumber: *license,
+			VehicleAttrs:  vehicleAttrs,
+		}
+		if vehicleType != nil {
+			p.Driver.VehicleType = model.VehicleType(*vehicleType)
+		}
+		if verified != nil {
+			p.Driver.IsVerified = *verified
+		}
+		if rating != nil {
+			p.Driver.Rating = *rating
+		}
+	}
+	return p, nil
+}
+
+// UpdateProfileAttrs записывает set в users.attrs и удаляет ключи unset.
+func (r *UserRepository) UpdateProfileAttrs(ctx context.Context, userID string, set map[string]string, unset []string) error {
+	patch, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	if unset == nil {
+		unset = []string{}
+	}
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users
+		SET attrs = (coalesce(attrs, '{}'::jsonb) || $2::jsonb) - $3::text[], updated_at = now()
+		WHERE id = $1
+	`, userID, patch, unset)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateVehicle меняет данные автомобиля и снимает допуск водителя.
+func (r *UserRepository) UpdateVehicle(ctx context.Context, driverID string, attrs json.RawMessage) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE drivers
+		SET vehicle_attrs = $2, is_verified = false,
+		    status = CASE WHEN status = 'AVAILABLE' THEN 'OFFLINE' ELSE status END,
+		    updated_at = now()
+		WHERE id = $1
+	`, driverID, attrs)
+	if err != nil {
+		return fmt.Errorf("failed to update vehicle: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE driver_sessions SET ended_at = now()
+		WHERE driver_id = $1 AND ended_at IS NULL
+		  AND EXISTS (SELECT 1 FROM drivers WHERE id = $1 AND status = 'OFFLINE')
+	`, driverID); err != nil {
+		return fmt.Errorf("failed to close driver session: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE driver_documents
+		SET status = 'PENDING', reviewed_at = NULL, reviewed_by = NULL, reject_reason = NULL
+		WHERE driver_id = $1 AND status = 'APPROVED' AND kind IN ('insurance', 'vehicle_registration')
+	`, driverID); err != nil {
+		return fmt.Errorf("failed to requeue vehicle documents: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *UserRepository) InsertEmailToken(ctx context.Context, userID string, purpose model.EmailTokenPurpose, email, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO email_tokens (user_id, purpose, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, purpose, email, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert email token: %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange гасит токен смены адреса и переносит на пользователя
+// адрес, на который токен был отправлен.
+func (r *UserRepository) ConfirmEmailChange(ctx context.Context, tokenHash string) (string, string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := consumeEmailToken(ctx, tx, tokenHash, model.EmailChange)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Письмо дошло до нового адреса, значит он подтверждён.
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET email = $2, email_verified_at = now(), updated_at = now() WHERE id = $1
+	`, userID, email); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return "", "", ErrEmailTaken
+		}
+		return "", "", fmt.Errorf("failed to update email: %w", err)
+	}
+
+	// Остальные незавершённые смены адреса больше не нужны, а ссылки на старый адрес — тем более.
+	if _, err := tx.Exec(ctx, `
+		UPDATE email_tokens SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return "", "", fmt.Errorf("failed to invalidate email tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", "", fmt.Errorf("failed to commit email change: %w", err)
+	}
+	return userID, email, nil
+}
+
+// VerifyEmail гасит токен подтверждения и отмечает адрес подтверждённым.
+func (r *UserRepository) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := consumeEmailToken(ctx, tx, tokenHash, model.EmailVerification)
+	if err != nil {
+		return "", err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET email_verified_at = coalesce(email_verified_at, now()), updated_at = now()
+		WHERE id = $1 AND email = $2
+	`, userID, email)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify email: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return "", ErrEmailTokenNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE email_tokens SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userID, model.EmailVerification); err != nil {
+		return "", fmt.Errorf("failed to invalidate email tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit email verification: %w", err)
+	}
+	return userID, nil
+}
+
+// ResetPassword гасит токены сброса, записывает пароль и подтверждает email.
+func (r *UserRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := consumeEmailToken(ctx, tx, tokenHash, model.PasswordReset)
+	if err != nil {
+		return "", "", err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $3, email_verified_at = coalesce(email_verified_at, now()), updated_at = now()
+		WHERE id = $1 AND email = $2
+	`, userID, email, passwordHash)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to reset password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return "", "", ErrEmailTokenNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE email_tokens SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userID, model.PasswordReset); err != nil {
+		return "", "", fmt.Errorf("failed to invalidate email tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", "", fmt.Errorf("failed to commit password reset: %w", err)
+	}
+	return userID, email, nil
+}
+
+// consumeEmailToken помечает токен использованным и возвращает владельца и
+// адрес, на который токен был отправлен.
+func consumeEmailToken(ctx context.Context, tx pgx.Tx, tokenHash string, purpose model.EmailTokenPurpose) (string, string, error) {
+	var userID, email string
+	err := tx.QueryRow(ctx, `
+		UPDATE email_tokens SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id::text, email
+	`, tokenHash, purpose).Scan(&userID, &email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", ErrEmailTokenNotFound
+		}
+		return "", "", fmt.Errorf("failed to consume email token: %w", err)
+	}
+	return userID, email, nil
+}
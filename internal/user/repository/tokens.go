@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/user/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrTokenNotFound = errors.New("refresh token not found")
+
+type TokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRepository(db *pgxpool.Pool) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) InsertRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id::text
+	`, userID, familyID, tokenHash, expiresAt).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+	return id, nil
+}
+
+// GetRefreshToken ищет токен по хэшу вместе с ролью и статусом владельца,
+// признаком подтверждённого адреса и включённой 2FA.
+func (r *TokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
+	var t model.RefreshToken
+	err := r.db.QueryRow(ctx, `
+		SELECT t.id::text, t.user_id::text, u.role, u.status, u.email_verified_at IS NOT NULL,
+		       EXISTS (SELECT 1 FROM user_totp o WHERE o.user_id = u.id AND o.enabled_at IS NOT NULL),
+		       t.family_id::text, t.expires_at, t.used_at, t.revoked_at
+		FROM refresh_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.Role, &t.UserStatus, &t.EmailVerified, &t.TwoFactorEnabled, &t.FamilyID, &t.ExpiresAt, &t.UsedAt, &t.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.RefreshToken{}, ErrTokenNotFound
+		}
+		return model.RefreshToken{}, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return t, nil
+}
+
+// RotateRefreshToken помечает токен использованным и выпускает следующий в семействе.
+func (r *TokenRepository) RotateRefreshToken(ctx context.Context, old model.RefreshToken, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var newID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id::text
+	`, old.UserID, old.FamilyID, tokenHash, expiresAt).Scan(&newID)
+	if err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = now(), replaced_by = $2
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`, old.ID, newID)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTokenNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+// RevokeUser отзывает все токены пользователя и возвращает отозванные семейства.
+func (r *TokenRepository) RevokeUser(ctx context.Context, userID, reason string, at time.Time) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE refresh_tokens SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING family_id::text
+	`, userID, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	families, err := distinctFamilies(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO token_revocations (user_id, revoked_before, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before, reason = EXCLUDED.reason
+	`, userID, at, reason); err != nil {
+		return nil, fmt.Errorf("failed to record revocation: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit revocation: %w", err)
+	}
+	return families, nil
+}
+
+// RevokeOtherFamilies отзывает все сессии пользователя, кроме keepFamilyID.
+func (r *TokenRepository) RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL AND family_id::text <> $2
+		RETURNING family_id::text
+	`, userID, keepFamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	families, err := distinctFamilies(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return families, nil
+}
+
+func distinctFamilies(rows pgx.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var families []string
+	seen := make(map[string]struct{})
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			families = append(families, id)
+		}
+	}
+	return families, rows.Err()
+}
+
+// LoadRevocations возвращает отозванных пользователей и сессии за последние window.
+func (r *TokenRepository) LoadRevocations(ctx context.Context, window time.Duration) (map[string]time.Time, map[string]struct{}, error) {
+	users := make(map[string]time.Time)
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id::text, revoked_before
+		FROM token_revocations
+		WHERE revoked_before > now() - make_interval(secs => $1)
+		UNION ALL
+		SELECT id::text, '9999-12-31'::timestamptz
+		FROM users
+		WHERE status IN ('BANNED', 'INACTIVE')
+	`, window.Seconds())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load user revocations: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var before time.Time
+		if err := rows.Scan(&id, &before); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan user revocation: %w", err)
+		}
+		if before.After(users[id]) {
+			users[id] = before
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sessions := make(map[string]struct{})
+	rows, err = r.db.Query(ctx, `
+		SELECT DISTINCT family_id::text
+		FROM refresh_tokens
+		WHERE revoked_at > now() - make_interval(secs => $1)
+	`, window.Seconds())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load session revocations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan session revocation: %w", err)
+		}
+		sessions[id] = struct{}{}
+	}
+	return users, sessions, rows.Err()
+}
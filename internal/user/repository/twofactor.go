@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ride-hail-system/internal/user/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTOTPNotFound         = errors.New("two-factor authentication is not set up")
+	ErrTOTPEnabled          = errors.New("two-factor authentication is already enabled")
+	ErrTOTPStepUsed         = errors.New("code has already been used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code is invalid or already used")
+	ErrChallengeNotFound    = errors.New("login challenge is invalid, expired or already used")
+)
+
+// TwoFactorRepository хранит секреты TOTP, коды восстановления и вторые шаги входа.
+type TwoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID string) (model.TOTP, error) {
+	t := model.TOTP{UserID: userID}
+	err := r.db.QueryRow(ctx, `
+		SELECT secret, enabled_at, last_step FROM user_totp WHERE user_id = $1
+	`, userID).Scan(&t.Secret, &t.EnabledAt, &t.LastStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.TOTP{}, ErrTOTPNotFound
+		}
+		return model.TOTP{}, fmt.Errorf("failed to get totp: %w", err)
+	}
+	return t, nil
+}
+
+// SetPendingTOTP сохраняет новый секрет, ожидающий подтверждения, поверх
+// прежнего неподтверждённого. Включённую 2FA не трогает.
+func (r *TwoFactorRepository) SetPendingTOTP(ctx context.Context, userID, secret string) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, created_at = now(), last_step = NULL
+		WHERE user_totp.enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPEnabled
+	}
+	return nil
+}
+
+// EnableTOTP включает 2FA и заменяет коды восстановления новыми.
+func (r *TwoFactorRepository) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_totp SET enabled_at = now(), last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// AdvanceStep запоминает принятый шаг.
+func (r *TwoFactorRepository) AdvanceStep(ctx context.Context, userID string, step int64) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_totp SET last_step = $2
+		WHERE user_id = $1 AND (last_step IS NULL OR last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to store totp step: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPStepUsed
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE totp_recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT count(*) FROM totp_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteTOTP выключает 2FA вместе с кодами восстановления.
+func (r *TwoFactorRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete totp: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepository) InsertChallenge(ctx context.Context, userID, email, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO login_challenges (user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, userID, email, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert login challenge: %w", err)
+	}
+	return nil
+}
+
+// AttemptChallenge засчитывает попытку ответа на второй шаг и возвращает его.
+func (r *TwoFactorRepository) AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int) (model.LoginChallenge, error) {
+	var c model.LoginChallenge
+	err := r.db.QueryRow(ctx, `
+		UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+		RETURNING id::text, user_id::text, email, expires_at, attempts
+	`, tokenHash, maxAttempts).Scan(&c.ID, &c.UserID, &c.Email, &c.ExpiresAt, &c.Attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.LoginChallenge{}, ErrChallengeNotFound
+		}
+		return model.LoginChallenge{}, fmt.Errorf("failed to get login challenge: %w", err)
+	}
+	return c, nil
+}
+
+func (r *TwoFactorRepository) ConsumeChallenge(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE login_challenges SET used_at = now() WHERE id = $1 AND used_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to consume login challenge: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChallengeNotFound
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO totp_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`, userID, codeHashes); err != nil {
+		return fmt.Errorf("failed to insert recovery codes: %w", err)
+	}
+	return nil
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
-	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"ride-hail-system/internal/common/logger"
 	"ride-hail-system/internal/user/handler/dto"
 	"ride-hail-system/internal/user/model"
+	"ride-hail-system/internal/user/repository"
 	"ride-hail-system/pkg/uuid"
 
 	token "ride-hail-system/internal/user/jwt"
@@ -23,16 +27,56 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, tx pgx.Tx, user model.User) (model.User, error)
 	CreateDriver(ctx context.Context, tx pgx.Tx, driver model.Driver) (model.Driver, error)
 	GetByEmail(ctx context.Context, email string) (model.User, error)
+	GetByID(ctx context.Context, userID string) (model.User, error)
+	UpdatePasswordHash(ctx context.Context, userID, hash string) error
 	BeginTx(ctx context.Context) (pgx.Tx, error)
 }
 
+// TokenRepository хранит хэши refresh-токенов, сгруппированные в семейства по сессиям входа.
+type TokenRepository interface {
+	InsertRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (string, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, old model.RefreshToken, tokenHash string, expiresAt time.Time) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID, reason string, at time.Time) ([]string, error)
+	RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID string) ([]string, error)
+}
+
+// AuditLog — журнал аутентификации для разбора инцидентов.
+type AuditLog interface {
+	InsertAuthEvent(ctx context.Context, e model.AuthEvent) error
+}
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrUserBanned          = errors.New("user is banned")
+	ErrUserInactive        = errors.New("user account is inactive")
+)
+
 type AuthService struct {
-	userRepo   UserRepository
-	jwtManager *token.Manager
+	userRepo    UserRepository
+	tokenRepo   TokenRepository
+	jwtManager  *token.Manager
+	revocations *RevocationList
+	passwords   *PasswordHasher
+	audit       AuditLog
+	throttle    *LoginThrottle
+	twoFactor   *TwoFactorService
 }
 
-func NewAuthService(userRepo UserRepository, tokenManager *token.Manager) *AuthService {
-	return &AuthService{userRepo: userRepo, jwtManager: tokenManager}
+func NewAuthService(userRepo UserRepository, tokenRepo TokenRepository, tokenManager *token.Manager, revocations *RevocationList, passwords *PasswordHasher, audit AuditLog, throttle *LoginThrottle, twoFactor *TwoFactorService) *AuthService {
+	return &AuthService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		jwtManager:  tokenManager,
+		revocations: revocations,
+		passwords:   passwords,
+		audit:       audit,
+		throttle:    throttle,
+		twoFactor:   twoFactor,
+	}
 }
 
 func (s *AuthService) Register(ctx context.Context, req dto.RegisterRequest) (model.User, error) {
@@ -60,7 +104,7 @@ func (s *AuthService) Register(ctx context.Context, req dto.RegisterRequest) (mo
 		}
 	}()
 
-	hash, err := hashPassword(req.Password)
+	hash, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		logger.Error(action, "failed to hash password", fmt.Sprint(requestID), "", err.Error())
 		return model.User{}, err
@@ -72,13 +116,18 @@ func (s *AuthService) Register(ctx context.Context, req dto.RegisterRequest) (mo
 		return model.User{}, err
 	}
 
+	attrs, err := json.Marshal(map[string]string{model.AttrName: strings.TrimSpace(req.Name)})
+	if err != nil {
+		return model.User{}, err
+	}
+
 	user := model.User{
 		ID:           uuid.UUID(userID),
 		Email:        req.Email,
 		PasswordHash: hash,
 		Role:         req.Role,
 		Status:       model.UserActive,
-		Attrs:        json.RawMessage(fmt.Sprintf(`{"name":"%s"}`, req.Name)),
+		Attrs:        attrs,
 	}
 
 	createdUser, err := s.userRepo.CreateUser(ctx, tx, user)
@@ -121,7 +170,8 @@ func (s *AuthService) Register(ctx context.Context, req dto.RegisterRequest) (mo
 	return createdUser, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, string, error) {
+// Login проверяет пароль; при включённой 2FA возвращает токен второго шага.
+func (s *AuthService) Login(ctx context.Context, email, password string, client model.ClientInfo) (dto.LoginResponse, error) {
 	action := "login_user"
 	requestID := ctx.Value("request_id")
 	if requestID == nil {
@@ -130,28 +180,152 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (string
 
 	logger.Info(action, fmt.Sprintf("login attempt for user: %s", email), fmt.Sprint(requestID), "")
 
+	if err := s.throttle.Check(ctx, email, client.IP); err != nil {
+		var locked *LockoutError
+		if !errors.As(err, &locked) {
+			logger.Error(action, "failed to check login throttle", fmt.Sprint(requestID), "", err.Error())
+			return dto.LoginResponse{}, err
+		}
+		logger.Warn(action, "login attempt while locked out", fmt.Sprint(requestID), "", email+" from "+client.IP)
+		s.record(ctx, model.AuthEvent{Event: model.EventLoginLocked, Email: email, Client: client})
+		return dto.LoginResponse{}, err
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn(action, "unknown email", fmt.Sprint(requestID), "", email)
+		return dto.LoginResponse{}, s.loginFailed(ctx, fmt.Sprint(requestID), "", email, client, "unknown_email")
+	}
 	if err != nil {
-		logger.Error(action, "user not found", fmt.Sprint(requestID), "", err.Error())
-		return "", "", fmt.Errorf("user not found: %w", err)
+		logger.Error(action, "failed to fetch user", fmt.Sprint(requestID), "", err.Error())
+		return dto.LoginResponse{}, err
 	}
 
-	if !checkPassword(user.PasswordHash, password) {
+	ok, needsRehash := s.passwords.Verify(user.PasswordHash, password)
+	if !ok {
 		logger.Warn(action, "invalid credentials", fmt.Sprint(requestID), string(user.ID), "")
-		return "", "", fmt.Errorf("invalid credentials")
+		return dto.LoginResponse{}, s.loginFailed(ctx, fmt.Sprint(requestID), string(user.ID), email, client, "wrong_password")
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, action, fmt.Sprint(requestID), string(user.ID), password)
 	}
 
-	access, refresh, err := s.jwtManager.GenerateTokens(string(user.ID), string(user.Role))
+	if err := statusError(user.Status); err != nil {
+		logger.Warn(action, "blocked user attempted to log in", fmt.Sprint(requestID), string(user.ID), string(user.Status))
+		s.record(ctx, model.AuthEvent{Event: model.EventLoginFailed, UserID: string(user.ID), Email: email, Client: client, Reason: strings.ToLower(string(user.Status))})
+		return dto.LoginResponse{}, err
+	}
+	enabled, err := s.twoFactor.Enabled(ctx, string(user.ID))
 	if err != nil {
-		logger.Error(action, "failed to generate tokens", fmt.Sprint(requestID), string(user.ID), err.Error())
-		return "", "", err
+		logger.Error(action, "failed to check two-factor status", fmt.Sprint(requestID), string(user.ID), err.Error())
+		return dto.LoginResponse{}, err
+	}
+	if enabled {
+		challenge, err := s.twoFactor.StartChallenge(ctx, string(user.ID), email)
+		if err != nil {
+			logger.Error(action, "failed to start two-factor challenge", fmt.Sprint(requestID), string(user.ID), err.Error())
+			return dto.LoginResponse{}, err
+		}
+		logger.Info(action, "password accepted, waiting for two-factor code", fmt.Sprint(requestID), string(user.ID))
+		s.record(ctx, model.AuthEvent{Event: model.EventLoginChallenged, UserID: string(user.ID), Email: email, Client: client})
+		return dto.LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	return s.startSession(ctx, action, fmt.Sprint(requestID), user, email, false, client)
+}
+
+// LoginTwoFactor завершает вход кодом второго фактора.
+func (s *AuthService) LoginTwoFactor(ctx context.Context, req dto.TwoFactorLoginRequest, client model.ClientInfo) (dto.LoginResponse, error) {
+	action := "login_two_factor"
+	requestID := ctx.Value("request_id")
+	if requestID == nil {
+		requestID = "none"
+	}
+
+	challenge, err := s.twoFactor.AttemptChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		logger.Warn(action, "invalid login challenge", fmt.Sprint(requestID), "", err.Error())
+		return dto.LoginResponse{}, err
+	}
+
+	if err := s.throttle.Check(ctx, challenge.Email, client.IP); err != nil {
+		var locked *LockoutError
+		if !errors.As(err, &locked) {
+			logger.Error(action, "failed to check login throttle", fmt.Sprint(requestID), challenge.UserID, err.Error())
+			return dto.LoginResponse{}, err
+		}
+		s.record(ctx, model.AuthEvent{Event: model.EventLoginLocked, UserID: challenge.UserID, Email: challenge.Email, Client: client})
+		return dto.LoginResponse{}, err
+	}
+
+	err = s.twoFactor.Verify(ctx, challenge.UserID, req.Code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		logger.Warn(action, "invalid two-factor code", fmt.Sprint(requestID), challenge.UserID, "")
+		if err := s.loginFailed(ctx, fmt.Sprint(requestID), challenge.UserID, challenge.Email, client, "wrong_two_factor_code"); errors.Is(err, ErrTooManyAttempts) {
+			return dto.LoginResponse{}, err
+		}
+		return dto.LoginResponse{}, ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		logger.Error(action, "failed to verify two-factor code", fmt.Sprint(requestID), challenge.UserID, err.Error())
+		return dto.LoginResponse{}, err
+	}
+	if err := s.twoFactor.ConsumeChallenge(ctx, challenge.ID); err != nil {
+		return dto.LoginResponse{}, err
+	}
+
+	// Статус мог измениться, пока пользователь вводил код.
+	user, err := s.userRepo.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		logger.Error(action, "failed to fetch user", fmt.Sprint(requestID), challenge.UserID, err.Error())
+		return dto.LoginResponse{}, err
+	}
+	if err := statusError(user.Status); err != nil {
+		s.record(ctx, model.AuthEvent{Event: model.EventLoginFailed, UserID: challenge.UserID, Email: challenge.Email, Client: client, Reason: strings.ToLower(string(user.Status))})
+		return dto.LoginResponse{}, err
+	}
+
+	return s.startSession(ctx, action, fmt.Sprint(requestID), user, challenge.Email, true, client)
+}
+
+// startSession открывает сессию после успешного входа: новое семейство
+// refresh-токенов и access-токен к нему.
+func (s *AuthService) startSession(ctx context.Context, action, requestID string, user model.User, email string, twoFactorPassed bool, client model.ClientInfo) (dto.LoginResponse, error) {
+	if err := s.throttle.Succeed(ctx, email); err != nil {
+		logger.Warn(action, "failed to reset login failures", requestID, string(user.ID), err.Error())
+	}
+
+	familyID, err := uuid.NewUUID()
+	if err != nil {
+		logger.Error(action, "failed to generate session ID", requestID, string(user.ID), err.Error())
+		return dto.LoginResponse{}, err
+	}
+
+	refresh, hash, err := newOpaqueToken()
+	if err != nil {
+		logger.Error(action, "failed to generate refresh token", requestID, string(user.ID), err.Error())
+		return dto.LoginResponse{}, err
+	}
+	if _, err := s.tokenRepo.InsertRefreshToken(ctx, string(user.ID), familyID, hash, time.Now().Add(s.jwtManager.RefreshTTL())); err != nil {
+		logger.Error(action, "failed to store refresh token", requestID, string(user.ID), err.Error())
+		return dto.LoginResponse{}, err
+	}
+
+	// Без второго фактора роль, для которой он обязателен, получает токен только для его настройки.
+	pending := !twoFactorPassed && s.twoFactor.Required(user.Role)
+	access, err := s.jwtManager.GenerateAccessToken(string(user.ID), string(user.Role), familyID, user.EmailVerifiedAt != nil, pending)
+	if err != nil {
+		logger.Error(action, "failed to generate access token", requestID, string(user.ID), err.Error())
+		return dto.LoginResponse{}, err
 	}
 
-	logger.Info(action, "user successfully logged in", fmt.Sprint(requestID), string(user.ID))
-	return access, refresh, nil
+	logger.Info(action, "user successfully logged in", requestID, string(user.ID))
+	s.record(ctx, model.AuthEvent{Event: model.EventLoginSucceeded, UserID: string(user.ID), Email: email, SessionID: familyID, Client: client})
+	return dto.LoginResponse{AccessToken: access, RefreshToken: refresh}, nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, req dto.RefreshTokenRequest) (dto.RefreshTokenResponse, error) {
+// RefreshToken обменивает refresh-токен на новую пару; повтор отзывает всё семейство.
+func (s *AuthService) RefreshToken(ctx context.Context, req dto.RefreshTokenRequest, client model.ClientInfo) (dto.RefreshTokenResponse, error) {
 	action := "refresh_token"
 	requestID := ctx.Value("request_id")
 	if requestID == nil {
@@ -160,30 +334,58 @@ func (s *AuthService) RefreshToken(ctx context.Context, req dto.RefreshTokenRequ
 
 	logger.Info(action, "refresh token process started", fmt.Sprint(requestID), "")
 
-	claims, err := s.jwtManager.ParseToken(req.RefreshToken)
+	current, err := s.tokenRepo.GetRefreshToken(ctx, hashToken(req.RefreshToken))
 	if err != nil {
-		logger.Error(action, "invalid refresh token", fmt.Sprint(requestID), "", err.Error())
-		return dto.RefreshTokenResponse{}, fmt.Errorf("invalid refresh token: %w", err)
+		logger.Warn(action, "unknown refresh token", fmt.Sprint(requestID), "", err.Error())
+		s.record(ctx, model.AuthEvent{Event: model.EventRefreshFailed, Client: client, Reason: "unknown_token"})
+		return dto.RefreshTokenResponse{}, ErrInvalidRefreshToken
 	}
 
-	if claims.Type != "refresh" {
-		logger.Warn(action, "provided token is not a refresh token", fmt.Sprint(requestID), claims.UserID, "")
-		return dto.RefreshTokenResponse{}, fmt.Errorf("provided token is not a refresh token")
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		s.revokeFamily(ctx, action, fmt.Sprint(requestID), current, client)
+		return dto.RefreshTokenResponse{}, ErrRefreshTokenReused
+	}
+	if time.Now().After(current.ExpiresAt) {
+		logger.Warn(action, "refresh token expired", fmt.Sprint(requestID), current.UserID, "")
+		s.record(ctx, model.AuthEvent{Event: model.EventRefreshFailed, UserID: current.UserID, SessionID: current.FamilyID, Client: client, Reason: "expired"})
+		return dto.RefreshTokenResponse{}, ErrInvalidRefreshToken
+	}
+	if err := statusError(current.UserStatus); err != nil {
+		logger.Warn(action, "refresh attempted by blocked user", fmt.Sprint(requestID), current.UserID, string(current.UserStatus))
+		s.record(ctx, model.AuthEvent{Event: model.EventRefreshFailed, UserID: current.UserID, SessionID: current.FamilyID, Client: client, Reason: strings.ToLower(string(current.UserStatus))})
+		if err := s.tokenRepo.RevokeFamily(ctx, current.FamilyID); err != nil {
+			logger.Error(action, "failed to revoke token family", fmt.Sprint(requestID), current.UserID, err.Error())
+		}
+		s.revocations.RevokeSession(current.FamilyID)
+		return dto.RefreshTokenResponse{}, err
 	}
 
-	accessToken, err := s.jwtManager.GenerateAccessToken(claims.UserID, claims.Role)
+	refreshToken, hash, err := newOpaqueToken()
 	if err != nil {
-		logger.Error(action, "failed to generate access token", fmt.Sprint(requestID), claims.UserID, err.Error())
-		return dto.RefreshTokenResponse{}, fmt.Errorf("failed to generate access token: %w", err)
+		logger.Error(action, "failed to generate refresh token", fmt.Sprint(requestID), current.UserID, err.Error())
+		return dto.RefreshTokenResponse{}, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	refreshToken, err := s.jwtManager.GenerateRefreshToken(claims.UserID, claims.Role)
+	err = s.tokenRepo.RotateRefreshToken(ctx, current, hash, time.Now().Add(s.jwtManager.RefreshTTL()))
+	if errors.Is(err, repository.ErrTokenNotFound) {
+		// Токен успели использовать параллельно — это тоже повторное предъявление.
+		s.revokeFamily(ctx, action, fmt.Sprint(requestID), current, client)
+		return dto.RefreshTokenResponse{}, ErrRefreshTokenReused
+	}
 	if err != nil {
-		logger.Error(action, "failed to generate refresh token", fmt.Sprint(requestID), claims.UserID, err.Error())
-		return dto.RefreshTokenResponse{}, fmt.Errorf("failed to generate refresh token: %w", err)
+		logger.Error(action, "failed to rotate refresh token", fmt.Sprint(requestID), current.UserID, err.Error())
+		return dto.RefreshTokenResponse{}, err
 	}
 
-	logger.Info(action, "tokens successfully refreshed", fmt.Sprint(requestID), claims.UserID)
+	accessToken, err := s.jwtManager.GenerateAccessToken(current.UserID, string(current.Role), current.FamilyID, current.EmailVerified,
+		!current.TwoFactorEnabled && s.twoFactor.Required(current.Role))
+	if err != nil {
+		logger.Error(action, "failed to generate access token", fmt.Sprint(requestID), current.UserID, err.Error())
+		return dto.RefreshTokenResponse{}, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	logger.Info(action, "tokens successfully refreshed", fmt.Sprint(requestID), current.UserID)
+	s.record(ctx, model.AuthEvent{Event: model.EventTokenRefreshed, UserID: current.UserID, SessionID: current.FamilyID, Client: client})
 
 	return dto.RefreshTokenResponse{
 		AccessToken:  accessToken,
@@ -191,35 +393,144 @@ func (s *AuthService) RefreshToken(ctx context.Context, req dto.RefreshTokenRequ
 	}, nil
 }
 
-func hashPassword(password string) (string, error) {
-	salt := make([]byte, 16)
-	_, err := rand.Read(salt)
-	if err != nil {
-		return "", err
+// Logout завершает сессию, к которой относится refresh-токен.
+func (s *AuthService) Logout(ctx context.Context, userID, refreshToken string, client model.ClientInfo) error {
+	action := "logout_user"
+	requestID := ctx.Value("request_id")
+	if requestID == nil {
+		requestID = "none"
 	}
 
-	hash := sha256.Sum256(append(salt, []byte(password)...))
+	current, err := s.tokenRepo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil || current.UserID != userID {
+		logger.Warn(action, "refresh token does not belong to user", fmt.Sprint(requestID), userID, "")
+		return ErrInvalidRefreshToken
+	}
 
-	return fmt.Sprintf("%x:%x", salt, hash[:]), nil
+	if err := s.tokenRepo.RevokeFamily(ctx, current.FamilyID); err != nil {
+		logger.Error(action, "failed to revoke session", fmt.Sprint(requestID), userID, err.Error())
+		return err
+	}
+	s.revocations.RevokeSession(current.FamilyID)
+
+	logger.Info(action, "user logged out", fmt.Sprint(requestID), userID)
+	s.record(ctx, model.AuthEvent{Event: model.EventLogout, UserID: userID, SessionID: current.FamilyID, Client: client})
+	return nil
+}
+
+// LogoutAll завершает все сессии пользователя на всех устройствах.
+func (s *AuthService) LogoutAll(ctx context.Context, userID string, client model.ClientInfo) error {
+	if err := s.RevokeUser(ctx, userID, "logout_all"); err != nil {
+		return err
+	}
+	s.record(ctx, model.AuthEvent{Event: model.EventLogoutAll, UserID: userID, Client: client})
+	return nil
 }
 
-func checkPassword(hashPassword, password string) bool {
-	var saltHex, hashHex string
-	_, err := fmt.Sscanf(hashPassword, "%32s:%64s", &saltHex, &hashHex)
+// RevokeUser отзывает все refresh-токены пользователя и выданные ему access-токены.
+func (s *AuthService) RevokeUser(ctx context.Context, userID, reason string) error {
+	action := "revoke_user"
+	requestID := ctx.Value("request_id")
+	if requestID == nil {
+		requestID = "none"
+	}
+
+	// iat в токене хранится с точностью до секунды, поэтому токены, выданные
+	// в ту же секунду, отсекаются по семействам, а не по времени.
+	now := time.Now().Truncate(time.Second)
+	families, err := s.tokenRepo.RevokeUser(ctx, userID, reason, now)
 	if err != nil {
-		return false
+		logger.Error(action, "failed to revoke user sessions", fmt.Sprint(requestID), userID, err.Error())
+		return err
+	}
+	s.revocations.RevokeUser(userID, now)
+	for _, family := range families {
+		s.revocations.RevokeSession(family)
 	}
 
-	salt, err := hex.DecodeString(saltHex)
+	logger.Info(action, "all user sessions revoked: "+reason, fmt.Sprint(requestID), userID)
+	return nil
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей,
+// например после смены пароля.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error {
+	families, err := s.tokenRepo.RevokeOtherFamilies(ctx, userID, keepSessionID)
 	if err != nil {
-		return false
+		return err
+	}
+	for _, family := range families {
+		s.revocations.RevokeSession(family)
 	}
-	storedHash, err := hex.DecodeString(hashHex)
+	logger.Info("revoke_other_sessions", fmt.Sprintf("%d other sessions revoked", len(families)), "", userID)
+	return nil
+}
+
+// rehashPassword пересчитывает устаревший хэш, пока пароль известен.
+func (s *AuthService) rehashPassword(ctx context.Context, action, requestID, userID, password string) {
+	hash, err := s.passwords.Hash(password)
 	if err != nil {
-		return false
+		logger.Warn(action, "failed to rehash password", requestID, userID, err.Error())
+		return
 	}
+	if err := s.userRepo.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		logger.Warn(action, "failed to store rehashed password", requestID, userID, err.Error())
+		return
+	}
+	logger.Info(action, "password hash upgraded", requestID, userID)
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, action, requestID string, t model.RefreshToken, client model.ClientInfo) {
+	logger.Warn(action, "refresh token reuse detected, revoking session", requestID, t.UserID, "family "+t.FamilyID)
+	s.record(ctx, model.AuthEvent{Event: model.EventRefreshReused, UserID: t.UserID, SessionID: t.FamilyID, Client: client})
+	if err := s.tokenRepo.RevokeFamily(ctx, t.FamilyID); err != nil {
+		logger.Error(action, "failed to revoke token family", requestID, t.UserID, err.Error())
+		return
+	}
+	s.revocations.RevokeSession(t.FamilyID)
+}
 
-	hash := sha256.Sum256(append(salt, []byte(password)...))
+// loginFailed записывает неудачный вход и учитывает его в LoginThrottle.
+func (s *AuthService) loginFailed(ctx context.Context, requestID, userID, email string, client model.ClientInfo, reason string) error {
+	s.record(ctx, model.AuthEvent{Event: model.EventLoginFailed, UserID: userID, Email: email, Client: client, Reason: reason})
+	if err := s.throttle.Fail(ctx, email, client.IP); err != nil {
+		if errors.Is(err, ErrTooManyAttempts) {
+			return err
+		}
+		logger.Error("login_user", "failed to register login failure", requestID, userID, err.Error())
+	}
+	return ErrInvalidCredentials
+}
+
+// record пишет событие в журнал аутентификации.
+func (s *AuthService) record(ctx context.Context, e model.AuthEvent) {
+	if err := s.audit.InsertAuthEvent(ctx, e); err != nil {
+		logger.Warn("auth_audit", "failed to record "+string(e.Event), "", e.UserID, err.Error())
+	}
+}
+
+// statusError возвращает причину, по которой пользователю нельзя выдавать токены.
+func statusError(status model.UserStatus) error {
+	switch status {
+	case model.UserBanned:
+		return ErrUserBanned
+	case model.UserInactive:
+		return ErrUserInactive
+	}
+	return nil
+}
+
+// newOpaqueToken возвращает случайный непрозрачный токен и его хэш для хранения.
+func newOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
 
-	return subtle.ConstantTimeCompare(storedHash, hash[:]) == 1
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
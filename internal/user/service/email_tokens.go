@@ -0,0 +1,70 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ride-hail-system/internal/user/model"
+)
+
+// EmailTokenSigner выпускает подписанные HMAC токены для писем.
+type EmailTokenSigner struct {
+	secret []byte
+}
+
+func NewEmailTokenSigner(secret []byte) *EmailTokenSigner {
+	return &EmailTokenSigner{secret: secret}
+}
+
+// Issue возвращает токен, его хэш для хранения и момент истечения.
+func (s *EmailTokenSigner) Issue(purpose model.EmailTokenPurpose, ttl time.Duration) (string, string, time.Time, error) {
+	if s == nil {
+		return "", "", time.Time{}, ErrEmailTokensDisabled
+	}
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	payload := fmt.Sprintf("%s.%d.%s", purpose, expiresAt.Unix(), base64.RawURLEncoding.EncodeToString(nonce))
+	token := payload + "." + s.sign(payload)
+	return token, hashToken(token), expiresAt, nil
+}
+
+// Verify проверяет подпись, назначение и срок и возвращает хэш для поиска в базе.
+func (s *EmailTokenSigner) Verify(purpose model.EmailTokenPurpose, token string) (string, error) {
+	if s == nil {
+		return "", ErrEmailTokensDisabled
+	}
+	token = strings.TrimSpace(token)
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", ErrEmailTokenInvalid
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return "", ErrEmailTokenInvalid
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 || parts[0] != string(purpose) {
+		return "", ErrEmailTokenInvalid
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= exp {
+		return "", ErrEmailTokenInvalid
+	}
+	return hashToken(token), nil
+}
+
+func (s *EmailTokenSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// PasswordHasher хэширует пароли argon2id в формате PHC.
+type PasswordHasher struct {
+	time      uint32
+	memoryKiB uint32
+	threads   uint8
+}
+
+func NewPasswordHasher(time, memoryKiB, threads int) *PasswordHasher {
+	return &PasswordHasher{
+		time:      uint32(max(time, 1)),
+		memoryKiB: uint32(max(memoryKiB, 8*max(threads, 1))),
+		threads:   uint8(min(max(threads, 1), 255)),
+	}
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memoryKiB, h.threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memoryKiB, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify проверяет пароль.
+func (h *PasswordHasher) Verify(encoded, password string) (ok, needsRehash bool) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		ok := checkLegacyPassword(encoded, password)
+		return ok, ok
+	}
+
+	var version int
+	var memoryKiB, time uint32
+	var threads uint8
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memoryKiB, &time, &threads); err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	stored, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(stored) == 0 {
+		return false, false
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memoryKiB, threads, uint32(len(stored)))
+	if subtle.ConstantTimeCompare(stored, key) != 1 {
+		return false, false
+	}
+	return true, memoryKiB != h.memoryKiB || time != h.time || threads != h.threads
+}
+
+// checkLegacyPassword проверяет хэш старого формата salt:sha256(salt+password).
+func checkLegacyPassword(encoded, password string) bool {
+	saltHex, hashHex, found := strings.Cut(encoded, ":")
+	if !found {
+		return false
+	}
+
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return false
+	}
+	storedHash, err := hex.DecodeString(hashHex)
+	if err != nil {
+		return false
+	}
+
+	hash := sha256.Sum256(append(salt, []byte(password)...))
+
+	return subtle.ConstantTimeCompare(storedHash, hash[:]) == 1
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/user/handler/dto"
+	"ride-hail-system/internal/user/model"
+	"ride-hail-system/internal/user/repository"
+	"ride-hail-system/pkg/phone"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ProfileRepository interface {
+	GetByID(ctx context.Context, userID string) (model.User, error)
+	GetByEmail(ctx context.Context, email string) (model.User, error)
+	GetProfile(ctx context.Context, userID string) (model.Profile, error)
+	UpdateProfileAttrs(ctx context.Context, userID string, set map[string]string, unset []string) error
+	UpdateVehicle(ctx context.Context, driverID string, attrs json.RawMessage) error
+	UpdatePasswordHash(ctx context.Context, userID, hash string) error
+	InsertEmailToken(ctx context.Context, userID string, purpose model.EmailTokenPurpose, email, tokenHash string, expiresAt time.Time) error
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (string, string, error)
+	VerifyEmail(ctx context.Context, tokenHash string) (string, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, string, error)
+}
+
+// Mailer отправляет письма пользователям.
+type Mailer interface {
+	Send(ctx context.Context, mail model.Mail) error
+}
+
+// SessionRevoker завершает сессии пользователя; реализуется AuthService.
+type SessionRevoker interface {
+	RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error
+	RevokeUser(ctx context.Context, userID, reason string) error
+}
+
+var (
+	ErrUserNotFound        = repository.ErrUserNotFound
+	ErrEmailTaken          = repository.ErrEmailTaken
+	ErrEmailTokenInvalid   = repository.ErrEmailTokenNotFound
+	ErrInvalidProfile      = errors.New("invalid profile")
+	ErrWrongPassword       = errors.New("current password is incorrect")
+	ErrSameEmail           = errors.New("new email is the same as the current one")
+	ErrVehicleNotAllowed   = errors.New("only drivers have vehicle attributes")
+	ErrAlreadyVerified     = errors.New("email is already verified")
+	ErrEmailTokensDisabled = errors.New("email links are disabled: EMAIL_TOKEN_SECRET is not set")
+)
+
+const (
+	maxNameLength      = 100
+	maxAvatarURLLength = 2048
+	emailChangeTTL     = 24 * time.Hour
+	verificationTTL    = 48 * time.Hour
+	passwordResetTTL   = time.Hour
+)
+
+var localeRe = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+type ProfileService struct {
+	users     ProfileRepository
+	passwords *PasswordHasher
+	sessions  SessionRevoker
+	mailer    Mailer
+	tokens    *EmailTokenSigner
+}
+
+func NewProfileService(users ProfileRepository, passwords *PasswordHasher, sessions SessionRevoker, mailer Mailer, tokens *EmailTokenSigner) *ProfileService {
+	return &ProfileService{users: users, passwords: passwords, sessions: sessions, mailer: mailer, tokens: tokens}
+}
+
+func (s *ProfileService) Get(ctx context.Context, userID string) (model.Profile, error) {
+	return s.users.GetProfile(ctx, userID)
+}
+
+// Update применяет частичное изменение профиля.
+func (s *ProfileService) Update(ctx context.Context, userID string, role model.Role, req dto.UpdateProfileRequest) (model.Profile, error) {
+	set := make(map[string]string)
+	var unset []string
+	apply := func(key string, value *string, normalize func(string) (string, error)) error {
+		if value == nil {
+			return nil
+		}
+		v := strings.TrimSpace(*value)
+		if v == "" {
+			unset = append(unset, key)
+			return nil
+		}
+		v, err := normalize(v)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidProfile, key, err)
+		}
+		set[key] = v
+		return nil
+	}
+
+	if err := apply(model.AttrName, req.Name, normalizeName); err != nil {
+		return model.Profile{}, err
+	}
+	if err := apply(model.AttrPhone, req.Phone, phone.Normalize); err != nil {
+		return model.Profile{}, err
+	}
+	if err := apply(model.AttrLocale, req.Locale, normalizeLocale); err != nil {
+		return model.Profile{}, err
+	}
+	if err := apply(model.AttrAvatarURL, req.AvatarURL, normalizeAvatarURL); err != nil {
+		return model.Profile{}, err
+	}
+
+	if len(req.VehicleAttrs) > 0 {
+		if role != model.RoleDriver {
+			return model.Profile{}, ErrVehicleNotAllowed
+		}
+		var attrs map[string]any
+		if err := json.Unmarshal(req.VehicleAttrs, &attrs); err != nil || attrs == nil {
+			return model.Profile{}, fmt.Errorf("%w: vehicle_attrs must be an object", ErrInvalidProfile)
+		}
+	}
+
+	if len(set) > 0 || len(unset) > 0 {
+		if err := s.users.UpdateProfileAttrs(ctx, userID, set, unset); err != nil {
+			return model.Profile{}, err
+		}
+	}
+	if len(req.VehicleAttrs) > 0 {
+		if err := s.users.UpdateVehicle(ctx, userID, req.VehicleAttrs); err != nil {
+			return model.Profile{}, err
+		}
+		logger.Info("update_profile", "vehicle changed, driver verification reset", "", userID)
+	}
+
+	return s.users.GetProfile(ctx, userID)
+}
+
+// ChangePassword меняет пароль после проверки текущего и завершает все
+// остальные сессии пользователя.
+func (s *ProfileService) ChangePassword(ctx context.Context, userID, sessionID string, req dto.ChangePasswordRequest) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.passwords.Verify(user.PasswordHash, req.OldPassword); !ok {
+		logger.Warn("change_password", "wrong current password", "", userID, "")
+		return ErrWrongPassword
+	}
+
+	hash, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		logger.Error("change_password", "failed to revoke other sessions", "", userID, err.Error())
+		return err
+	}
+
+	logger.Info("change_password", "password changed", "", userID)
+	s.notify(ctx, userID, model.Mail{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body:    "The password for your account was just changed. If this wasn't you, reset your password immediately.",
+	})
+	return nil
+}
+
+// RequestEmailChange отправляет код подтверждения на новый адрес.
+func (s *ProfileService) RequestEmailChange(ctx context.Context, userID string, req dto.ChangeEmailRequest) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.passwords.Verify(user.PasswordHash, req.Password); !ok {
+		logger.Warn("change_email", "wrong current password", "", userID, "")
+		return ErrWrongPassword
+	}
+	if strings.EqualFold(user.Email, req.NewEmail) {
+		return ErrSameEmail
+	}
+	if _, err := s.users.GetByEmail(ctx, req.NewEmail); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	token, hash, expiresAt, err := s.tokens.Issue(model.EmailChange, emailChangeTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.users.InsertEmailToken(ctx, userID, model.EmailChange, req.NewEmail, hash, expiresAt); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, model.Mail{
+		To:      req.NewEmail,
+		Subject: "Confirm your new email address",
+		Body:    fmt.Sprintf("Confirmation token: %s (valid for %s)", token, emailChangeTTL),
+	}); err != nil {
+		logger.Error("change_email", "failed to send confirmation", "", userID, err.Error())
+		return err
+	}
+	s.notify(ctx, userID, model.Mail{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body:    fmt.Sprintf("A change of your account email to %s was requested. If this wasn't you, change your password.", req.NewEmail),
+	})
+
+	logger.Info("change_email", "email change requested", "", userID)
+	return nil
+}
+
+func (s *ProfileService) ConfirmEmailChange(ctx context.Context, token string) error {
+	hash, err := s.tokens.Verify(model.EmailChange, token)
+	if err != nil {
+		return err
+	}
+	userID, email, err := s.users.ConfirmEmailChange(ctx, hash)
+	if err != nil {
+		return err
+	}
+	logger.Info("change_email", "email changed to "+email, "", userID)
+	return nil
+}
+
+// SendVerification отправляет код подтверждения на текущий адрес пользователя.
+func (s *ProfileService) SendVerification(ctx context.Context, userID string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	token, hash, expiresAt, err := s.tokens.Issue(model.EmailVerification, verificationTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.users.InsertEmailToken(ctx, userID, model.EmailVerification, user.Email, hash, expiresAt); err != nil {
+		return err
+	}
+	if err := s.mailer.Send(ctx, model.Mail{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body:    fmt.Sprintf("Verification token: %s (valid for %s)", token, verificationTTL),
+	}); err != nil {
+		logger.Error("send_verification", "failed to send verification", "", userID, err.Error())
+		return err
+	}
+
+	logger.Info("send_verification", "verification email sent", "", userID)
+	return nil
+}
+
+// VerifyEmail подтверждает адрес по коду из письма.
+func (s *ProfileService) VerifyEmail(ctx context.Context, token string) error {
+	hash, err := s.tokens.Verify(model.EmailVerification, token)
+	if err != nil {
+		return err
+	}
+	userID, err := s.users.VerifyEmail(ctx, hash)
+	if err != nil {
+		return err
+	}
+	logger.Info("verify_email", "email verified", "", userID)
+	return nil
+}
+
+// ForgotPassword отправляет код сброса пароля.
+func (s *ProfileService) ForgotPassword(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Debug("forgot_password", "password reset requested for unknown email", "", "")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Status == model.UserBanned {
+		logger.Warn("forgot_password", "password reset requested for banned user", "", string(user.ID), "")
+		return nil
+	}
+
+	token, hash, expiresAt, err := s.tokens.Issue(model.PasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.users.InsertEmailToken(ctx, string(user.ID), model.PasswordReset, user.Email, hash, expiresAt); err != nil {
+		return err
+	}
+	if err := s.mailer.Send(ctx, model.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Password reset token: %s (valid for %s). If you didn't request a reset, ignore this email.", token, passwordResetTTL),
+	}); err != nil {
+		logger.Error("forgot_password", "failed to send reset email", "", string(user.ID), err.Error())
+		return err
+	}
+
+	logger.Info("forgot_password", "password reset email sent", "", string(user.ID))
+	return nil
+}
+
+// ResetPassword задаёт новый пароль по коду из письма и завершает все сессии пользователя.
+func (s *ProfileService) ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error {
+	hash, err := s.tokens.Verify(model.PasswordReset, req.Token)
+	if err != nil {
+		return err
+	}
+	passwordHash, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	userID, email, err := s.users.ResetPassword(ctx, hash, passwordHash)
+	if err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeUser(ctx, userID, "password_reset"); err != nil {
+		logger.Error("reset_password", "failed to revoke sessions", "", userID, err.Error())
+		return err
+	}
+
+	logger.Info("reset_password", "password reset", "", userID)
+	s.notify(ctx, userID, model.Mail{
+		To:      email,
+		Subject: "Your password was reset",
+		Body:    "The password for your account was reset and all sessions were signed out. If this wasn't you, contact support.",
+	})
+	return nil
+}
+
+// notify отправляет уведомление; ошибка не отменяет уже выполненное действие.
+func (s *ProfileService) notify(ctx context.Context, userID string, mail model.Mail) {
+	if err := s.mailer.Send(ctx, mail); err != nil {
+		logger.Warn("notify_user", "failed to send notification", "", userID, err.Error())
+	}
+}
+
+func normalizeName(name string) (string, error) {
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", fmt.Errorf("must be at most %d characters", maxNameLength)
+	}
+	return name, nil
+}
+
+func normalizeLocale(locale string) (string, error) {
+	if !localeRe.MatchString(locale) {
+		return "", errors.New("must look like en or en-US")
+	}
+	return locale, nil
+}
+
+func normalizeAvatarURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || len(raw) > maxAvatarURLLength || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", errors.New("must be an http(s) URL")
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+)
+
+// RevocationStore отдаёт отзывы, ещё влияющие на живые access-токены.
+type RevocationStore interface {
+	LoadRevocations(ctx context.Context, window time.Duration) (map[string]time.Time, map[string]struct{}, error)
+}
+
+// RevocationList держит в памяти отозванных пользователей и сессии.
+type RevocationList struct {
+	store  RevocationStore
+	window time.Duration
+
+	mu       sync.RWMutex
+	users    map[string]time.Time
+	sessions map[string]struct{}
+}
+
+// NewRevocationList создаёт список; window — срок жизни access-токена,
+// более старые отзывы уже ни на что не влияют.
+func NewRevocationList(store RevocationStore, window time.Duration) *RevocationList {
+	return &RevocationList{
+		store:    store,
+		window:   window,
+		users:    make(map[string]time.Time),
+		sessions: make(map[string]struct{}),
+	}
+}
+
+// Revoked сообщает, отозван ли access-токен пользователя userID из сессии
+// sessionID, выданный в issuedAt.
+func (l *RevocationList) Revoked(userID, sessionID string, issuedAt time.Time) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if before, ok := l.users[userID]; ok && issuedAt.Before(before) {
+		return true
+	}
+	if sessionID != "" {
+		if _, ok := l.sessions[sessionID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeSession и RevokeUser применяют отзыв локально, не дожидаясь Refresh.
+func (l *RevocationList) RevokeSession(sessionID string) {
+	l.mu.Lock()
+	l.sessions[sessionID] = struct{}{}
+	l.mu.Unlock()
+}
+
+func (l *RevocationList) RevokeUser(userID string, before time.Time) {
+	l.mu.Lock()
+	if before.After(l.users[userID]) {
+		l.users[userID] = before
+	}
+	l.mu.Unlock()
+}
+
+// Refresh перечитывает список из базы.
+func (l *RevocationList) Refresh(ctx context.Context) error {
+	users, sessions, err := l.store.LoadRevocations(ctx, l.window)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.users = users
+	l.sessions = sessions
+	l.mu.Unlock()
+	return nil
+}
+
+// Run периодически обновляет список из базы.
+func (l *RevocationList) Run(ctx context.Context, interval time.Duration) {
+	if err := l.Refresh(ctx); err != nil {
+		logger.Warn("revocations_refresh", "Failed to load token revocations", "", "", err.Error())
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Refresh(ctx); err != nil {
+				logger.Warn("revocations_refresh", "Failed to load token revocations", "", "", err.Error())
+			}
+		}
+	}
+}
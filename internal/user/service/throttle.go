@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+)
+
+// ThrottleStore хранит счётчики неудачных входов; общий для всех реплик.
+type ThrottleStore interface {
+	LockedUntil(ctx context.Context, keys []string) (time.Time, error)
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	ClearFailures(ctx context.Context, key string) error
+}
+
+// ThrottleOptions — пороги и длительность блокировок входа.
+type ThrottleOptions struct {
+	AccountThreshold int
+	IPThreshold      int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+}
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts, try again later")
+
+// LockoutError сообщает, сколько осталось ждать до следующей попытки входа.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string { return ErrTooManyAttempts.Error() }
+func (e *LockoutError) Unwrap() error { return ErrTooManyAttempts }
+
+// LoginThrottle ограничивает подбор пароля по email и по IP.
+type LoginThrottle struct {
+	store ThrottleStore
+	opts  ThrottleOptions
+}
+
+func NewLoginThrottle(store ThrottleStore, opts ThrottleOptions) *LoginThrottle {
+	return &LoginThrottle{store: store, opts: opts}
+}
+
+// Check возвращает *LockoutError, если email или IP сейчас заблокированы.
+func (t *LoginThrottle) Check(ctx context.Context, email, ip string) error {
+	until, err := t.store.LockedUntil(ctx, t.keys(email, ip))
+	if err != nil {
+		return err
+	}
+	if wait := time.Until(until); wait > 0 {
+		return &LockoutError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Fail учитывает неудачный вход и возвращает *LockoutError, если после
+// него email или IP заблокированы.
+func (t *LoginThrottle) Fail(ctx context.Context, email, ip string) error {
+	var lockout time.Duration
+	for _, k := range []struct {
+		key       string
+		threshold int
+	}{
+		{accountKey(email), t.opts.AccountThreshold},
+		{ipKey(ip), t.opts.IPThreshold},
+	} {
+		if k.key == "" {
+			continue
+		}
+		// Счётчик живёт дольше самой длинной блокировки, иначе после неё
+		// подбор начинался бы с чистого листа.
+		failures, err := t.store.RegisterFailure(ctx, k.key, 2*t.opts.LockoutMax)
+		if err != nil {
+			return err
+		}
+		d := t.lockout(failures, k.threshold)
+		if d == 0 {
+			continue
+		}
+		if err := t.store.Lock(ctx, k.key, time.Now().Add(d)); err != nil {
+			return err
+		}
+		logger.Warn("login_throttle", fmt.Sprintf("%s locked for %s after %d failures", k.key, d, failures), "", "", "")
+		lockout = max(lockout, d)
+	}
+	if lockout > 0 {
+		return &LockoutError{RetryAfter: lockout}
+	}
+	return nil
+}
+
+// Succeed сбрасывает счётчик по email.
+func (t *LoginThrottle) Succeed(ctx context.Context, email string) error {
+	return t.store.ClearFailures(ctx, accountKey(email))
+}
+
+// lockout — длительность блокировки после failures неудач подряд.
+func (t *LoginThrottle) lockout(failures, threshold int) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+	d := t.opts.LockoutBase
+	for i := threshold; i < failures && d < t.opts.LockoutMax; i++ {
+		d *= 2
+	}
+	return min(d, t.opts.LockoutMax)
+}
+
+func (t *LoginThrottle) keys(email, ip string) []string {
+	keys := []string{accountKey(email)}
+	if k := ipKey(ip); k != "" {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/user/model"
+	"ride-hail-system/internal/user/repository"
+	"ride-hail-system/pkg/totp"
+)
+
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userID string) (model.TOTP, error)
+	SetPendingTOTP(ctx context.Context, userID, secret string) error
+	EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	AdvanceStep(ctx context.Context, userID string, step int64) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	DeleteTOTP(ctx context.Context, userID string) error
+	InsertChallenge(ctx context.Context, userID, email, tokenHash string, expiresAt time.Time) error
+	AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int) (model.LoginChallenge, error)
+	ConsumeChallenge(ctx context.Context, id string) error
+}
+
+// UserReader читает пользователя по ID.
+type UserReader interface {
+	GetByID(ctx context.Context, userID string) (model.User, error)
+}
+
+var (
+	ErrTwoFactorNotEnabled  = repository.ErrTOTPNotFound
+	ErrTwoFactorEnabled     = repository.ErrTOTPEnabled
+	ErrInvalidChallenge     = repository.ErrChallengeNotFound
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorMandatory   = errors.New("two-factor authentication is mandatory for this role")
+)
+
+const (
+	// Допуск на расхождение часов: код предыдущего и следующего шага тоже принимается.
+	totpSkew           = 1
+	recoveryCodeCount  = 10
+	challengeTTL       = 5 * time.Minute
+	challengeAttempts  = 5
+	recoveryCodeLength = 10
+)
+
+// TwoFactorService управляет вторым фактором: TOTP и кодами восстановления.
+type TwoFactorService struct {
+	repo      TwoFactorRepository
+	users     UserReader
+	passwords *PasswordHasher
+	audit     AuditLog
+	issuer    string
+	required  map[model.Role]bool
+}
+
+// NewTwoFactorService создаёт сервис.
+func NewTwoFactorService(repo TwoFactorRepository, users UserReader, passwords *PasswordHasher, audit AuditLog, issuer string, requiredFor ...model.Role) *TwoFactorService {
+	required := make(map[model.Role]bool, len(requiredFor))
+	for _, role := range requiredFor {
+		required[role] = true
+	}
+	return &TwoFactorService{repo: repo, users: users, passwords: passwords, audit: audit, issuer: issuer, required: required}
+}
+
+// Required сообщает, обязательна ли 2FA для роли.
+func (s *TwoFactorService) Required(role model.Role) bool {
+	return s.required[role]
+}
+
+// Enabled сообщает, включена ли у пользователя 2FA.
+func (s *TwoFactorService) Enabled(ctx context.Context, userID string) (bool, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.EnabledAt != nil, nil
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID string, role model.Role) (model.TwoFactorStatus, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return model.TwoFactorStatus{}, err
+	}
+	status := model.TwoFactorStatus{Enabled: enabled, Required: s.Required(role)}
+	if enabled {
+		if status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(ctx, userID); err != nil {
+			return model.TwoFactorStatus{}, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll выпускает новый секрет.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID, password string) (model.TwoFactorEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return model.TwoFactorEnrollment{}, err
+	}
+	if ok, _ := s.passwords.Verify(user.PasswordHash, password); !ok {
+		logger.Warn("two_factor_enroll", "wrong current password", "", userID, "")
+		return model.TwoFactorEnrollment{}, ErrWrongPassword
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return model.TwoFactorEnrollment{}, err
+	}
+	if err := s.repo.SetPendingTOTP(ctx, userID, secret); err != nil {
+		return model.TwoFactorEnrollment{}, err
+	}
+
+	logger.Info("two_factor_enroll", "two-factor secret issued", "", userID)
+	return model.TwoFactorEnrollment{
+		Secret:     secret,
+		OtpauthURI: totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm включает 2FA по первому коду из приложения и возвращает коды восстановления.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string, client model.ClientInfo) ([]string, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	logger.Info("two_factor_confirm", "two-factor authentication enabled", "", userID)
+	s.record(ctx, model.AuthEvent{Event: model.EventTwoFactorOn, UserID: userID, Client: client})
+	return codes, nil
+}
+
+// Disable выключает 2FA после проверки пароля и кода.
+func (s *TwoFactorService) Disable(ctx context.Context, userID string, role model.Role, password, code string, client model.ClientInfo) error {
+	if s.Required(role) {
+		return ErrTwoFactorMandatory
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.passwords.Verify(user.PasswordHash, password); !ok {
+		logger.Warn("two_factor_disable", "wrong current password", "", userID, "")
+		return ErrWrongPassword
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+
+	logger.Info("two_factor_disable", "two-factor authentication disabled", "", userID)
+	s.record(ctx, model.AuthEvent{Event: model.EventTwoFactorOff, UserID: userID, Client: client})
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми, например
+// когда старые почти израсходованы.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	logger.Info("two_factor_recovery", "recovery codes regenerated", "", userID)
+	return codes, nil
+}
+
+// Verify принимает код из приложения или код восстановления.
+func (s *TwoFactorService) Verify(ctx context.Context, userID, code string) error {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if t.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totp.Digits {
+		err := s.repo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		if err == nil {
+			logger.Warn("two_factor_verify", "recovery code used", "", userID, "")
+		}
+		return err
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	err = s.repo.AdvanceStep(ctx, userID, step)
+	if errors.Is(err, repository.ErrTOTPStepUsed) {
+		logger.Warn("two_factor_verify", "totp code replayed", "", userID, "")
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// StartChallenge выдаёт токен второго шага входа.
+func (s *TwoFactorService) StartChallenge(ctx context.Context, userID, email string) (string, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.InsertChallenge(ctx, userID, email, hash, time.Now().Add(challengeTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// AttemptChallenge засчитывает попытку ответа на второй шаг.
+func (s *TwoFactorService) AttemptChallenge(ctx context.Context, token string) (model.LoginChallenge, error) {
+	return s.repo.AttemptChallenge(ctx, hashToken(strings.TrimSpace(token)), challengeAttempts)
+}
+
+func (s *TwoFactorService) ConsumeChallenge(ctx context.Context, id string) error {
+	return s.repo.ConsumeChallenge(ctx, id)
+}
+
+func (s *TwoFactorService) record(ctx context.Context, e model.AuthEvent) {
+	if err := s.audit.InsertAuthEvent(ctx, e); err != nil {
+		logger.Warn("auth_audit", "failed to record "+string(e.Event), "", e.UserID, err.Error())
+	}
+}
+
+// newRecoveryCodes возвращает коды восстановления вида xxxxx-xxxxx и их хэши.
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	buf := make([]byte, 8)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(buf))[:recoveryCodeLength]
+		codes[i] = raw[:recoveryCodeLength/2] + "-" + raw[recoveryCodeLength/2:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
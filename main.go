@@ -9,15 +9,23 @@ import (
 	"time"
 
 	cmdAdmin "ride-hail-system/cmd/admin-service"
+	cmdChat "ride-hail-system/cmd/chat-service"
+	cmdContact "ride-hail-system/cmd/contact-service"
 	cmdDriver "ride-hail-system/cmd/driver-location-service"
+	cmdOnboarding "ride-hail-system/cmd/onboarding-service"
 	cmdRide "ride-hail-system/cmd/ride-service"
 	cmdUser "ride-hail-system/cmd/user-service"
+	"ride-hail-system/internal/common/auth"
 	"ride-hail-system/internal/common/config"
 	"ride-hail-system/internal/common/db"
+	"ride-hail-system/internal/common/idempotency"
 	"ride-hail-system/internal/common/logger"
+	"ride-hail-system/internal/common/outbox"
 	"ride-hail-system/internal/common/rmq"
 	"ride-hail-system/internal/common/websocket"
 	"ride-hail-system/internal/user/jwt"
+	userRepository "ride-hail-system/internal/user/repository"
+	userService "ride-hail-system/internal/user/service"
 )
 
 func main() {
@@ -33,6 +41,7 @@ func main() {
 	pg, err := db.NewPostgres(
 		cfg.Database.Host, cfg.Database.Port,
 		cfg.Database.User, cfg.Database.Password, cfg.Database.Name,
+		cfg.Database.MaxConns,
 	)
 	if err != nil {
 		logger.Error("init_db", "failed to connect to PostgreSQL", "", "", err.Error())
@@ -58,20 +67,101 @@ func main() {
 	defer commonRMQ.Close()
 	logger.Info("init_rabbitmq", "RabbitMQ connection established", "", "")
 
-	jwtManager := jwt.NewManager("super-secret-key", 15*time.Minute, 7*24*time.Hour)
+	topologyBus, err := rmq.NewAMQPBus(commonRMQ.Conn)
+	if err != nil {
+		logger.Error("init_rabbitmq_topology", "failed to open topology channel", "", "", err.Error())
+		os.Exit(1)
+	}
+	defer topologyBus.Close()
+
+	topology := rmq.DefaultTopology()
+	for _, q := range topology.Queues {
+		tuning := cfg.Consumer(q.Name)
+		topology = topology.Tune(q.Name, tuning.Prefetch, tuning.Workers)
+	}
+
+	if err := topology.Apply(topologyBus); err != nil {
+		logger.Error("init_rabbitmq_topology", "failed to apply RabbitMQ topology", "", "", err.Error())
+		os.Exit(1)
+	}
+
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+
+	keyRepository, err := userRepository.NewKeyRepository(pg.Pool, cfg.JWT.KeyEncryptionKey)
+	if err != nil {
+		logger.Error("init_jwt", "JWT_KEY_ENCRYPTION_KEY is invalid", "", "", err.Error())
+		os.Exit(1)
+	}
+	jwtManager := jwt.NewManager(keyRepository, jwt.Options{
+		AccessTTL:   time.Duration(cfg.JWT.AccessTTLMinutes) * time.Minute,
+		RefreshTTL:  time.Duration(cfg.JWT.RefreshTTLHours) * time.Hour,
+		RotateEvery: time.Duration(cfg.JWT.KeyRotationHours) * time.Hour,
+		ReloadEvery: time.Duration(cfg.JWT.KeyReloadSeconds) * time.Second,
+	})
+	if err := jwtManager.Init(appCtx); err != nil {
+		logger.Error("init_jwt", "failed to load signing keys", "", "", err.Error())
+		os.Exit(1)
+	}
+	go jwtManager.Run(appCtx)
+	revocations := userService.NewRevocationList(userRepository.NewTokenRepository(pg.Pool), jwtManager.AccessTTL())
+	go revocations.Run(appCtx, 5*time.Second)
+	// Без секрета письма с кодами не отправляются, и подтвердить адрес нельзя.
+	requireVerified := cfg.Mail.TokenSecret != ""
+	if !requireVerified {
+		logger.Warn("init_auth", "EMAIL_TOKEN_SECRET is not set, email verification is not required", "", "", "")
+	}
+	authn := auth.NewAuthenticator(jwtManager, auth.DefaultPolicy(), revocations, requireVerified)
 	logger.Info("init_jwt", "JWT manager initialized", "", "")
 
-	hub := websocket.NewHub()
-	go hub.Run()
-	logger.Info("init_websocket", "WebSocket hub started", "", "")
+	outboxStore := outbox.NewStore(pg.Pool)
+	relay := outbox.NewRelay(outboxStore, commonRMQ.URL, outbox.DefaultRelayOptions())
+	go relay.Run(appCtx)
+	logger.Info("init_outbox", "outbox relay started", "", "")
+
+	dedupe := idempotency.NewStore(pg.Pool, 24*time.Hour)
+	go dedupe.Run(appCtx, 10*time.Minute)
+	logger.Info("init_idempotency", "idempotency store initialized", "", "")
+
+	consumerOpts := rmq.ConsumerOptions{
+		Topology: topology,
+		Dedupe:   dedupe,
+		Metrics:  rmq.NewMetrics(),
+	}
+
+	hubCfg := websocket.DefaultConfig()
+	hubCfg.Retention = time.Duration(cfg.WebSocket.RetentionSeconds) * time.Second
+	hubCfg.RetentionSize = cfg.WebSocket.RetentionSize
+	hub := websocket.NewHubWithConfig(hubCfg)
+	logger.Info("init_websocket", "WebSocket hub initialized", "", "")
+
+	clusterBus, err := rmq.NewAMQPBus(commonRMQ.Conn)
+	if err != nil {
+		logger.Error("init_ws_cluster", "failed to open cluster channel", "", "", err.Error())
+		os.Exit(1)
+	}
+	defer clusterBus.Close()
+
+	presence := websocket.NewPostgresPresence(pg.Pool, 30*time.Second)
+	sessions := websocket.NewPostgresSessions(pg.Pool, 30*time.Second)
+	cluster := websocket.NewCluster(hub, cfg.WebSocket.NodeID, clusterBus, presence, sessions, consumerOpts, 10*time.Second)
+	if err := cluster.Start(appCtx); err != nil {
+		logger.Error("init_ws_cluster", "failed to start WebSocket cluster delivery", "", "", err.Error())
+		os.Exit(1)
+	}
 
 	mux := http.NewServeMux()
 	wsMux := http.NewServeMux()
-
-	go cmdUser.RunUser(pg.Conn, mux, jwtManager)
-	go cmdRide.RunRide(cfg, pg.Conn, commonRMQ, mux, hub, wsMux, jwtManager)
-	go cmdDriver.RunDriver(cfg, pg.Conn, commonRMQ, mux, hub, wsMux, jwtManager)
-	go cmdAdmin.RunAdmin(cfg, pg.Conn, mux)
+	// Каталог сообщений WebSocket с JSON-схемами для генерации клиентов.
+	wsMux.Handle("GET /ws/schemas", hub.Registry())
+
+	users := cmdUser.RunUser(cfg, pg.Pool, mux, jwtManager, authn, revocations)
+	contacts := cmdContact.RunContact(cfg, pg.Pool, mux, authn)
+	go cmdRide.RunRide(appCtx, cfg, pg.Pool, commonRMQ, outboxStore, consumerOpts, mux, hub, wsMux, authn, contacts)
+	go cmdDriver.RunDriver(appCtx, cfg, pg.Pool, commonRMQ, outboxStore, consumerOpts, mux, hub, wsMux, authn)
+	go cmdOnboarding.RunOnboarding(appCtx, cfg, pg.Pool, mux, authn)
+	go cmdChat.RunChat(appCtx, pg.Pool, commonRMQ, consumerOpts, mux, hub, authn)
+	go cmdAdmin.RunAdmin(appCtx, cfg, pg.Pool, commonRMQ, consumerOpts, mux, hub, wsMux, authn, topologyBus, cluster, users)
 	logger.Info("run_services", "all microservices initialized", "", "")
 
 	go func() {
@@ -102,6 +192,7 @@ func main() {
 
 	<-stop
 	logger.Warn("shutdown", "received stop signal, shutting down gracefully...", "", "", "")
+	stopApp()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,19 @@
+begin;
+
+-- Transactional outbox: messages are written in the same transaction as the
+-- business change and published to RabbitMQ by a relay afterwards
+create table outbox (
+                        id uuid primary key default gen_random_uuid(),
+                        created_at timestamptz not null default now(),
+                        exchange text not null,
+                        routing_key text not null,
+                        payload jsonb not null,
+                        attempts integer not null default 0,
+                        last_error text,
+                        sent_at timestamptz
+);
+
+-- Relay only scans rows that have not been published yet
+create index idx_outbox_pending on outbox(created_at) where sent_at is null;
+
+commit;
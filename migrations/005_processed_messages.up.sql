@@ -0,0 +1,14 @@
+begin;
+
+-- Message IDs already handled by a consumer, used to make redelivery harmless
+create table processed_messages (
+                                    consumer text not null,
+                                    message_id text not null,
+                                    processed_at timestamptz not null default now(),
+                                    expires_at timestamptz not null,
+                                    primary key (consumer, message_id)
+);
+
+create index idx_processed_messages_expires on processed_messages(expires_at);
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists ws_presence cascade;
+
+commit;
@@ -0,0 +1,14 @@
+begin;
+
+-- Which service node holds a WebSocket connection for a user topic
+create table ws_presence (
+                             topic text not null,
+                             node_id text not null,
+                             connected_at timestamptz not null default now(),
+                             seen_at timestamptz not null default now(),
+                             primary key (topic, node_id)
+);
+
+create index idx_ws_presence_node on ws_presence(node_id);
+
+commit;
@@ -0,0 +1,18 @@
+begin;
+
+-- Active WebSocket connections, several per user (phone, tablet)
+create table ws_sessions (
+                             id text primary key,
+                             user_id text not null,
+                             role text not null,
+                             node_id text not null,
+                             remote_addr text,
+                             user_agent text,
+                             connected_at timestamptz not null default now(),
+                             seen_at timestamptz not null default now()
+);
+
+create index idx_ws_sessions_user on ws_sessions(user_id);
+create index idx_ws_sessions_node on ws_sessions(node_id);
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists chat_messages cascade;
+
+commit;
@@ -0,0 +1,20 @@
+begin;
+
+-- In-ride chat between passenger and driver, kept for dispute resolution
+create table chat_messages (
+                               id uuid primary key default gen_random_uuid(),
+                               ride_id uuid not null references rides(id),
+                               sender_id uuid not null references users(id),
+                               sender_role text not null references "roles"(value),
+                               client_id text not null,
+                               body text not null,
+                               template text,
+                               created_at timestamptz not null default now(),
+                               delivered_at timestamptz,
+                               read_at timestamptz,
+                               unique (ride_id, sender_id, client_id)
+);
+
+create index idx_chat_messages_ride on chat_messages(ride_id, created_at);
+
+commit;
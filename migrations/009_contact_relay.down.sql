@@ -0,0 +1,6 @@
+begin;
+
+drop table if exists contact_intents cascade;
+drop table if exists ride_contacts cascade;
+
+commit;
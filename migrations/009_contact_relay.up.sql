@@ -0,0 +1,30 @@
+begin;
+
+-- Real phone numbers of ride participants, reachable only through a proxy id
+create table ride_contacts (
+                               id uuid primary key default gen_random_uuid(),
+                               ride_id uuid not null references rides(id),
+                               user_id uuid not null references users(id),
+                               role text not null references "roles"(value),
+                               real_number text not null,
+                               proxy_id text unique not null,
+                               created_at timestamptz not null default now(),
+                               expires_at timestamptz not null,
+                               unique (ride_id, user_id)
+);
+
+-- Call and SMS intents routed through the telephony provider
+create table contact_intents (
+                                 id uuid primary key default gen_random_uuid(),
+                                 ride_id uuid not null references rides(id),
+                                 proxy_id text not null,
+                                 caller_id uuid not null references users(id),
+                                 kind text not null check (kind in ('call', 'sms')),
+                                 status text not null,
+                                 provider_ref text,
+                                 created_at timestamptz not null default now()
+);
+
+create index idx_contact_intents_ride on contact_intents(ride_id, created_at);
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+drop table if exists token_revocations cascade;
+drop table if exists refresh_tokens cascade;
+
+commit;
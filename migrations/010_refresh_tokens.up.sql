@@ -0,0 +1,28 @@
+begin;
+
+-- Refresh tokens, stored as sha256 hashes. Every login starts a family;
+-- each refresh rotates the token within the family, and presenting an
+-- already rotated token revokes the whole family.
+create table refresh_tokens (
+                                id uuid primary key default gen_random_uuid(),
+                                user_id uuid not null references users(id),
+                                family_id uuid not null,
+                                token_hash text unique not null,
+                                created_at timestamptz not null default now(),
+                                expires_at timestamptz not null,
+                                used_at timestamptz,
+                                revoked_at timestamptz,
+                                replaced_by uuid references refresh_tokens(id)
+);
+
+create index idx_refresh_tokens_family on refresh_tokens(family_id);
+create index idx_refresh_tokens_user on refresh_tokens(user_id) where revoked_at is null;
+
+-- Access tokens of a user issued before revoked_before are rejected
+create table token_revocations (
+                                   user_id uuid primary key references users(id),
+                                   revoked_before timestamptz not null,
+                                   reason text
+);
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists signing_keys cascade;
+
+commit;
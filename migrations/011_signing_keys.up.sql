@@ -0,0 +1,15 @@
+begin;
+
+-- Ed25519 keys for signing access tokens. The newest key signs; older keys
+-- stay published in JWKS until tokens signed with them have expired
+create table signing_keys (
+                              kid text primary key,
+                              algorithm text not null,
+                              private_key bytea not null,
+                              public_key bytea not null,
+                              created_at timestamptz not null default now()
+);
+
+create index idx_signing_keys_created on signing_keys(created_at desc);
+
+commit;
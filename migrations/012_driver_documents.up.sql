@@ -0,0 +1,25 @@
+begin;
+
+-- Documents uploaded by drivers during onboarding. The file itself lives in
+-- the blob store under blob_key; a driver is verified while every kind has an
+-- approved document that has not expired
+create table driver_documents (
+                                  id uuid primary key default gen_random_uuid(),
+                                  driver_id uuid not null references drivers(id),
+                                  kind text not null check (kind in ('license', 'insurance', 'vehicle_registration')),
+                                  status text not null default 'PENDING' check (status in ('PENDING', 'APPROVED', 'REJECTED', 'EXPIRED')),
+                                  blob_key text unique not null,
+                                  content_type text not null,
+                                  size_bytes bigint not null,
+                                  expires_at timestamptz not null,
+                                  uploaded_at timestamptz not null default now(),
+                                  reviewed_at timestamptz,
+                                  reviewed_by uuid references users(id),
+                                  reject_reason text
+);
+
+create index idx_driver_documents_driver on driver_documents(driver_id, kind);
+create index idx_driver_documents_pending on driver_documents(uploaded_at) where status = 'PENDING';
+create index idx_driver_documents_expiry on driver_documents(expires_at) where status = 'APPROVED';
+
+commit;
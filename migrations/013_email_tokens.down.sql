@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists email_tokens cascade;
+
+commit;
@@ -0,0 +1,18 @@
+begin;
+
+-- Single-use tokens sent by email, stored as sha256 hashes. email is the
+-- address the token was sent to, e.g. the new address of an email change
+create table email_tokens (
+                              id uuid primary key default gen_random_uuid(),
+                              user_id uuid not null references users(id),
+                              purpose text not null check (purpose in ('email_change')),
+                              email text not null,
+                              token_hash text unique not null,
+                              created_at timestamptz not null default now(),
+                              expires_at timestamptz not null,
+                              used_at timestamptz
+);
+
+create index idx_email_tokens_user on email_tokens(user_id, purpose) where used_at is null;
+
+commit;
@@ -0,0 +1,9 @@
+begin;
+
+delete from email_tokens where purpose <> 'email_change';
+alter table email_tokens drop constraint email_tokens_purpose_check;
+alter table email_tokens add constraint email_tokens_purpose_check check (purpose in ('email_change'));
+
+alter table users drop column if exists email_verified_at;
+
+commit;
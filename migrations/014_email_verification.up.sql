@@ -0,0 +1,12 @@
+begin;
+
+-- Accounts confirm their email before getting full access. Accounts created
+-- before verification existed are treated as verified
+alter table users add column email_verified_at timestamptz;
+update users set email_verified_at = created_at;
+
+alter table email_tokens drop constraint email_tokens_purpose_check;
+alter table email_tokens add constraint email_tokens_purpose_check
+    check (purpose in ('email_change', 'email_verification', 'password_reset'));
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+drop table if exists login_throttle cascade;
+drop table if exists auth_events cascade;
+
+commit;
@@ -0,0 +1,33 @@
+begin;
+
+-- Security log of authentication: logins, failures, refreshes and logouts.
+-- user_id is empty for attempts against an unknown email
+create table auth_events (
+                             id bigserial primary key,
+                             created_at timestamptz not null default now(),
+                             event text not null check (event in (
+                                 'login_succeeded', 'login_failed', 'login_locked',
+                                 'token_refreshed', 'refresh_failed', 'refresh_reused',
+                                 'logout', 'logout_all')),
+                             user_id uuid references users(id),
+                             email text,
+                             session_id uuid,
+                             ip text,
+                             user_agent text,
+                             reason text
+);
+
+create index idx_auth_events_created on auth_events(created_at desc);
+create index idx_auth_events_user on auth_events(user_id, created_at desc);
+create index idx_auth_events_ip on auth_events(ip, created_at desc);
+
+-- Failed logins per key ("account:<email>" or "ip:<address>"). Past the
+-- threshold the key is locked for exponentially growing periods
+create table login_throttle (
+                                key text primary key,
+                                failures int not null default 0,
+                                last_failure_at timestamptz not null,
+                                locked_until timestamptz
+);
+
+commit;
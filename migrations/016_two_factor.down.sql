@@ -0,0 +1,14 @@
+begin;
+
+delete from auth_events where event in ('login_challenged', 'two_factor_enabled', 'two_factor_disabled');
+alter table auth_events drop constraint auth_events_event_check;
+alter table auth_events add constraint auth_events_event_check check (event in (
+    'login_succeeded', 'login_failed', 'login_locked',
+    'token_refreshed', 'refresh_failed', 'refresh_reused',
+    'logout', 'logout_all'));
+
+drop table if exists login_challenges cascade;
+drop table if exists totp_recovery_codes cascade;
+drop table if exists user_totp cascade;
+
+commit;
@@ -0,0 +1,40 @@
+begin;
+
+-- TOTP second factor. The secret is pending until the user confirms it with
+-- a code; last_step is the last accepted time step, so a code can't be replayed
+create table user_totp (
+                           user_id uuid primary key references users(id),
+                           secret text not null,
+                           created_at timestamptz not null default now(),
+                           enabled_at timestamptz,
+                           last_step bigint
+);
+
+-- Single-use recovery codes, stored as sha256 hashes
+create table totp_recovery_codes (
+                                     id uuid primary key default gen_random_uuid(),
+                                     user_id uuid not null references users(id),
+                                     code_hash text not null,
+                                     used_at timestamptz,
+                                     unique (user_id, code_hash)
+);
+
+-- Second login step: issued after a correct password when 2FA is enabled
+create table login_challenges (
+                                  id uuid primary key default gen_random_uuid(),
+                                  user_id uuid not null references users(id),
+                                  email text not null,
+                                  token_hash text unique not null,
+                                  created_at timestamptz not null default now(),
+                                  expires_at timestamptz not null,
+                                  attempts int not null default 0,
+                                  used_at timestamptz
+);
+
+alter table auth_events drop constraint auth_events_event_check;
+alter table auth_events add constraint auth_events_event_check check (event in (
+    'login_succeeded', 'login_failed', 'login_locked', 'login_challenged',
+    'token_refreshed', 'refresh_failed', 'refresh_reused',
+    'logout', 'logout_all', 'two_factor_enabled', 'two_factor_disabled'));
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+drop index if exists idx_outbox_pending;
+alter table outbox drop column if exists dead_at;
+create index idx_outbox_pending on outbox(created_at) where sent_at is null;
+
+commit;
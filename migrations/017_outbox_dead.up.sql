@@ -0,0 +1,10 @@
+begin;
+
+-- Messages that failed max attempts are parked: the relay skips them and
+-- they stay in the table for inspection and manual replay
+alter table outbox add column dead_at timestamptz;
+
+drop index if exists idx_outbox_pending;
+create index idx_outbox_pending on outbox(created_at) where sent_at is null and dead_at is null;
+
+commit;
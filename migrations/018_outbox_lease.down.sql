@@ -0,0 +1,8 @@
+begin;
+
+drop index if exists idx_outbox_pending;
+alter table outbox add column sent_at timestamptz;
+alter table outbox drop column if exists claimed_until;
+create index idx_outbox_pending on outbox(created_at) where sent_at is null and dead_at is null;
+
+commit;
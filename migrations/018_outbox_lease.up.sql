@@ -0,0 +1,12 @@
+begin;
+
+-- The relay claims rows with a short lease instead of holding row locks while
+-- it waits for broker confirms; published rows are deleted right away
+alter table outbox add column claimed_until timestamptz;
+
+delete from outbox where sent_at is not null;
+drop index if exists idx_outbox_pending;
+alter table outbox drop column sent_at;
+create index idx_outbox_pending on outbox(created_at) where dead_at is null;
+
+commit;
@@ -0,0 +1,28 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("phone number must be in international format, e.g. +77011234567")
+
+// Normalize приводит номер к виду E.164: +, затем 8–15 цифр.
+func Normalize(number string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(number) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	digits := b.String()
+	if len(digits) < 8 || len(digits) > 15 || !strings.HasPrefix(strings.TrimSpace(number), "+") {
+		return "", ErrInvalid
+	}
+	return "+" + digits, nil
+}